@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jake/llmify/internal/cache"
+	"github.com/jake/llmify/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var cacheClearExpired bool
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the LLM response cache",
+	Long: `docs and refactor cache LLM responses on disk, keyed by provider, model,
+prompt and parameters, so unchanged files are not sent to the LLM again.
+Use --no-cache on those commands to bypass it.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size and contents of the response cache",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, cfg, err := openCache()
+		if err != nil {
+			return err
+		}
+		stats, err := store.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to read cache: %w", err)
+		}
+
+		fmt.Printf("Directory: %s\n", stats.Dir)
+		fmt.Printf("Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size:      %.1f MB", float64(stats.Bytes)/(1024*1024))
+		if cfg.Cache.MaxSizeMB > 0 {
+			fmt.Printf(" of %d MB", cfg.Cache.MaxSizeMB)
+		}
+		fmt.Println()
+		if cfg.Cache.TTL > 0 {
+			fmt.Printf("TTL:       %s\n", cfg.Cache.TTL)
+		}
+		if stats.Entries > 0 {
+			fmt.Printf("Oldest:    %s\n", stats.Oldest.Format(time.RFC3339))
+			fmt.Printf("Newest:    %s\n", stats.Newest.Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove cached responses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, _, err := openCache()
+		if err != nil {
+			return err
+		}
+		removed, err := store.Clear(cacheClearExpired)
+		if err != nil {
+			return fmt.Errorf("failed to clear cache: %w", err)
+		}
+		fmt.Printf("Removed %d cached response(s).\n", removed)
+		return nil
+	},
+}
+
+func openCache() (*cache.Store, *config.Config, error) {
+	if err := config.LoadConfig(); err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := &config.GlobalConfig
+	store, err := cache.Open(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, cfg, nil
+}
+
+func init() {
+	cacheClearCmd.Flags().BoolVar(&cacheClearExpired, "expired", false, "Only remove entries older than the cache TTL")
+	cacheCmd.AddCommand(cacheStatsCmd, cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
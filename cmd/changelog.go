@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jake/llmify/internal/changelog"
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Commits are summarized in batches of this size, then the batches are merged.
+const (
+	changelogBatchCommits = 40
+	changelogBatchChars   = 12 * 1000
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog",
+	Short: "Generate a Keep a Changelog section from git history",
+	Long: `Generates a Keep a Changelog (https://keepachangelog.com) section for the
+commits between --from and --to. Commits are sorted into Added, Changed,
+Deprecated, Removed, Fixed and Security by their Conventional Commits type.
+The LLM classifies the commits that do not follow the convention and rewrites
+everything in user-facing terms. Internal changes (refactor, chore, ci, test,
+docs, ...) are left out. Large ranges are summarized in batches, which are
+then merged.
+
+The section is printed, or inserted into CHANGELOG.md with --write. An existing
+section for the same version is replaced.
+
+Examples:
+  # Changes since the last tag, as an Unreleased section
+  llmify changelog
+
+  # Release notes for 1.3.0, written into CHANGELOG.md
+  llmify changelog --from v1.2.0 --to HEAD --version 1.3.0 --write`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose := viper.GetBool("verbose")
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		version, _ := cmd.Flags().GetString("version")
+		date, _ := cmd.Flags().GetString("date")
+		write, _ := cmd.Flags().GetBool("write")
+		file, _ := cmd.Flags().GetString("file")
+		instruction, _ := cmd.Flags().GetString("prompt")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		modelFlag, _ := cmd.Flags().GetString("model")
+
+		if err := config.LoadConfig(); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		cfg := &config.GlobalConfig
+		config.ApplyModelOverride(cfg, modelFlag)
+
+		if !cmd.Flags().Changed("from") {
+			from = git.GetLatestTag(to)
+		}
+		if version != "" && date == "" {
+			date = time.Now().Format("2006-01-02")
+		}
+
+		// --- Collect and pre-sort the commits ---
+		entries, err := git.GetLog(from, to)
+		if err != nil {
+			return err
+		}
+		var commits []changelog.Commit
+		for _, c := range changelog.Classify(entries) {
+			if c.Category != changelog.Internal {
+				commits = append(commits, c)
+			}
+		}
+		rangeName := to
+		if from != "" {
+			rangeName = from + ".." + to
+		}
+		if verbose {
+			log.Printf("%s: %d commits, %d skipped as internal", rangeName, len(entries), len(entries)-len(commits))
+		}
+
+		var items []changelog.Entry
+		if len(commits) > 0 {
+			client, finishUsage, err := newCommandClient("changelog", cfg, !noCache)
+			if err != nil {
+				return fmt.Errorf("failed to initialize LLM client: %w", err)
+			}
+			defer finishUsage()
+
+			items, err = summarizeChangelog(cmd.Context(), client, cfg.LLM.Model, commits, instruction)
+			if err == errInterrupted {
+				fmt.Fprintln(os.Stderr, "Changelog generation cancelled.")
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if len(items) == 0 {
+			fmt.Fprintf(os.Stderr, "No user-facing changes in %s.\n", rangeName)
+			return nil
+		}
+
+		section := changelog.Render(changelog.Heading(version, date), items)
+		if !write {
+			fmt.Print(section)
+			return nil
+		}
+		existing, err := os.ReadFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		updated := changelog.Insert(string(existing), section)
+		if err := os.WriteFile(file, []byte(updated), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated %s\n", file)
+		return nil
+	},
+}
+
+// summarizeChangelog turns commits into changelog entries. Each batch of
+// commits is summarized on its own (map); with more than one batch, the
+// results are merged in a final request (reduce).
+func summarizeChangelog(ctx context.Context, client llm.LLMClient, model string, commits []changelog.Commit, instruction string) ([]changelog.Entry, error) {
+	batches := changelog.Batches(commits, changelogBatchCommits, changelogBatchChars)
+	var all []changelog.Entry
+	done := 0
+	for i, batch := range batches {
+		fmt.Fprintf(os.Stderr, "Summarizing commits %d-%d of %d (batch %d/%d)...\n", done+1, done+len(batch), len(commits), i+1, len(batches))
+		done += len(batch)
+
+		data := prompts.Data{Instruction: instruction}
+		for _, c := range batch {
+			data.Commits = append(data.Commits, c.String())
+		}
+		req, err := llm.CreateChangelogPrompt(data)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := generateInterruptible(ctx, client, req.WithModel(model), nil)
+		if err != nil {
+			if err == errInterrupted {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to summarize batch %d: %w", i+1, err)
+		}
+		entries, err := changelog.ParseEntries(resp.Text)
+		if err != nil {
+			return nil, fmt.Errorf("batch %d: %w", i+1, err)
+		}
+		all = append(all, entries...)
+	}
+	if len(batches) < 2 || len(all) == 0 {
+		return all, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Merging %d entries from %d batches...\n", len(all), len(batches))
+	data := prompts.Data{Instruction: instruction}
+	for _, e := range all {
+		data.Entries = append(data.Entries, e.String())
+	}
+	req, err := llm.CreateChangelogMergePrompt(data)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := generateInterruptible(ctx, client, req.WithModel(model), nil)
+	if err != nil {
+		if err == errInterrupted {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to merge changelog entries: %w", err)
+	}
+	merged, err := changelog.ParseEntries(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(merged) == 0 {
+		return all, nil // Better unmerged entries than an empty changelog
+	}
+	return merged, nil
+}
+
+func init() {
+	changelogCmd.Flags().String("from", "", "Start of the range, exclusive (default: the latest tag; all history if there is none)")
+	changelogCmd.Flags().String("to", "HEAD", "End of the range, inclusive")
+	changelogCmd.Flags().String("version", "", "Version for the section heading (default: Unreleased)")
+	changelogCmd.Flags().String("date", "", "Release date for the heading (default: today when --version is set)")
+	changelogCmd.Flags().BoolP("write", "w", false, "Insert the section into the changelog file instead of printing it")
+	changelogCmd.Flags().String("file", "CHANGELOG.md", "Changelog file updated by --write")
+	changelogCmd.Flags().String("prompt", "", "Extra instruction for the changelog, e.g. \"group API changes together\"")
+	changelogCmd.Flags().Bool("no-cache", false, "Always query the LLM instead of reusing cached responses")
+	changelogCmd.Flags().String("model", "", "Use this model (or provider:model) instead of the configured model and fallbacks")
+	rootCmd.AddCommand(changelogCmd)
+}
@@ -2,16 +2,22 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/jake/llmify/internal/commitlint"
+	"github.com/jake/llmify/internal/commitstyle"
 	"github.com/jake/llmify/internal/config"
 	"github.com/jake/llmify/internal/git"
 	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/standards"
 	"github.com/jake/llmify/internal/ui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -21,6 +27,12 @@ var (
 	commitUpdateDocs bool
 	commitForce      bool
 	commitNoEdit     bool
+	commitNoStream   bool
+	commitModelFlag  string
+	commitStyle      string
+	commitShowStyle  bool
+	commitSplit      bool
+	commitHookFile   string
 )
 
 var CommitCmd = &cobra.Command{
@@ -29,13 +41,25 @@ var CommitCmd = &cobra.Command{
 	Long: `Analyzes staged code changes (git diff --staged), generates a detailed
 commit message suggestion using the configured LLM, allows editing,
 and optionally updates documentation files before committing.`,
-	RunE: runCommit,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if commitHookFile != "" {
+			return runCommitHook(cmd, args)
+		}
+		return runCommit(cmd, args)
+	},
 }
 
 func init() {
 	CommitCmd.Flags().BoolVar(&commitUpdateDocs, "docs", false, "Attempt to automatically update relevant documentation files (*.md) based on changes.")
 	CommitCmd.Flags().BoolVarP(&commitForce, "force", "f", false, "Skip the final confirmation prompt before committing.")
 	CommitCmd.Flags().BoolVar(&commitNoEdit, "no-edit", false, "Disable editing of the commit message.")
+	CommitCmd.Flags().BoolVar(&commitNoStream, "no-stream", false, "Do not render the commit message live while it is generated.")
+	CommitCmd.Flags().StringVar(&commitModelFlag, "model", "", "Use this model (or provider:model) instead of the configured model and fallbacks.")
+	CommitCmd.Flags().StringVar(&commitStyle, "style", "", "Commit message style: conventional, or learn from git history (overrides commit.style).")
+	CommitCmd.Flags().BoolVar(&commitShowStyle, "show-style", false, "Print the commit style learned from git history and exit.")
+	CommitCmd.Flags().BoolVar(&commitSplit, "split", false, "Group unrelated staged changes into several commits.")
+	CommitCmd.Flags().StringVar(&commitHookFile, "hook", "", "Run as a prepare-commit-msg hook: write the message to this file instead of committing. See 'llmify hook'.")
+	CommitCmd.MarkFlagsMutuallyExclusive("split", "docs", "hook")
 	// Add other flags if necessary
 }
 
@@ -52,6 +76,14 @@ func runCommit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 	cfg := &config.GlobalConfig // Use the globally loaded config
+	config.ApplyModelOverride(cfg, commitModelFlag)
+	if commitStyle != "" {
+		cfg.Commit.Style = commitStyle
+	}
+
+	if commitShowStyle {
+		return showCommitStyle(cfg)
+	}
 
 	// --- 1. Get Staged Changes ---
 	if verbose {
@@ -78,83 +110,37 @@ func runCommit(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Lockfiles and generated files are reduced to stats; a diff that is still
+	// too large for the model is summarized in parts once the client exists
+	commitModel := cfg.Commit.Model // Use specific commit model
+	fitted := fitDiff(diff, diffBudget(cfg, commitModel))
+	if verbose {
+		log.Printf("Diff is %d tokens of a %d token budget, %d files collapsed", fitted.Tokens, fitted.Budget, len(fitted.Collapsed))
+	}
+
 	// --- 2. Gather Context ---
 	if verbose {
 		log.Println("Gathering context from staged files...")
 	}
-	var contextBuilder strings.Builder
 	repoRoot, err := git.GetRepoRoot() // Get root to construct full paths
 	if err != nil {
 		log.Printf("Warning: could not get repo root, using relative paths: %v", err)
 		repoRoot = "." // Fallback
 	}
-
-	const maxContextChars = 100 * 1000 // Conservative limit to leave room for prompts and responses
-	currentChars := 0
-	filesIncluded := 0
-
-	for _, fileRelPath := range stagedFiles {
-		fullPath := filepath.Join(repoRoot, fileRelPath)
-		if verbose {
-			log.Printf("Processing file: %s", fullPath)
-		}
-
-		// Check if file exists before reading (it might be a deleted file in the diff)
-		if _, statErr := os.Stat(fullPath); os.IsNotExist(statErr) {
-			contextBuilder.WriteString(fmt.Sprintf("\n--- File (Deleted): %s ---\n", fileRelPath))
-			filesIncluded++
-			continue
-		}
-
-		content, readErr := os.ReadFile(fullPath)
-		if readErr != nil {
-			log.Printf("Warning: could not read file %s: %v", fileRelPath, readErr)
-			contextBuilder.WriteString(fmt.Sprintf("\n--- File (Error): %s ---\nError reading file: %v\n", fileRelPath, readErr))
-			filesIncluded++
-			continue
-		}
-
-		fileHeader := fmt.Sprintf("\n--- File: %s ---\n", fileRelPath)
-		fileContent := string(content)
-
-		// Check if adding this file would exceed the context limit
-		if currentChars+len(fileHeader)+len(fileContent) > maxContextChars {
-			remainingSpace := maxContextChars - currentChars - len(fileHeader) - 20 // reserve space for truncation message
-			if remainingSpace > 0 {
-				contextBuilder.WriteString(fileHeader)
-				contextBuilder.WriteString(fileContent[:remainingSpace])
-				contextBuilder.WriteString("\n... (file truncated)\n")
-				filesIncluded++
-			}
-			if verbose {
-				log.Printf("Warning: Context limit reached. Files included: %d of %d", filesIncluded, len(stagedFiles))
-			}
-			break
-		}
-
-		contextBuilder.WriteString(fileHeader)
-		contextBuilder.WriteString(fileContent)
-		currentChars += len(fileHeader) + len(fileContent)
-		filesIncluded++
-	}
-
-	if verbose {
-		log.Printf("Context gathered: %d files included, %d characters total", filesIncluded, currentChars)
-	}
-
-	fullContext := contextBuilder.String()
+	// Whatever the diff leaves of the context window; lockfiles and generated files are only listed as stats
+	contextFiles, currentChars := gatherContext(repoRoot, stagedFiles, fitted.ContextChars(100*1000), fitted.IsCollapsed)
 
 	// --- 3. Create LLM Client ---
 	if verbose {
 		log.Printf("Initializing LLM client (Provider: %s)", cfg.LLM.Provider)
 	}
-	llmClient, err := llm.NewLLMClient(cfg)
+	llmClient, finishUsage, err := newCommandClient("commit", cfg, false) // Regenerating should give a fresh message
 	if err != nil {
 		return fmt.Errorf("failed to create LLM client: %w", err)
 	}
+	defer finishUsage()
 
 	// --- 4. Generate Commit Message ---
-	commitModel := cfg.Commit.Model // Use specific commit model
 	if verbose {
 		log.Printf("Generating commit message using model: %s...", commitModel)
 	}
@@ -169,53 +155,69 @@ func runCommit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create the commit prompt
-	commitPrompt := llm.CreateCommitPrompt(diff, fullContext)
+	promptData := prompts.Data{Files: contextFiles}
+	if err := fitted.Fill(cmd.Context(), llmClient, commitModel, &promptData); err != nil {
+		if errors.Is(err, errInterrupted) {
+			fmt.Println("Commit message generation cancelled.")
+			return nil
+		}
+		return err
+	}
+	style, err := applyCommitStyle(cfg, &promptData)
+	if err != nil {
+		return err
+	}
+	commitPrompt, err := llm.CreateCommitPrompt(promptData)
+	if err != nil {
+		return err
+	}
+	commitPrompt = commitPrompt.WithModel(commitModel)
 
 	// Log the size of our request for debugging
 	if verbose {
-		log.Printf("Request size - Diff: %d chars, Context: %d chars", len(diff), len(fullContext))
+		log.Printf("Request size - Diff: %d chars, Context: %d chars", len(promptData.Diff), currentChars)
 	}
 
-	// Generate the commit message with retries
-	var proposedMessage string
-	maxRetries := 3
-	var lastErr error
-	var ctx context.Context
-	var cancel context.CancelFunc
+	out := streamOutput(commitNoStream)
+	lintRules := commitLintRules(cfg, style)
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			if verbose {
-				log.Printf("Retry attempt %d of %d...", attempt, maxRetries)
-			}
-			// Create a new context for each retry
-			ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
-		} else {
-			// First attempt
-			ctx, cancel = context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	// generate asks the LLM for a commit message and, if enabled, has it fix lint violations
+	generate := func(req llm.Request) (string, error) {
+		if out != nil {
+			fmt.Fprintln(out, "Generating commit message (Ctrl-C to cancel)...")
 		}
-		defer cancel()
-
-		proposedMessage, lastErr = llmClient.Generate(ctx, commitPrompt, commitModel)
-		if lastErr == nil {
-			break // Success, exit retry loop
+		message, err := generateCommitMessage(cmd.Context(), llmClient, req, timeoutSeconds, out)
+		if err != nil || !cfg.Commit.Lint.Enabled {
+			return message, err
 		}
+		return repairCommitMessage(cmd.Context(), llmClient, req, message, lintRules, cfg.Commit.Lint.MaxAttempts, timeoutSeconds, out)
+	}
 
-		// Check if it's a timeout error
-		if strings.Contains(lastErr.Error(), "context deadline exceeded") {
-			if attempt < maxRetries {
-				if verbose {
-					log.Printf("Request timed out, will retry...")
-				}
-				continue
-			}
+	if commitSplit {
+		changes, err := stagedChanges()
+		if err != nil {
+			return err
+		}
+		if len(changes) > 1 {
+			return runSplitCommit(cmd, llmClient, promptData, changes, commitModel, lintRules, timeoutSeconds, out)
 		}
+		fmt.Println("Only one change is staged, so there is nothing to split.")
+	}
 
-		// For other errors or if we're out of retries, return the error
-		return fmt.Errorf("failed to generate commit message (attempt %d/%d): %w", attempt, maxRetries, lastErr)
+	proposedMessage, err := generate(commitPrompt)
+	if errors.Is(err, errInterrupted) {
+		fmt.Println("Commit message generation cancelled.")
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	proposedMessage = strings.TrimSpace(proposedMessage) // Clean up LLM output
+	if commitHookFile != "" {
+		// git commit reads the message from the file; keep git's comments below it
+		existing, _ := os.ReadFile(commitHookFile)
+		return os.WriteFile(commitHookFile, []byte(proposedMessage+"\n"+string(existing)), 0644)
+	}
 
 	// --- 5. Handle --docs flag ---
 	updatedDocs := []string{}
@@ -257,17 +259,33 @@ func runCommit(cmd *cobra.Command, args []string) error {
 				continue
 			}
 
-			docPrompt := llm.CreateDocsUpdatePrompt(diff, string(docContent))
-			ctxDocs, cancelDocs := context.WithTimeout(context.Background(), time.Duration(viper.GetInt("llm.timeout_seconds"))*time.Second) // Separate timeout
+			docPrompt, err := llm.CreateDocsUpdatePrompt(prompts.Data{
+				Diff:      fitted.Text(),
+				Path:      docPath,
+				Target:    string(docContent),
+				Language:  "markdown",
+				Standards: standards.RulePrompts(docPath, "markdown"),
+			})
+			if err != nil {
+				return err
+			}
+			docPrompt = docPrompt.WithModel(docsModel)
+			ctxDocs, cancelDocs := context.WithTimeout(cmd.Context(), time.Duration(viper.GetInt("llm.timeout_seconds"))*time.Second) // Separate timeout
 
-			docResponse, llmErr := llmClient.Generate(ctxDocs, docPrompt, docsModel)
+			// The reply is the whole document, so it is not rendered live
+			docResponse, llmErr := generateInterruptible(ctxDocs, llmClient, docPrompt, nil)
 			cancelDocs() // Release context resources
+			if errors.Is(llmErr, errInterrupted) {
+				// Documents already updated are still staged below
+				fmt.Println("Documentation update cancelled; the remaining files were not checked.")
+				break
+			}
 			if llmErr != nil {
 				log.Printf("Warning: LLM failed to process doc %s: %v", docPath, llmErr)
 				continue
 			}
 
-			needsUpdate, newContent := llm.NeedsDocUpdate(docResponse)
+			needsUpdate, newContent := llm.NeedsDocUpdate(docResponse.Text)
 			if needsUpdate {
 				if verbose {
 					log.Printf("LLM proposed update for: %s", docPath)
@@ -302,42 +320,328 @@ func runCommit(cmd *cobra.Command, args []string) error {
 	}
 
 	// --- 6. Edit, Confirm, Commit Loop ---
-	finalMessage := proposedMessage
+	// Every generated or edited message is kept so the user can go back to an earlier one
+	candidates := []string{proposedMessage}
+	current := 0
 	for {
 		fmt.Println("\n--- Proposed Commit Message ---")
-		fmt.Println(finalMessage)
+		fmt.Println(candidates[current])
 		fmt.Println("-----------------------------")
 
-		if !commitNoEdit {
-			fmt.Println("\nTo edit the message, use: git commit --amend")
-			fmt.Println("Or press 'n' to try generating a new message.")
-		}
-
-		// Confirm
-		proceed, editAgain, confirmErr := ui.ConfirmCommit(commitForce)
+		choice, confirmErr := ui.ConfirmCommit(ui.ConfirmOptions{
+			Force:      commitForce,
+			AllowEdit:  !commitNoEdit,
+			Candidate:  current + 1,
+			Candidates: len(candidates),
+		})
 		if confirmErr != nil {
 			return confirmErr // Error reading confirmation
 		}
 
-		if proceed {
-			break // Exit loop to commit
-		}
-		if !editAgain {
+		switch choice.Action {
+		case ui.CommitAccept:
+			finalMessage := candidates[current]
+			// --- 7. Execute Commit ---
+			if verbose {
+				log.Println("Executing git commit...")
+			}
+			if err := git.Commit(finalMessage); err != nil {
+				return fmt.Errorf("git commit execution failed: %w", err)
+			}
+			fmt.Println("Commit successful.")
+			return nil
+		case ui.CommitAbort:
 			fmt.Println("Commit aborted.")
-			return nil // User chose 'no'
+			return nil
+		case ui.CommitEdit:
+			edited, editErr := ui.EditCommitMessage(candidates[current])
+			if editErr != nil {
+				fmt.Printf("Could not edit the message: %v\n", editErr)
+				continue
+			}
+			if edited == "" {
+				fmt.Println("Aborting commit due to empty commit message.")
+				return nil
+			}
+			if edited != candidates[current] {
+				candidates = append(candidates, edited)
+				current = len(candidates) - 1
+			}
+		case ui.CommitRegenerate:
+			data := promptData
+			data.Instruction = choice.Instruction
+			req, promptErr := llm.CreateCommitPrompt(data)
+			if promptErr != nil {
+				return promptErr
+			}
+			message, genErr := generate(req.WithModel(commitModel))
+			if errors.Is(genErr, errInterrupted) {
+				fmt.Println("Regeneration cancelled.")
+				continue
+			}
+			if genErr != nil {
+				fmt.Printf("Could not regenerate the message: %v\n", genErr)
+				continue
+			}
+			candidates = append(candidates, message)
+			current = len(candidates) - 1
+		case ui.CommitPrevious:
+			current = (current + len(candidates) - 1) % len(candidates)
+		case ui.CommitNext:
+			current = (current + 1) % len(candidates)
 		}
-		// Otherwise, loop back to show message again
+	}
+}
+
+// gatherContext reads the files at paths (relative to repoRoot) for the prompt,
+// up to maxContextChars characters in total; the file that crosses the limit is
+// truncated and the rest are left out. Files for which skip returns true are
+// left out as well. It returns the files and the characters used.
+func gatherContext(repoRoot string, paths []string, maxContextChars int, skip func(string) bool) ([]prompts.File, int) {
+	verbose := viper.GetBool("verbose")
+	var contextFiles []prompts.File
+	currentChars := 0
+	filesIncluded := 0
+
+	for _, fileRelPath := range paths {
+		fullPath := filepath.Join(repoRoot, fileRelPath)
+		if verbose {
+			log.Printf("Processing file: %s", fullPath)
+		}
+
+		if skip != nil && skip(fileRelPath) {
+			continue
+		}
+
+		// Check if file exists before reading (it might be a deleted file in the diff)
+		if _, statErr := os.Stat(fullPath); os.IsNotExist(statErr) {
+			contextFiles = append(contextFiles, prompts.File{Path: fileRelPath, Deleted: true})
+			filesIncluded++
+			continue
+		}
+
+		content, readErr := os.ReadFile(fullPath)
+		if readErr != nil {
+			log.Printf("Warning: could not read file %s: %v", fileRelPath, readErr)
+			contextFiles = append(contextFiles, prompts.File{Path: fileRelPath, Content: fmt.Sprintf("Error reading file: %v", readErr)})
+			filesIncluded++
+			continue
+		}
+
+		fileContent := string(content)
+
+		// Check if adding this file would exceed the context limit
+		if currentChars+len(fileRelPath)+len(fileContent) > maxContextChars {
+			remainingSpace := maxContextChars - currentChars - len(fileRelPath) - 20 // reserve space for truncation message
+			if remainingSpace > 0 {
+				contextFiles = append(contextFiles, prompts.File{Path: fileRelPath, Content: fileContent[:remainingSpace] + "\n... (file truncated)\n"})
+				filesIncluded++
+			}
+			if verbose {
+				log.Printf("Warning: Context limit reached. Files included: %d of %d", filesIncluded, len(paths))
+			}
+			break
+		}
+
+		contextFiles = append(contextFiles, prompts.File{Path: fileRelPath, Content: fileContent})
+		currentChars += len(fileRelPath) + len(fileContent)
+		filesIncluded++
 	}
 
-	// --- 7. Execute Commit ---
 	if verbose {
-		log.Println("Executing git commit...")
+		log.Printf("Context gathered: %d files included, %d characters total", filesIncluded, currentChars)
+	}
+	return contextFiles, currentChars
+}
+
+// runCommitHook is commit --hook <msgfile> [source [sha]], run by the
+// prepare-commit-msg hook. It fills the message file only for a plain commit
+// whose message is still empty, and never fails the commit: problems are
+// printed as warnings.
+func runCommitHook(cmd *cobra.Command, args []string) error {
+	source := ""
+	if len(args) > 0 {
+		source = args[0]
+	}
+	switch source {
+	case "message", "merge", "squash", "commit":
+		// -m/-F, merges, squashes and amends or -c/-C already have a message
+		return nil
 	}
-	err = git.Commit(finalMessage)
+	content, err := os.ReadFile(commitHookFile)
 	if err != nil {
-		return fmt.Errorf("git commit execution failed: %w", err)
+		fmt.Fprintf(os.Stderr, "llmify: could not read %s: %v\n", commitHookFile, err)
+		return nil
+	}
+	if ui.StripComments(string(content)) != "" {
+		return nil // A template or message that already has content
+	}
+
+	commitNoStream = true // No terminal to render into when committing from an IDE
+	fmt.Fprintln(os.Stderr, "llmify: generating commit message...")
+	if err := runCommit(cmd, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "llmify: could not generate a commit message: %v\n", err)
+	}
+	return nil
+}
+
+// applyCommitStyle adds the learned commit style to the prompt data when
+// commit.style is "learn" and returns the profile used. Without usable history
+// it returns nil and the default style is used.
+func applyCommitStyle(cfg *config.Config, data *prompts.Data) (*commitstyle.Profile, error) {
+	switch strings.ToLower(cfg.Commit.Style) {
+	case "", "conventional":
+		return nil, nil
+	case "learn":
+	default:
+		return nil, fmt.Errorf("unknown commit style %q (use conventional or learn)", cfg.Commit.Style)
+	}
+
+	profile, cached, err := commitstyle.Load(cfg.Commit.StyleSamples)
+	if err != nil || profile.Samples == 0 {
+		if viper.GetBool("verbose") {
+			log.Printf("Could not learn commit style, using the default: %v", err)
+		}
+		return nil, nil
+	}
+	if viper.GetBool("verbose") {
+		log.Printf("Using %s commit style learned from %d commits (cached: %v)", profile.Convention, profile.Samples, cached)
+	}
+	data.Style = profile.Guidance()
+	data.Examples = profile.Examples
+	return profile, nil
+}
+
+// commitLintRules builds the lint rules from config. A type prefix is only
+// required when the message should follow Conventional Commits.
+func commitLintRules(cfg *config.Config, style *commitstyle.Profile) commitlint.Rules {
+	lint := cfg.Commit.Lint
+	return commitlint.Rules{
+		RequireType:      style == nil || style.Convention == commitstyle.Conventional,
+		Types:            lint.Types,
+		Scopes:           lint.Scopes,
+		SubjectMaxLength: lint.SubjectMaxLength,
+		Imperative:       lint.Imperative,
+		BodyWrap:         lint.BodyWrap,
+		Trailers:         lint.Trailers,
+	}
+}
+
+// generateCommitMessage asks the LLM for a commit message, retrying requests
+// that time out.
+func generateCommitMessage(parent context.Context, client llm.LLMClient, req llm.Request, timeoutSeconds int, out io.Writer) (string, error) {
+	maxRetries := 3
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && viper.GetBool("verbose") {
+			log.Printf("Retry attempt %d of %d...", attempt, maxRetries)
+		}
+
+		// Create a new context for each attempt
+		ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutSeconds)*time.Second)
+		resp, err := generateInterruptible(ctx, client, req, out)
+		cancel()
+		if err == nil {
+			return strings.TrimSpace(resp.Text), nil // Clean up LLM output
+		}
+		if errors.Is(err, errInterrupted) {
+			return "", err
+		}
+
+		// Retry timeouts; return other errors or if we're out of retries
+		if errors.Is(err, context.DeadlineExceeded) && attempt < maxRetries {
+			if viper.GetBool("verbose") {
+				log.Printf("Request timed out, will retry...")
+			}
+			continue
+		}
+		return "", fmt.Errorf("failed to generate commit message (attempt %d/%d): %w", attempt, maxRetries, err)
+	}
+}
+
+// repairCommitMessage lints message and, while it breaks rules, asks the LLM to
+// fix it up to maxAttempts times. It returns the best message it has; if it still
+// breaks rules they are printed so the user can fix them while editing.
+func repairCommitMessage(parent context.Context, client llm.LLMClient, req llm.Request, message string, rules commitlint.Rules, maxAttempts, timeoutSeconds int, out io.Writer) (string, error) {
+	for attempt := 0; ; attempt++ {
+		violations := commitlint.Lint(message, rules)
+		if len(violations) == 0 {
+			return message, nil
+		}
+		var problems []string
+		for _, v := range violations {
+			problems = append(problems, v.String())
+		}
+		if attempt >= maxAttempts {
+			fmt.Println("Warning: the commit message still breaks these rules:")
+			for _, p := range problems {
+				fmt.Printf("  - %s\n", p)
+			}
+			return message, nil
+		}
+
+		if out != nil {
+			fmt.Fprintf(out, "Commit message breaks %d rule(s), asking for a fix (%d/%d)...\n", len(violations), attempt+1, maxAttempts)
+		} else if viper.GetBool("verbose") {
+			log.Printf("Commit message breaks %d rule(s): %s", len(violations), strings.Join(problems, "; "))
+		}
+		repairReq, err := llm.CreateCommitRepairPrompt(req, message, problems)
+		if err != nil {
+			return message, err
+		}
+		ctx, cancel := context.WithTimeout(parent, time.Duration(timeoutSeconds)*time.Second)
+		resp, err := generateInterruptible(ctx, client, repairReq, out)
+		cancel()
+		if errors.Is(err, errInterrupted) {
+			return message, err
+		}
+		if err != nil {
+			log.Printf("Warning: could not repair commit message: %v", err)
+			return message, nil
+		}
+		message = strings.TrimSpace(resp.Text)
 	}
+}
 
-	fmt.Println("Commit successful.")
+// showCommitStyle prints the commit style profile learned from git history.
+func showCommitStyle(cfg *config.Config) error {
+	profile, cached, err := commitstyle.Load(cfg.Commit.StyleSamples)
+	if err != nil {
+		return err
+	}
+	source := "freshly inferred"
+	if cached {
+		source = "cached"
+	}
+	fmt.Printf("Commit style learned from the last %d commits (%s):\n\n", profile.Samples, source)
+	fmt.Printf("Convention:     %s (%.0f%% of commits)\n", profile.Convention, profile.ConventionShare*100)
+	if len(profile.Types) > 0 {
+		fmt.Printf("Types:          %s\n", formatCounts(profile.Types))
+	}
+	if len(profile.Scopes) > 0 {
+		fmt.Printf("Scopes:         %s\n", formatCounts(profile.Scopes))
+	}
+	if profile.TicketFormat != "" {
+		fmt.Printf("Ticket format:  %s (%s)\n", profile.TicketFormat, formatCounts(profile.TicketPrefixes))
+	}
+	fmt.Printf("Subject length: ~%d chars after prefix, 90%% under %d\n", profile.AvgSubjectLength, profile.MaxSubjectLength+1)
+	fmt.Printf("Body:           %.0f%% of commits\n", profile.BodyShare*100)
+	fmt.Printf("\nPrompt guidance:\n%s\n", profile.Guidance())
+	if len(profile.Examples) > 0 {
+		fmt.Println("\nExamples:")
+		for _, example := range profile.Examples {
+			fmt.Printf("---\n%s\n", example)
+		}
+	}
+	if !strings.EqualFold(cfg.Commit.Style, "learn") {
+		fmt.Println("\nSet commit.style: learn (or pass --style learn) to use this style when generating messages.")
+	}
 	return nil
 }
+
+func formatCounts(counts []commitstyle.Count) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s (%d)", c.Value, c.Count)
+	}
+	return strings.Join(parts, ", ")
+}
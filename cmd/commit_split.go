@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jake/llmify/internal/commitlint"
+	"github.com/jake/llmify/internal/commitsplit"
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// stagedChanges parses the staged diff into independently committable changes.
+func stagedChanges() ([]diff.Change, error) {
+	patch, err := git.GetStagedPatch()
+	if err != nil {
+		return nil, err
+	}
+	files, err := diff.Parse(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse staged diff: %w", err)
+	}
+	return diff.Changes(files), nil
+}
+
+// runSplitCommit asks the LLM to group the staged changes into several
+// commits, lets the user review the plan and then creates the commits.
+func runSplitCommit(cmd *cobra.Command, client llm.LLMClient, data prompts.Data, changes []diff.Change, model string, rules commitlint.Rules, timeoutSeconds int, out io.Writer) error {
+	data.Changes = make([]prompts.Change, len(changes))
+	for i, c := range changes {
+		data.Changes[i] = prompts.Change{ID: c.ID, Path: c.File.Path(), Diff: describeChange(c)}
+	}
+
+	propose := func(instruction string) (*commitsplit.Plan, error) {
+		data.Instruction = instruction
+		req, err := llm.CreateCommitSplitPrompt(data)
+		if err != nil {
+			return nil, err
+		}
+		if out != nil {
+			fmt.Fprintf(out, "Grouping %d staged changes into commits (Ctrl-C to cancel)...\n", len(changes))
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(timeoutSeconds)*time.Second)
+		defer cancel()
+		// The JSON plan is not worth rendering live
+		resp, err := generateInterruptible(ctx, client, req.WithModel(model), nil)
+		if err != nil {
+			return nil, err
+		}
+		return commitsplit.ParsePlan(resp.Text, changes)
+	}
+
+	plan, err := propose("")
+	if errors.Is(err, errInterrupted) {
+		fmt.Println("Commit message generation cancelled.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to split staged changes: %w", err)
+	}
+
+	// Like single commits, every proposed or edited plan is kept so the user can go back
+	candidates := []*commitsplit.Plan{plan}
+	current := 0
+	for {
+		printSplitPlan(candidates[current], rules)
+
+		choice, err := ui.ConfirmCommit(ui.ConfirmOptions{
+			Question:   "Create these commits?",
+			Force:      commitForce,
+			AllowEdit:  !commitNoEdit,
+			Candidate:  current + 1,
+			Candidates: len(candidates),
+		})
+		if err != nil {
+			return err
+		}
+
+		switch choice.Action {
+		case ui.CommitAccept:
+			ctx, stop := interruptible(cmd.Context())
+			created, err := commitsplit.Apply(ctx, candidates[current])
+			stop()
+			if err != nil {
+				return fmt.Errorf("split commit failed: %w", err)
+			}
+			fmt.Printf("Created %d commits.\n", created)
+			if n := len(candidates[current].Unassigned); n > 0 {
+				fmt.Printf("%d change(s) were not part of any commit and are still staged.\n", n)
+			}
+			return nil
+		case ui.CommitAbort:
+			fmt.Println("Commit aborted.")
+			return nil
+		case ui.CommitEdit:
+			edited, ok, err := editSplitPlan(candidates[current])
+			if err != nil {
+				fmt.Printf("Could not edit the messages: %v\n", err)
+				continue
+			}
+			if !ok {
+				fmt.Println("Aborting commit due to empty commit message.")
+				return nil
+			}
+			candidates = append(candidates, edited)
+			current = len(candidates) - 1
+		case ui.CommitRegenerate:
+			plan, err := propose(choice.Instruction)
+			if errors.Is(err, errInterrupted) {
+				fmt.Println("Regeneration cancelled.")
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Could not regenerate the commits: %v\n", err)
+				continue
+			}
+			candidates = append(candidates, plan)
+			current = len(candidates) - 1
+		case ui.CommitPrevious:
+			current = (current + len(candidates) - 1) % len(candidates)
+		case ui.CommitNext:
+			current = (current + 1) % len(candidates)
+		}
+	}
+}
+
+// describeChange renders a change for the prompt. File headers and binary
+// patch data only cost tokens, so they are summarized.
+func describeChange(c diff.Change) string {
+	switch {
+	case c.File.Binary:
+		return "(binary file changed)"
+	case len(c.Hunks) == 0:
+		return strings.Join(c.File.Header[1:], "\n") // Mode change or empty file
+	}
+	var b strings.Builder
+	if c.File.OldPath == "" {
+		b.WriteString("(new file)\n")
+	} else if c.File.NewPath == "" {
+		b.WriteString("(deleted file)\n")
+	}
+	for _, h := range c.Hunks {
+		b.WriteString(h.String())
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// printSplitPlan shows the proposed commits with the files and hunks in each.
+func printSplitPlan(plan *commitsplit.Plan, rules commitlint.Rules) {
+	for _, w := range plan.Warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	fmt.Printf("\n--- Proposed Commits (%d) ---\n", len(plan.Groups))
+	for i, group := range plan.Groups {
+		fmt.Printf("\n%d. %s\n", i+1, strings.ReplaceAll(group.Message, "\n", "\n   "))
+		fmt.Printf("   Changes: %s\n", summarizeChanges(group.Changes))
+		for _, v := range commitlint.Lint(group.Message, rules) {
+			fmt.Printf("   Lint: %s\n", v)
+		}
+	}
+	if len(plan.Unassigned) > 0 {
+		fmt.Printf("\nLeft staged: %s\n", summarizeChanges(plan.Unassigned))
+	}
+	fmt.Println("-----------------------------")
+}
+
+// summarizeChanges lists the files touched by changes, noting which hunks of a
+// file are included when it is split across commits.
+func summarizeChanges(changes []diff.Change) string {
+	var order []*diff.File
+	hunks := make(map[*diff.File][]string)
+	for _, c := range changes {
+		if _, ok := hunks[c.File]; !ok {
+			order = append(order, c.File)
+			hunks[c.File] = nil
+		}
+		for _, h := range c.Hunks {
+			for n, fh := range c.File.Hunks {
+				if fh == h {
+					hunks[c.File] = append(hunks[c.File], fmt.Sprintf("%d", n+1))
+				}
+			}
+		}
+	}
+	parts := make([]string, len(order))
+	for i, f := range order {
+		parts[i] = f.Path()
+		if n := len(hunks[f]); f.Splittable() && n < len(f.Hunks) {
+			label := "hunk"
+			if n > 1 {
+				label = "hunks"
+			}
+			parts[i] += fmt.Sprintf(" (%s %s of %d)", label, strings.Join(hunks[f], ", "), len(f.Hunks))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// editSplitPlan opens each commit message of plan in the editor in turn and
+// returns the edited copy. ok is false if a message was emptied.
+func editSplitPlan(plan *commitsplit.Plan) (edited *commitsplit.Plan, ok bool, err error) {
+	edited = &commitsplit.Plan{Unassigned: plan.Unassigned}
+	for _, group := range plan.Groups {
+		message, err := ui.EditCommitMessage(group.Message)
+		if err != nil {
+			return nil, false, err
+		}
+		if message == "" {
+			return nil, false, nil
+		}
+		edited.Groups = append(edited.Groups, commitsplit.Group{Message: message, Changes: group.Changes})
+	}
+	return edited, true, nil
+}
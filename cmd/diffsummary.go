@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/tokenizer"
+)
+
+const (
+	// diffSummaryWorkers is how many parts of a large diff are summarized at once.
+	diffSummaryWorkers = 4
+	// diffSummaryRounds limits how often summaries that are still too large
+	// are condensed again.
+	diffSummaryRounds = 3
+	// charsPerToken converts a token budget into characters for file contents.
+	charsPerToken = 3
+)
+
+// diffBudget returns how many tokens of diff fit into one request for model:
+// three quarters of its context window, less room for the instructions and
+// the reply.
+func diffBudget(cfg *config.Config, model string) int {
+	window := llm.ContextWindow(model, cfg.LLM.ContextWindow)
+	budget := window*3/4 - 2048
+	if budget < 1024 {
+		budget = 1024
+	}
+	return budget
+}
+
+// tokenCounter returns a function that counts tokens with the default
+// tokenizer, or estimates them if it is unavailable.
+func tokenCounter() func(string) int {
+	tok, err := tokenizer.Get(tokenizer.DefaultTokenizer)
+	if err != nil {
+		tok, _ = tokenizer.Get("approx")
+	}
+	return tok.Count
+}
+
+// fittedDiff is a diff prepared for a model's context window. Lockfiles and
+// generated files are left out as one-line stats; the rest is sent whole if it
+// fits the budget, or summarized in parts if it does not.
+type fittedDiff struct {
+	Diff      string   // The diff without the collapsed files
+	Collapsed []string // Stats of the files left out
+	Summaries []string // Summaries of the parts of Diff, once Fill has made them
+	Sections  []diff.Section
+	Tokens    int // Tokens in Diff
+	Budget    int
+
+	collapsed map[string]bool // Paths of the collapsed files
+	count     func(string) int
+}
+
+// fitDiff sets lockfiles and generated files aside and measures the rest
+// against budget tokens.
+func fitDiff(text string, budget int) *fittedDiff {
+	d := &fittedDiff{Budget: budget, collapsed: make(map[string]bool), count: tokenCounter()}
+	var kept []string
+	for _, s := range diff.Sections(text) {
+		if s.Generated {
+			d.Collapsed = append(d.Collapsed, s.Stat())
+			d.collapsed[s.Path] = true
+			continue
+		}
+		d.Sections = append(d.Sections, s)
+		kept = append(kept, s.String())
+	}
+	if len(kept) == 0 && len(d.Collapsed) == 0 {
+		kept = []string{text} // Not a git diff; send it as it is
+	}
+	d.Diff = strings.Join(kept, "\n")
+	d.Tokens = d.count(d.Diff)
+	return d
+}
+
+// TooLarge reports whether the diff has to be summarized.
+func (d *fittedDiff) TooLarge() bool {
+	return d.Tokens > d.Budget
+}
+
+// IsCollapsed reports whether the file at path (relative to the repository
+// root) was left out of the diff, so its contents are not worth sending either.
+func (d *fittedDiff) IsCollapsed(path string) bool {
+	return d.collapsed[path]
+}
+
+// ContextChars returns how many characters of file contents fit next to the
+// diff, at most limit.
+func (d *fittedDiff) ContextChars(limit int) int {
+	if d.TooLarge() {
+		return 0
+	}
+	return min(limit, (d.Budget-d.Tokens)*charsPerToken)
+}
+
+// Fill sets data.Diff, or data.Summaries for a diff that is too large, and
+// data.Collapsed. Summaries are made with model, several parts at a time.
+func (d *fittedDiff) Fill(ctx context.Context, client llm.LLMClient, model string, data *prompts.Data) error {
+	data.Collapsed = d.Collapsed
+	if !d.TooLarge() {
+		data.Diff = d.Diff
+		return nil
+	}
+
+	chunks := diff.Chunks(d.Sections, d.Budget, d.count)
+	fmt.Fprintf(os.Stderr, "The diff is too large for one request (%d tokens, %d fit); summarizing it in %d parts...\n", d.Tokens, d.Budget, len(chunks))
+	var reqs []llm.Request
+	for _, c := range chunks {
+		req, err := llm.CreateDiffSummaryPrompt(prompts.Data{Diff: c.Diff, Path: strings.Join(c.Paths, ", ")})
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, req.WithModel(model))
+	}
+	summaries, err := generateParallel(ctx, client, reqs)
+	if err != nil {
+		return err
+	}
+	for i, c := range chunks {
+		summaries[i] = strings.Join(c.Paths, ", ") + ":\n" + summaries[i]
+	}
+
+	// Condense the summaries until they fit, in groups that each fit a request
+	for round := 0; round < diffSummaryRounds && d.count(strings.Join(summaries, "\n")) > d.Budget; round++ {
+		var groups [][]string
+		used := 0
+		for _, s := range summaries {
+			n := d.count(s)
+			if len(groups) == 0 || used+n > d.Budget {
+				groups = append(groups, nil)
+				used = 0
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], s)
+			used += n
+		}
+		fmt.Fprintf(os.Stderr, "Condensing %d summaries into %d...\n", len(summaries), len(groups))
+		reqs = reqs[:0]
+		for _, group := range groups {
+			req, err := llm.CreateDiffSummaryPrompt(prompts.Data{Summaries: group})
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, req.WithModel(model))
+		}
+		if summaries, err = generateParallel(ctx, client, reqs); err != nil {
+			return err
+		}
+	}
+	d.Summaries = summaries
+	data.Diff = ""
+	data.Summaries = summaries
+	return nil
+}
+
+// Text returns the diff, or its summaries once Fill has made them, for
+// prompts that only take a diff.
+func (d *fittedDiff) Text() string {
+	if len(d.Summaries) > 0 {
+		return strings.Join(d.Summaries, "\n\n")
+	}
+	return d.Diff
+}
+
+// generateParallel runs reqs with up to diffSummaryWorkers in flight and
+// returns the replies in order. The first failure cancels the rest; Ctrl-C
+// cancels everything and returns errInterrupted.
+func generateParallel(parent context.Context, client llm.LLMClient, reqs []llm.Request) ([]string, error) {
+	sigCtx, stop := interruptible(parent)
+	defer stop()
+	ctx, cancel := context.WithCancel(sigCtx)
+	defer cancel()
+
+	replies := make([]string, len(reqs))
+	sem := make(chan struct{}, diffSummaryWorkers)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, req := range reqs {
+		wg.Add(1)
+		go func(i int, req llm.Request) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			resp, err := client.Generate(ctx, req)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil && ctx.Err() == nil {
+					firstErr = fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(reqs), err)
+				}
+				mu.Unlock()
+				cancel()
+				return
+			}
+			replies[i] = strings.TrimSpace(resp.Text)
+		}(i, req)
+	}
+	wg.Wait()
+
+	if sigCtx.Err() != nil {
+		return nil, errInterrupted
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return replies, nil
+}
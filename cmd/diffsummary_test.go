@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+)
+
+// changedFile returns the diff of a file that gains n lines.
+func changedFile(path string, n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n@@ -0,0 +1,%d @@\n", path, path, path, path, n)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "+%s line %d\n", path, i)
+	}
+	return b.String()
+}
+
+// fitLines fits text to budget tokens, counting a line as one token.
+func fitLines(text string, budget int) *fittedDiff {
+	d := fitDiff(text, budget)
+	d.count = func(s string) int { return strings.Count(s, "\n") + 1 }
+	d.Tokens = d.count(d.Diff)
+	return d
+}
+
+func TestFitDiff(t *testing.T) {
+	text := changedFile("main.go", 3) + changedFile("go.sum", 50) + changedFile("vendor/x/y.go", 50)
+	d := fitLines(text, 100)
+	if want := strings.TrimSuffix(changedFile("main.go", 3), "\n"); d.Diff != want {
+		t.Errorf("Diff = %q, want only main.go", d.Diff)
+	}
+	if want := []string{"go.sum (generated, +50 -0)", "vendor/x/y.go (generated, +50 -0)"}; !reflect.DeepEqual(d.Collapsed, want) {
+		t.Errorf("Collapsed = %q, want %q", d.Collapsed, want)
+	}
+	for path, want := range map[string]bool{"go.sum": true, "vendor/x/y.go": true, "main.go": false, "sub/go.sum": false} {
+		if d.IsCollapsed(path) != want {
+			t.Errorf("IsCollapsed(%q) = %v", path, !want)
+		}
+	}
+	if d.Tokens != 8 || d.TooLarge() {
+		t.Errorf("Tokens = %d, TooLarge = %v; want 8 and false", d.Tokens, d.TooLarge())
+	}
+	if got := d.ContextChars(1000); got != (100-8)*charsPerToken {
+		t.Errorf("ContextChars(1000) = %d", got)
+	}
+	if got := d.ContextChars(10); got != 10 {
+		t.Errorf("ContextChars(10) = %d", got)
+	}
+
+	if d := fitLines("just some text", 100); d.Diff != "just some text" || d.Collapsed != nil {
+		t.Errorf("text that is not a diff was changed to %q", d.Diff)
+	}
+	if d := fitLines(text, 5); !d.TooLarge() || d.ContextChars(1000) != 0 {
+		t.Error("a diff over the budget leaves room for context")
+	}
+}
+
+func TestFillWholeDiff(t *testing.T) {
+	client, err := llm.NewFakeClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := fitLines(changedFile("main.go", 3)+changedFile("go.sum", 50), 100)
+	var data prompts.Data
+	if err := d.Fill(context.Background(), client, "model", &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Diff != d.Diff || data.Summaries != nil || !reflect.DeepEqual(data.Collapsed, d.Collapsed) {
+		t.Errorf("data = %+v", data)
+	}
+	if len(client.Requests) != 0 || d.Text() != d.Diff {
+		t.Errorf("a diff that fits was summarized")
+	}
+}
+
+func TestFillSummarizes(t *testing.T) {
+	// a.go and b.go (10 lines each) fit one request of 25, c.go the next
+	text := changedFile("a.go", 5) + changedFile("b.go", 5) + changedFile("c.go", 5)
+	long := func(name string) string { return "LONG-" + name + strings.Repeat("\nmore detail", 20) }
+
+	tests := []struct {
+		name      string
+		fixtures  []llm.Fixture
+		requests  int
+		summaries []string
+	}{
+		{
+			name: "summaries fit",
+			fixtures: []llm.Fixture{
+				{Match: `a\.go line`, Text: " Changed a and b. "},
+				{Match: `c\.go line`, Text: "Changed c."},
+			},
+			requests:  2,
+			summaries: []string{"a.go, b.go:\nChanged a and b.", "c.go:\nChanged c."},
+		},
+		{
+			// Together the summaries are over the budget, so each is condensed
+			name: "summaries are condensed",
+			fixtures: []llm.Fixture{
+				{Match: "LONG-ab", Text: "Short a and b."},
+				{Match: "LONG-c", Text: "Short c."},
+				{Match: `a\.go line`, Text: long("ab")},
+				{Match: `c\.go line`, Text: long("c")},
+			},
+			requests:  4,
+			summaries: []string{"Short a and b.", "Short c."},
+		},
+	}
+	for _, tt := range tests {
+		client, err := llm.NewFakeClient(tt.fixtures)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := fitLines(text, 25)
+		data := prompts.Data{Diff: "stale"}
+		if err := d.Fill(context.Background(), client, "summary-model", &data); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(client.Requests) != tt.requests {
+			t.Errorf("%s: made %d requests, want %d", tt.name, len(client.Requests), tt.requests)
+		}
+		for _, req := range client.Requests {
+			if req.Model != "summary-model" {
+				t.Errorf("%s: request for model %q", tt.name, req.Model)
+			}
+		}
+		if !reflect.DeepEqual(data.Summaries, tt.summaries) || data.Diff != "" {
+			t.Errorf("%s: summaries = %q, diff = %q; want %q", tt.name, data.Summaries, data.Diff, tt.summaries)
+		}
+		if want := strings.Join(tt.summaries, "\n\n"); d.Text() != want {
+			t.Errorf("%s: Text() = %q, want %q", tt.name, d.Text(), want)
+		}
+	}
+}
+
+func TestFillFails(t *testing.T) {
+	client, err := llm.NewFakeClient([]llm.Fixture{
+		{Match: `a\.go line`, Text: "Changed a and b."},
+		{Match: `c\.go line`, Status: 400, Error: "bad request"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := fitLines(changedFile("a.go", 5)+changedFile("b.go", 5)+changedFile("c.go", 5), 25)
+	var data prompts.Data
+	err = d.Fill(context.Background(), client, "model", &data)
+	if err == nil || !strings.Contains(err.Error(), "failed to summarize part 2 of 2") {
+		t.Errorf("err = %v, want the failed part", err)
+	}
+	if data.Summaries != nil || d.Summaries != nil {
+		t.Error("a failed summary left partial summaries behind")
+	}
+}
@@ -12,7 +12,10 @@ import (
 	"github.com/jake/llmify/internal/diff"
 	"github.com/jake/llmify/internal/editor"
 	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/language"
 	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/standards"
 	"github.com/jake/llmify/internal/walker"
 	gitignore "github.com/sabhiram/go-gitignore"
 	"github.com/spf13/cobra"
@@ -53,7 +56,10 @@ Examples:
 		force, _ := cmd.Flags().GetBool("force")
 		stage, _ := cmd.Flags().GetBool("stage")
 		noStage, _ := cmd.Flags().GetBool("no-stage")
+		noStream, _ := cmd.Flags().GetBool("no-stream")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
 		verbose := viper.GetBool("verbose")
+		out := streamOutput(noStream)
 
 		// Handle --no-diff and --no-stage flags
 		if noDiff {
@@ -74,6 +80,8 @@ Examples:
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 		cfg := &config.GlobalConfig
+		modelFlag, _ := cmd.Flags().GetString("model")
+		config.ApplyModelOverride(cfg, modelFlag)
 
 		// Get git diff for context
 		gitDiff, err := git.GetStagedDiff()
@@ -83,10 +91,11 @@ Examples:
 		}
 
 		// Initialize LLM client
-		client, err := llm.NewLLMClient(cfg)
+		client, finishUsage, err := newCommandClient("docs", cfg, !noCache)
 		if err != nil {
 			return fmt.Errorf("failed to initialize LLM client: %w", err)
 		}
+		defer finishUsage()
 
 		// Get target path (default to current directory if not specified)
 		targetPath := "."
@@ -127,75 +136,36 @@ Examples:
 				}
 
 				// Create documentation update prompt
-				updatePrompt := fmt.Sprintf(`
-You are an expert technical writer specializing in clear and accurate documentation.
-Your task is to update the provided documentation based on code changes, ensuring it remains accurate and helpful.
-
-USER'S DOCUMENTATION UPDATE GOAL:
-%s
-
-CONTEXT (Code Changes):
---- CONTEXT START ---
-%s
---- CONTEXT END ---
-
-TARGET DOCUMENTATION:
---- TARGET START ---
-%s
---- TARGET END ---
-
-IMPORTANT INSTRUCTIONS:
-1. Only update the documentation if necessary based on the code changes.
-2. Focus on changes to:
-   - Function signatures
-   - Parameters
-   - Return types
-   - Added/removed features
-   - Usage examples
-   - Clarifications based on code changes
-3. Do not make unnecessary changes or add speculative information.
-4. Preserve existing formatting and style.
-5. If no updates are needed, respond with exactly: NO_UPDATE_NEEDED
-
-OUTPUT FORMAT:
-If changes are needed, provide them in one of these formats:
-
-1. For replacing existing content:
---- LLMIFY REPLACE START ---
-<<< ORIGINAL >>>
-[The exact lines to be replaced]
-<<< REPLACEMENT >>>
-[The new lines to replace the original block]
---- LLMIFY REPLACE END ---
-
-2. For inserting new content:
---- LLMIFY INSERT_AFTER START ---
-<<< CONTEXT_LINE >>>
-[The exact line content *immediately preceding* the desired insertion point]
-<<< INSERTION >>>
-[The new lines to be inserted]
---- LLMIFY INSERT_AFTER END ---
-
-3. For deleting content:
---- LLMIFY DELETE START ---
-<<< CONTENT >>>
-[The exact lines to be deleted]
---- LLMIFY DELETE END ---
-
-If the changes are too extensive or complex for the edit format, provide the complete updated content enclosed in triple backticks:
-`+"```"+`markdown
-[Complete updated content]
-`+"```"+`
-`, prompt, gitDiff, string(content))
+				promptLang := language.Detect(filePathRel)
+				updatePrompt, err := llm.CreateDocsUpdatePrompt(prompts.Data{
+					Goal:      prompt,
+					Diff:      gitDiff,
+					Path:      filePathRel,
+					Target:    string(content),
+					Language:  promptLang,
+					Standards: standards.RulePrompts(filePathRel, promptLang),
+				})
+				if err != nil {
+					return err
+				}
+				updatePrompt = updatePrompt.WithModel(cfg.Docs.Model)
 
 				// Get LLM response
-				response, err := client.Generate(cmd.Context(), updatePrompt, cfg.LLM.Model)
+				if out != nil {
+					fmt.Fprintf(out, "\n--- Generating update for %s (Ctrl-C to cancel) ---\n", filePathRel)
+				}
+				resp, err := generateInterruptible(cmd.Context(), client, updatePrompt, out)
+				if err == errInterrupted {
+					return err // Stop walking; the user cancelled
+				}
 				if err != nil {
 					errors++
 					log.Printf("Error getting LLM response for %s: %v", filePathRel, err)
 					return nil
 				}
 
+				response := resp.Text
+
 				// Handle "NO_UPDATE_NEEDED" response
 				if strings.TrimSpace(response) == "NO_UPDATE_NEEDED" {
 					if verbose {
@@ -264,7 +234,9 @@ If the changes are too extensive or complex for the edit format, provide the com
 				return nil
 			})
 
-			if err != nil {
+			if err == errInterrupted {
+				fmt.Println("\nDocumentation update cancelled.")
+			} else if err != nil {
 				return fmt.Errorf("error walking project files: %w", err)
 			}
 
@@ -291,73 +263,35 @@ If the changes are too extensive or complex for the edit format, provide the com
 			}
 
 			// Create documentation update prompt
-			updatePrompt := fmt.Sprintf(`
-You are an expert technical writer specializing in clear and accurate documentation.
-Your task is to update the provided documentation based on code changes, ensuring it remains accurate and helpful.
-
-USER'S DOCUMENTATION UPDATE GOAL:
-%s
-
-CONTEXT (Code Changes):
---- CONTEXT START ---
-%s
---- CONTEXT END ---
-
-TARGET DOCUMENTATION:
---- TARGET START ---
-%s
---- TARGET END ---
-
-IMPORTANT INSTRUCTIONS:
-1. Only update the documentation if necessary based on the code changes.
-2. Focus on changes to:
-   - Function signatures
-   - Parameters
-   - Return types
-   - Added/removed features
-   - Usage examples
-   - Clarifications based on code changes
-3. Do not make unnecessary changes or add speculative information.
-4. Preserve existing formatting and style.
-5. If no updates are needed, respond with exactly: NO_UPDATE_NEEDED
-
-OUTPUT FORMAT:
-If changes are needed, provide them in one of these formats:
-
-1. For replacing existing content:
---- LLMIFY REPLACE START ---
-<<< ORIGINAL >>>
-[The exact lines to be replaced]
-<<< REPLACEMENT >>>
-[The new lines to replace the original block]
---- LLMIFY REPLACE END ---
-
-2. For inserting new content:
---- LLMIFY INSERT_AFTER START ---
-<<< CONTEXT_LINE >>>
-[The exact line content *immediately preceding* the desired insertion point]
-<<< INSERTION >>>
-[The new lines to be inserted]
---- LLMIFY INSERT_AFTER END ---
-
-3. For deleting content:
---- LLMIFY DELETE START ---
-<<< CONTENT >>>
-[The exact lines to be deleted]
---- LLMIFY DELETE END ---
-
-If the changes are too extensive or complex for the edit format, provide the complete updated content enclosed in triple backticks:
-`+"```"+`markdown
-[Complete updated content]
-`+"```"+`
-`, prompt, gitDiff, string(content))
+			promptLang := language.Detect(relPath)
+			updatePrompt, err := llm.CreateDocsUpdatePrompt(prompts.Data{
+				Goal:      prompt,
+				Diff:      gitDiff,
+				Path:      relPath,
+				Target:    string(content),
+				Language:  promptLang,
+				Standards: standards.RulePrompts(relPath, promptLang),
+			})
+			if err != nil {
+				return err
+			}
+			updatePrompt = updatePrompt.WithModel(cfg.Docs.Model)
 
 			// Get LLM response
-			response, err := client.Generate(cmd.Context(), updatePrompt, cfg.LLM.Model)
+			if out != nil {
+				fmt.Fprintf(out, "--- Generating update for %s (Ctrl-C to cancel) ---\n", relPath)
+			}
+			resp, err := generateInterruptible(cmd.Context(), client, updatePrompt, out)
+			if err == errInterrupted {
+				fmt.Println("Documentation update cancelled.")
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("failed to get LLM response: %w", err)
 			}
 
+			response := resp.Text
+
 			// Handle "NO_UPDATE_NEEDED" response
 			if strings.TrimSpace(response) == "NO_UPDATE_NEEDED" {
 				if verbose {
@@ -434,6 +368,9 @@ func init() {
 	docsCmd.Flags().BoolP("force", "f", false, "Apply changes without confirmation")
 	docsCmd.Flags().Bool("stage", true, "Stage modified files in git")
 	docsCmd.Flags().Bool("no-stage", false, "Do not stage modified files in git")
+	docsCmd.Flags().Bool("no-stream", false, "Do not render LLM output live while it is generated")
+	docsCmd.Flags().String("model", "", "Use this model (or provider:model) instead of the configured model and fallbacks")
+	docsCmd.Flags().Bool("no-cache", false, "Always query the LLM instead of reusing cached responses")
 }
 
 // confirmChanges prompts the user to confirm changes to a file
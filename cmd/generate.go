@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jake/llmify/internal/cache"
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/usage"
+)
+
+// interruptible returns a context that is cancelled on Ctrl-C so an in-flight
+// LLM request stops cleanly. Call stop as soon as the request finishes so that
+// Ctrl-C keeps its default behaviour at interactive prompts.
+func interruptible(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+// streamOutput returns where live LLM output is rendered, or nil when streaming is disabled.
+// Output goes to stderr so stdout stays clean for the final result.
+func streamOutput(noStream bool) io.Writer {
+	if noStream {
+		return nil
+	}
+	return os.Stderr
+}
+
+// generateInterruptible runs a (possibly streamed) generation that the user can cancel with Ctrl-C.
+func generateInterruptible(parent context.Context, client llm.LLMClient, req llm.Request, w io.Writer) (*llm.Response, error) {
+	ctx, stop := interruptible(parent)
+	defer stop()
+
+	resp, err := llm.GenerateLive(ctx, client, req, w)
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		return nil, errInterrupted
+	}
+	return resp, err
+}
+
+// errInterrupted is returned when the user cancels generation with Ctrl-C.
+var errInterrupted = fmt.Errorf("generation cancelled")
+
+// newCommandClient creates the configured LLM client for a command, with usage
+// tracking and, if useCache is set, the response cache in front of it.
+// Call finish when the command is done to print a usage summary to stderr and
+// append it to the usage ledger.
+func newCommandClient(command string, cfg *config.Config, useCache bool) (llm.LLMClient, func(), error) {
+	client, err := llm.NewLLMClient(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Cache hits never reach the tracker, so they are not counted as spend
+	tracker := usage.NewTracker(command, cfg.LLM.Provider)
+	client = tracker.Wrap(client)
+	if useCache && !cfg.Cache.Disabled {
+		store, err := cache.Open(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: response cache disabled: %v\n", err)
+		} else {
+			client = store.Wrap(client, cfg.LLM.Provider)
+		}
+	}
+
+	finish := func() {
+		prices := usage.LoadPrices(cfg)
+		tracker.WriteSummary(os.Stderr, prices)
+		if cfg.Usage.Disabled {
+			return
+		}
+		path, err := usage.LedgerPath(cfg)
+		if err == nil {
+			err = usage.AppendLedger(path, tracker.Entries(prices))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record usage: %v\n", err)
+		}
+	}
+	return client, finish, nil
+}
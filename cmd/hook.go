@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jake/llmify/internal/githook"
+	"github.com/spf13/cobra"
+)
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage the git hook that writes commit messages for plain git commit",
+	Long: `Installs a prepare-commit-msg hook so that "git commit" (including commits
+made from an IDE) opens with an llmify message already filled in. The hook runs
+"llmify commit --hook", which leaves the message alone for merges, squashes,
+amends, -m/-F messages and templates that already have content.
+
+core.hooksPath is respected. An existing prepare-commit-msg hook is kept and
+runs before llmify; uninstalling puts it back.`,
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the prepare-commit-msg hook",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate the llmify binary: %w", err)
+		}
+		if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+			exe = resolved
+		}
+		status, err := githook.Install(exe)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed %s\n", status.Path)
+		if status.Chained != "" {
+			fmt.Printf("The existing hook was moved to %s and still runs first.\n", status.Chained)
+		}
+		return nil
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the prepare-commit-msg hook",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		before, err := githook.Inspect()
+		if err != nil {
+			return err
+		}
+		if !before.Installed && !before.Foreign {
+			fmt.Println("The llmify hook is not installed.")
+			return nil
+		}
+		status, err := githook.Uninstall()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", before.Path)
+		if status.Foreign {
+			fmt.Printf("Restored the previous hook from %s\n", before.Chained)
+		}
+		return nil
+	},
+}
+
+var hookStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the prepare-commit-msg hook is installed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, err := githook.Inspect()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Hooks directory: %s", status.Dir)
+		if status.HooksPath != "" {
+			fmt.Printf(" (core.hooksPath = %s)", status.HooksPath)
+		}
+		fmt.Println()
+		switch {
+		case status.Installed:
+			fmt.Printf("Hook:            installed (%s)\n", status.Path)
+		case status.Foreign:
+			fmt.Printf("Hook:            not installed; another %s hook exists and will be kept\n", githook.Name)
+		default:
+			fmt.Println("Hook:            not installed (run: llmify hook install)")
+		}
+		if status.Chained != "" {
+			fmt.Printf("Previous hook:   %s (runs first)\n", status.Chained)
+		}
+		return nil
+	},
+}
+
+func init() {
+	hookCmd.AddCommand(hookInstallCmd, hookUninstallCmd, hookStatusCmd)
+	rootCmd.AddCommand(hookCmd)
+}
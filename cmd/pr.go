@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// prTemplatePaths are where GitHub looks for a pull request template, relative
+// to the repository root.
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+}
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Generate a pull request title and description for the current branch",
+	Long: `Generates a pull request title and description from the commits on the
+current branch and its diff against the merge base with --base. The body covers
+the summary, motivation, testing notes and risk areas. If the repository has a
+pull request template (e.g. .github/pull_request_template.md) its sections are
+filled in instead.
+
+The first line of the output is the title, followed by a blank line and the
+body. Nothing is sent to GitHub.
+
+Examples:
+  # Describe the current branch against main
+  llmify pr --base main
+
+  # Write the description to a file
+  llmify pr -o pr.md
+
+  # Use it with the GitHub CLI
+  llmify pr -o pr.md && gh pr create --title "$(head -n1 pr.md)" --body "$(tail -n +3 pr.md)"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose := viper.GetBool("verbose")
+		base, _ := cmd.Flags().GetString("base")
+		head, _ := cmd.Flags().GetString("head")
+		outputPath, _ := cmd.Flags().GetString("output")
+		templatePath, _ := cmd.Flags().GetString("template")
+		instruction, _ := cmd.Flags().GetString("prompt")
+		noStream, _ := cmd.Flags().GetBool("no-stream")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		modelFlag, _ := cmd.Flags().GetString("model")
+
+		if err := config.LoadConfig(); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		cfg := &config.GlobalConfig
+		config.ApplyModelOverride(cfg, modelFlag)
+
+		if base == "" {
+			base = git.GetDefaultBranch()
+		}
+		branch := head
+		if head == "HEAD" {
+			if current, err := git.GetCurrentBranch(); err == nil {
+				branch = current
+			}
+		}
+
+		// --- Collect the branch's commits and diff ---
+		mergeBase, err := git.GetMergeBase(base, head)
+		if err != nil {
+			return err
+		}
+		commits, err := git.GetCommitMessagesBetween(mergeBase, head)
+		if err != nil {
+			return err
+		}
+		diff, stat, err := git.GetDiffBetween(mergeBase, head)
+		if err != nil {
+			return err
+		}
+		if len(commits) == 0 && diff == "" {
+			return fmt.Errorf("%s has no changes compared to %s", branch, base)
+		}
+		fitted := fitDiff(diff, diffBudget(cfg, cfg.LLM.Model))
+		if verbose {
+			log.Printf("Describing %s against %s (merge base %s): %d commits", branch, base, mergeBase, len(commits))
+			log.Printf("Diff is %d tokens of a %d token budget, %d files collapsed", fitted.Tokens, fitted.Budget, len(fitted.Collapsed))
+		}
+
+		template, templateSource, err := readPRTemplate(templatePath)
+		if err != nil {
+			return err
+		}
+		if templateSource != "" && verbose {
+			log.Printf("Using pull request template %s", templateSource)
+		}
+
+		// --- Generate ---
+		client, finishUsage, err := newCommandClient("pr", cfg, !noCache)
+		if err != nil {
+			return fmt.Errorf("failed to initialize LLM client: %w", err)
+		}
+		defer finishUsage()
+
+		data := prompts.Data{
+			Context:     stat,
+			Commits:     commits,
+			Branch:      branch,
+			Base:        base,
+			Template:    template,
+			Instruction: instruction,
+		}
+		if err := fitted.Fill(cmd.Context(), client, cfg.LLM.Model, &data); err != nil {
+			if err == errInterrupted {
+				fmt.Fprintln(os.Stderr, "Pull request description cancelled.")
+				return nil
+			}
+			return err
+		}
+		req, err := llm.CreatePRPrompt(data)
+		if err != nil {
+			return err
+		}
+		req = req.WithModel(cfg.LLM.Model)
+
+		out := streamOutput(noStream)
+		if out != nil {
+			fmt.Fprintln(out, "Generating pull request description (Ctrl-C to cancel)...")
+		}
+		resp, err := generateInterruptible(cmd.Context(), client, req, out)
+		if err == errInterrupted {
+			fmt.Fprintln(os.Stderr, "Pull request description cancelled.")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to generate pull request description: %w", err)
+		}
+		if out != nil {
+			fmt.Fprintln(out)
+		}
+
+		title, body := splitPRDescription(resp.Text)
+		result := title + "\n\n" + body + "\n"
+		if outputPath == "" {
+			fmt.Print(result)
+			return nil
+		}
+		if err := os.WriteFile(outputPath, []byte(result), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", outputPath, err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote pull request description to %s\n", outputPath)
+		return nil
+	},
+}
+
+// readPRTemplate returns the pull request template at path or, if path is
+// empty, the first one found in the usual places. It returns "" if there is none.
+func readPRTemplate(path string) (string, string, error) {
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read pull request template: %w", err)
+		}
+		return string(content), path, nil
+	}
+	root, err := git.GetRepoRoot()
+	if err != nil {
+		return "", "", err
+	}
+	for _, candidate := range prTemplatePaths {
+		content, err := os.ReadFile(filepath.Join(root, candidate))
+		if err == nil {
+			return string(content), candidate, nil
+		}
+	}
+	return "", "", nil
+}
+
+// splitPRDescription separates the title line from the body, dropping labels
+// or markdown the LLM may have put around the title.
+func splitPRDescription(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```markdown")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = strings.TrimSpace(text)
+
+	title, body, _ := strings.Cut(text, "\n")
+	title = strings.TrimSpace(strings.TrimLeft(title, "# "))
+	for _, label := range []string{"Title:", "**Title:**", "title:"} {
+		title = strings.TrimSpace(strings.TrimPrefix(title, label))
+	}
+	return strings.Trim(title, "*`\""), strings.TrimSpace(body)
+}
+
+func init() {
+	prCmd.Flags().String("base", "", "Branch the pull request targets (default: the remote's default branch, main or master)")
+	prCmd.Flags().String("head", "HEAD", "Branch or commit to describe")
+	prCmd.Flags().StringP("output", "o", "", "Write the title and description to this file instead of stdout")
+	prCmd.Flags().String("template", "", "Pull request template to fill in (default: .github/pull_request_template.md if present)")
+	prCmd.Flags().String("prompt", "", "Extra instruction for the description, e.g. \"mention the config migration\"")
+	prCmd.Flags().Bool("no-stream", false, "Do not render the description live while it is generated")
+	prCmd.Flags().Bool("no-cache", false, "Always query the LLM instead of reusing cached responses")
+	prCmd.Flags().String("model", "", "Use this model (or provider:model) instead of the configured model and fallbacks")
+	rootCmd.AddCommand(prCmd)
+}
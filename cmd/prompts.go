@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/spf13/cobra"
+)
+
+var promptsShowRaw bool
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List, preview and validate prompt templates",
+	Long: `Prompts are Go text/template templates. Override one by creating
+.llmify/prompts/<name>.tmpl (see prompts.dir) or by setting
+prompts.templates.<name> in the config file.
+
+Templates can use these variables:
+  .Diff        Git diff of the changes
+  .Files       Changed files, each with .Path, .Content and .Deleted
+  .Goal        What the user asked for (docs and refactor)
+  .Language    Language of the target file, e.g. "go" or "markdown"
+  .Standards   Applicable rules from .llmify_standards.yaml (list of strings)
+  .Path        Path of the target file, or the files in a part of a diff (diff_summary)
+  .Target      The document or code being updated
+  .Context     Supporting context such as imports
+  .Style       Commit conventions learned from history (commit.style: learn)
+  .Examples    Representative commit messages from history
+  .Violations  Lint rules a generated commit message broke (commit_repair)
+  .Instruction Extra instruction given when regenerating commit messages
+  .Changes     Staged changes to group (commit_split), each with .ID, .Path and .Diff
+  .Commits     Commit messages, oldest first (pr, changelog)
+  .Branch      The branch being described (pr)
+  .Base        The branch it targets (pr)
+  .Template    The repository's pull request template, if any (pr)
+  .Entries     Changelog entries from each batch, as "Category: text" (changelog_merge)
+  .Summaries   Summaries of the parts of a diff too large to send whole; .Diff is then empty
+  .Collapsed   Lockfiles and generated files left out of .Diff, as one-line stats
+  .Scope       Part of the file a refactoring may change, e.g. "func:Parse" (refactor --scope)
+  .Failures    Verification commands a refactoring failed, each with its output (refactor_repair)
+
+and the functions join, lower, upper and trim.`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List prompt templates and where they are loaded from",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tDESCRIPTION")
+		for _, name := range prompts.Names() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, set.Source(name), prompts.Descriptions[name])
+		}
+		return w.Flush()
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Render a prompt template against sample data",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if _, ok := prompts.Descriptions[name]; !ok {
+			return fmt.Errorf("unknown prompt template %q (run 'llmify prompts list')", name)
+		}
+		if promptsShowRaw {
+			fmt.Print(set.Text(name))
+			return nil
+		}
+		rendered, err := set.Render(name, prompts.SampleData())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s (%s), rendered with sample data\n\n%s\n", name, set.Source(name), rendered)
+		return nil
+	},
+}
+
+var promptsValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that every prompt template parses and renders",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		set, err := loadPrompts()
+		if err != nil {
+			return err
+		}
+		failed := 0
+		for _, name := range prompts.Names() {
+			if _, err := set.Render(name, prompts.SampleData()); err != nil {
+				fmt.Printf("FAIL %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("ok   %s (%s)\n", name, set.Source(name))
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d prompt template(s) failed to render", failed)
+		}
+		return nil
+	},
+}
+
+func loadPrompts() (*prompts.Set, error) {
+	if err := config.LoadConfig(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return prompts.Active()
+}
+
+func init() {
+	promptsShowCmd.Flags().BoolVar(&promptsShowRaw, "raw", false, "Print the template source instead of rendering it")
+	promptsCmd.AddCommand(promptsListCmd, promptsShowCmd, promptsValidateCmd)
+	rootCmd.AddCommand(promptsCmd)
+}
@@ -1,17 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jake/llmify/internal/config"
-	"github.com/jake/llmify/internal/editor"
 	"github.com/jake/llmify/internal/git"
 	"github.com/jake/llmify/internal/language"
 	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/refactor"
+	"github.com/jake/llmify/internal/standards"
 	"github.com/jake/llmify/internal/tools"
 	"github.com/jake/llmify/internal/walker"
 	gitignore "github.com/sabhiram/go-gitignore"
@@ -29,8 +34,26 @@ Examples:
   # Refactor a single file
   llmify refactor src/process.ts --prompt "Convert to functional style"
 
+  # Refactor one function; the rest of the file is sent as read-only context
+  llmify refactor internal/editor/apply.go --scope func:ParseLLMResponse --prompt "Simplify"
+
+  # Other scopes: a class or type, or a range of lines
+  llmify refactor src/process.ts --scope class:Processor --prompt "Add logging"
+  llmify refactor src/process.ts --scope lines:40-90 --prompt "Extract a helper"
+
   # Refactor all TypeScript files in a directory
-  llmify refactor src/ --prompt "Add error handling"`,
+  llmify refactor src/ --prompt "Add error handling"
+
+Before a change to a Go file is written, it must parse, gofmt must accept it
+and its package must type check; problems are shown as file:line:col
+diagnostics. The file's compiler or type checker (go build and go vet,
+tsc --noEmit or mypy) also runs against it, if installed, in a temporary
+copy of the working tree, so your files are only written once a change
+passes. Setting refactor.verify.enabled runs the commands configured for the
+language in refactor.verify.commands instead. If a check fails, its output goes back to the LLM for a fix,
+up to refactor.verify.max_attempts times. A change that still fails
+is not applied; it is saved as a patch with the output of the checks under
+refactor.verify.patch_dir (.llmify/refactor). --no-verify skips the checks.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Get repository root
 		repoRoot, err := git.GetRepoRoot()
@@ -43,6 +66,8 @@ Examples:
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 		cfg := &config.GlobalConfig
+		modelFlag, _ := cmd.Flags().GetString("model")
+		config.ApplyModelOverride(cfg, modelFlag)
 
 		// Get git diff for context
 		diff, err := git.GetStagedDiff()
@@ -52,10 +77,31 @@ Examples:
 		}
 
 		// Initialize LLM client
-		client, err := llm.NewLLMClient(cfg)
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		client, finishUsage, err := newCommandClient("refactor", cfg, !noCache)
 		if err != nil {
 			return fmt.Errorf("failed to initialize LLM client: %w", err)
 		}
+		defer finishUsage()
+
+		noStream, _ := cmd.Flags().GetBool("no-stream")
+		out := streamOutput(noStream)
+		noVerify, _ := cmd.Flags().GetBool("no-verify")
+		verify := !noVerify
+
+		// Checks run in a private copy of the working tree. Copies left by
+		// runs that crashed are removed first, whether or not this run makes one.
+		if removed, err := refactor.RecoverWorkspaces(); err != nil {
+			log.Printf("Warning: %v", err)
+		} else if removed > 0 {
+			log.Printf("Removed %d verification workspace(s) left by an interrupted run.", removed)
+		}
+		workspace := refactor.NewWorkspace(repoRoot)
+		defer workspace.Close()
+		scope, _ := cmd.Flags().GetString("scope")
+		if scope != "" && len(args) == 0 {
+			return fmt.Errorf("--scope needs a single file to refactor")
+		}
 
 		// Process single file if specified
 		if len(args) > 0 {
@@ -83,39 +129,78 @@ Examples:
 				return fmt.Errorf("prompt is required for refactoring")
 			}
 
+			// Only the scoped part of the file is editable
+			target, err := refactor.SelectTarget(relPath, string(content), scope)
+			if err != nil {
+				return err
+			}
+
 			// Prepare context for LLM
-			context := fmt.Sprintf("File: %s\n\nContent:\n%s\n\nChanges:\n%s", relPath, string(content), diff)
+			context := fmt.Sprintf("File: %s\n\nStaged changes:\n%s", relPath, diff)
+			if !target.Whole() {
+				context = fmt.Sprintf("File: %s\n\n%s\n\nStaged changes:\n%s", relPath, target.Context, diff)
+			}
+			promptLang := language.Detect(relPath)
+			refactorPrompt, err := llm.CreateRefactorPrompt(prompts.Data{
+				Goal:      prompt,
+				Diff:      diff,
+				Path:      relPath,
+				Target:    target.Code,
+				Context:   context,
+				Language:  promptLang,
+				Standards: standards.RulePrompts(relPath, promptLang),
+				Scope:     target.Scope,
+			})
+			if err != nil {
+				return err
+			}
+			refactorPrompt = refactorPrompt.WithModel(cfg.LLM.Model)
 
 			// Get LLM response
-			response, err := client.Generate(cmd.Context(), context, cfg.LLM.Model)
+			if out != nil {
+				fmt.Fprintf(out, "--- Refactoring %s (Ctrl-C to cancel) ---\n", relPath)
+			}
+			resp, err := generateInterruptible(cmd.Context(), client, refactorPrompt, out)
+			if err == errInterrupted {
+				fmt.Println("Refactoring cancelled.")
+				return nil
+			}
 			if err != nil {
 				return fmt.Errorf("failed to get LLM response: %w", err)
 			}
 
-			// Apply changes using editor package
-			edits, fullContent, err := editor.ParseLLMResponse(response)
+			// Apply the edits or replacement to the target, keeping the rest of the file byte for byte
+			newContent, _, _, err := target.Apply(string(content), resp.Text)
 			if err != nil {
-				return fmt.Errorf("failed to parse LLM response: %w", err)
+				return fmt.Errorf("failed to apply edits: %w", err)
 			}
 
-			var newContent string
-			if fullContent != "" {
-				newContent = fullContent
-			} else if len(edits) > 0 {
-				newContent, err = editor.ApplyEdits(string(content), edits)
-				if err != nil {
-					return fmt.Errorf("failed to apply edits: %w", err)
+			if newContent != "" && newContent != string(content) {
+				if verify {
+					var passed bool
+					newContent, passed, err = verifyRefactor(cmd.Context(), client, cfg, workspace, refactorPrompt, resp.Text, target, repoRoot, relPath, string(content), newContent, out)
+					if err == errInterrupted {
+						fmt.Println("Refactoring cancelled.")
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					if !passed {
+						cmd.SilenceErrors, cmd.SilenceUsage = true, true
+						return exitError{code: 1}
+					}
 				}
-			}
-
-			if newContent != "" {
-				if err := os.WriteFile(absPath, []byte(newContent), 0644); err != nil {
+				lang := language.Detect(absPath)
+				if lang == "go" {
+					newContent = formatGo(relPath, newContent)
+				}
+				if err := refactor.WriteChange(absPath, string(content), newContent); err != nil {
 					return fmt.Errorf("failed to write changes: %w", err)
 				}
 
-				// Format and lint the file if tools are available
-				lang := language.Detect(absPath)
-				if formatter, linter := tools.GetToolForLanguage(lang); formatter != nil {
+				// Format and lint the file if tools are available; Go is already formatted
+				if formatter, linter := tools.GetToolForLanguage(lang); formatter != nil && lang != "go" {
 					if err := formatter.Format(absPath); err != nil {
 						log.Printf("Warning: Failed to format %s: %v", relPath, err)
 					}
@@ -166,45 +251,77 @@ Examples:
 			}
 
 			// Prepare context for LLM
-			context := fmt.Sprintf("File: %s\n\nContent:\n%s\n\nChanges:\n%s", filePathRel, string(content), diff)
+			context := fmt.Sprintf("File: %s\n\nStaged changes:\n%s", filePathRel, diff)
+			promptLang := language.Detect(filePathRel)
+			refactorPrompt, err := llm.CreateRefactorPrompt(prompts.Data{
+				Goal:      prompt,
+				Diff:      diff,
+				Path:      filePathRel,
+				Target:    string(content),
+				Context:   context,
+				Language:  promptLang,
+				Standards: standards.RulePrompts(filePathRel, promptLang),
+			})
+			if err != nil {
+				return err
+			}
+			refactorPrompt = refactorPrompt.WithModel(cfg.LLM.Model)
 
 			// Get LLM response
-			response, err := client.Generate(cmd.Context(), context, cfg.LLM.Model)
+			if out != nil {
+				fmt.Fprintf(out, "\n--- Refactoring %s (Ctrl-C to cancel) ---\n", filePathRel)
+			}
+			resp, err := generateInterruptible(cmd.Context(), client, refactorPrompt, out)
+			if err == errInterrupted {
+				return err // Stop walking; the user cancelled
+			}
 			if err != nil {
 				errors++
 				log.Printf("Error getting LLM response for %s: %v", filePathRel, err)
 				return nil
 			}
 
-			// Apply changes using editor package
-			edits, fullContent, err := editor.ParseLLMResponse(response)
+			// Apply the edits or replacement to the whole file
+			target, _ := refactor.SelectTarget(filePathRel, string(content), "")
+			newContent, _, _, err := target.Apply(string(content), resp.Text)
 			if err != nil {
 				errors++
-				log.Printf("Error parsing LLM response for %s: %v", filePathRel, err)
+				log.Printf("Error applying edits to %s: %v", filePathRel, err)
 				return nil
 			}
+			if newContent == string(content) {
+				newContent = ""
+			}
 
-			var newContent string
-			if fullContent != "" {
-				newContent = fullContent
-			} else if len(edits) > 0 {
-				newContent, err = editor.ApplyEdits(string(content), edits)
+			if newContent != "" && verify {
+				var passed bool
+				newContent, passed, err = verifyRefactor(cmd.Context(), client, cfg, workspace, refactorPrompt, resp.Text, target, repoRoot, filePathRel, string(content), newContent, out)
+				if err == errInterrupted {
+					return err
+				}
 				if err != nil {
 					errors++
-					log.Printf("Error applying edits to %s: %v", filePathRel, err)
+					log.Printf("Error verifying %s: %v", filePathRel, err)
+					return nil
+				}
+				if !passed {
+					errors++
 					return nil
 				}
 			}
 
 			if newContent != "" {
-				if err := os.WriteFile(absPath, []byte(newContent), 0644); err != nil {
+				if lang == "go" {
+					newContent = formatGo(filePathRel, newContent)
+				}
+				if err := refactor.WriteChange(absPath, string(content), newContent); err != nil {
 					errors++
 					log.Printf("Error writing changes to %s: %v", filePathRel, err)
 					return nil
 				}
 
-				// Format and lint the file if tools are available
-				if formatter, linter := tools.GetToolForLanguage(lang); formatter != nil {
+				// Format and lint the file if tools are available; Go is already formatted
+				if formatter, linter := tools.GetToolForLanguage(lang); formatter != nil && lang != "go" {
 					if err := formatter.Format(absPath); err != nil {
 						log.Printf("Warning: Failed to format %s: %v", filePathRel, err)
 					}
@@ -215,6 +332,13 @@ Examples:
 					}
 				}
 
+				// Later files are checked against this one as written
+				if written, err := os.ReadFile(absPath); err == nil {
+					if err := workspace.Update(filePathRel, string(written)); err != nil {
+						log.Printf("Warning: Failed to update the verification workspace: %v", err)
+					}
+				}
+
 				changed++
 				fmt.Printf("Refactored %s\n", filePathRel)
 			}
@@ -222,7 +346,9 @@ Examples:
 			return nil
 		})
 
-		if err != nil {
+		if err == errInterrupted {
+			fmt.Println("\nRefactoring cancelled.")
+		} else if err != nil {
 			return fmt.Errorf("error walking project files: %w", err)
 		}
 
@@ -237,10 +363,122 @@ Examples:
 	},
 }
 
+// formatGo formats a refactored Go file before it is written. A file gofmt
+// cannot format is written as it is, with the problem reported.
+func formatGo(relPath, content string) string {
+	formatted, diagnostics := refactor.FormatGo(relPath, content)
+	for _, d := range diagnostics {
+		fmt.Printf("Warning: %s\n", d)
+	}
+	return formatted
+}
+
+// verifyRefactor runs the verification commands for relPath against proposed,
+// the new content of the file, in workspace. While they fail, their output goes back to the
+// LLM as a follow-up to req and its reply, up to refactor.verify.max_attempts
+// times. It returns the last proposal and whether it passed; one that fails
+// is saved as a patch next to the output of the checks.
+func verifyRefactor(ctx context.Context, client llm.LLMClient, cfg *config.Config, workspace *refactor.Workspace, req llm.Request, reply string, target *refactor.Target, repoRoot, relPath, original, proposed string, out io.Writer) (string, bool, error) {
+	lang := language.Detect(relPath)
+	commands := refactor.Commands(cfg, lang, relPath)
+	// Go files are always parsed, formatted and type checked in process,
+	// which is quick and reports errors precisely
+	checkGo := lang == "go"
+	names := commands
+	if checkGo {
+		names = append([]string{refactor.GoCheckName}, commands...)
+	}
+	if len(names) == 0 {
+		if viper.GetBool("verbose") {
+			log.Printf("No verification commands configured for %s", relPath)
+		}
+		return proposed, true, nil
+	}
+	maxAttempts := cfg.Refactor.Verify.MaxAttempts
+
+	for attempt := 0; ; attempt++ {
+		fmt.Printf("Verifying %s: %s\n", relPath, strings.Join(names, "; "))
+		verifyCtx, stop := interruptible(ctx)
+		result := &refactor.Verification{}
+		if checkGo {
+			result.Checks = append(result.Checks, refactor.CheckGoChange(verifyCtx, repoRoot, relPath, proposed))
+		}
+		var err error
+		if len(commands) > 0 && result.Passed() && verifyCtx.Err() == nil {
+			var commandResult *refactor.Verification
+			commandResult, err = workspace.Verify(verifyCtx, relPath, original, proposed, commands, cfg.Refactor.Verify.Timeout)
+			if err == nil {
+				result.Checks = append(result.Checks, commandResult.Checks...)
+			}
+		}
+		interrupted := verifyCtx.Err() != nil
+		stop()
+		if interrupted {
+			return proposed, false, errInterrupted
+		}
+		if err != nil {
+			return proposed, false, err
+		}
+		for _, c := range result.Checks {
+			for _, d := range c.Diagnostics {
+				fmt.Printf("  %s\n", d)
+			}
+		}
+		if attempt == 0 {
+			for _, c := range result.Checks {
+				switch {
+				case c.Skipped && c.Builtin:
+					log.Printf("Warning: skipped the %s: %s", c.Command, c.Output)
+				case c.Skipped:
+					log.Printf("Warning: skipped %q, its program is not installed", c.Command)
+				}
+			}
+		}
+		if result.Passed() {
+			return proposed, true, nil
+		}
+
+		failed := strings.Join(result.Failed(), "; ")
+		if attempt < maxAttempts {
+			fmt.Printf("The refactoring of %s fails verification (%s), asking for a fix (%d/%d)...\n", relPath, failed, attempt+1, maxAttempts)
+			repairReq, err := llm.CreateRefactorRepairPrompt(req, reply, result.Failures(), target.Scope)
+			if err != nil {
+				return proposed, false, err
+			}
+			resp, err := generateInterruptible(ctx, client, repairReq, out)
+			if err == errInterrupted {
+				return proposed, false, err
+			}
+			if err != nil {
+				return proposed, false, fmt.Errorf("failed to get LLM response: %w", err)
+			}
+			// The fix applies to the proposal, not the original
+			fixed, _, _, err := target.Within(original, proposed).Apply(proposed, resp.Text)
+			if err == nil {
+				req, reply, proposed = repairReq, resp.Text, fixed
+				continue
+			}
+			log.Printf("Warning: could not apply the fix for %s: %v", relPath, err)
+		}
+
+		patchPath, logPath, err := refactor.SaveRejected(repoRoot, cfg.Refactor.Verify.PatchDir, relPath, original, proposed, result)
+		if err != nil {
+			return proposed, false, fmt.Errorf("failed to save the rejected refactoring of %s: %w", relPath, err)
+		}
+		fmt.Printf("Not applying the refactoring of %s: it fails verification (%s).\nThe change is saved in %s and the output of the checks in %s.\n", relPath, failed, patchPath, logPath)
+		return proposed, false, nil
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(refactorCmd)
 
 	// Add flags
 	refactorCmd.Flags().String("prompt", "", "Prompt describing the refactoring goal (required)")
+	refactorCmd.Flags().String("scope", "", "Only refactor part of the file: func:<name>, class:<name> or lines:<start>-<end>")
+	refactorCmd.Flags().Bool("no-verify", false, "Apply the refactoring without checking it")
+	refactorCmd.Flags().Bool("no-stream", false, "Do not render LLM output live while it is generated")
+	refactorCmd.Flags().String("model", "", "Use this model (or provider:model) instead of the configured model and fallbacks")
+	refactorCmd.Flags().Bool("no-cache", false, "Always query the LLM instead of reusing cached responses")
 	viper.BindPFlag("prompt", refactorCmd.Flags().Lookup("prompt"))
 }
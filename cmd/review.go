@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/language"
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/prompts"
+	"github.com/jake/llmify/internal/review"
+	"github.com/jake/llmify/internal/standards"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Output formats of llmify review.
+var reviewFormats = []string{"text", "json", "sarif"}
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Review staged changes or a branch with the LLM",
+	Long: `Reviews the staged changes (the default, or --staged) or the changes on the
+current branch since it left --base. Each changed file is reviewed on its own:
+its hunks, numbered with the line numbers of the file after the change, are sent
+together with the file's contents and any rules from .llmify_standards.yaml
+that apply to it. Lockfiles, generated, binary and deleted files are skipped.
+
+Every finding has a file, line, severity (low, medium, high, critical),
+category and suggested fix. Findings are printed as text, or as JSON or SARIF
+for CI and code scanning dashboards. With --fail-on, llmify exits with an
+error if any finding is at least that severe.
+
+Examples:
+  # Review what is about to be committed
+  llmify review
+
+  # Review a branch in CI and fail on serious problems
+  llmify review --base origin/main --format sarif -o review.sarif --fail-on high`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verbose := viper.GetBool("verbose")
+		base, _ := cmd.Flags().GetString("base")
+		head, _ := cmd.Flags().GetString("head")
+		format, _ := cmd.Flags().GetString("format")
+		outputPath, _ := cmd.Flags().GetString("output")
+		failOn, _ := cmd.Flags().GetString("fail-on")
+		instruction, _ := cmd.Flags().GetString("prompt")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		modelFlag, _ := cmd.Flags().GetString("model")
+
+		if !containsString(reviewFormats, format) {
+			return fmt.Errorf("unknown format %q (want one of %s)", format, strings.Join(reviewFormats, ", "))
+		}
+		if failOn != "" {
+			severity, err := review.ParseSeverity(failOn)
+			if err != nil {
+				return fmt.Errorf("invalid --fail-on: %w", err)
+			}
+			failOn = severity
+		}
+
+		if err := config.LoadConfig(); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		cfg := &config.GlobalConfig
+		config.ApplyModelOverride(cfg, modelFlag)
+
+		// --- Collect the changes ---
+		var patch string
+		var err error
+		if base != "" {
+			mergeBase, mbErr := git.GetMergeBase(base, head)
+			if mbErr != nil {
+				return mbErr
+			}
+			if verbose {
+				log.Printf("Reviewing %s against %s (merge base %s)", head, base, mergeBase)
+			}
+			patch, err = git.GetPatchBetween(mergeBase, head)
+		} else {
+			patch, err = git.GetStagedPatch()
+			if err != nil && strings.Contains(err.Error(), "no changes staged") {
+				fmt.Fprintln(os.Stderr, "No changes staged for review.")
+				return writeFindings(format, outputPath, nil)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		files, err := diff.Parse(patch)
+		if err != nil {
+			return fmt.Errorf("failed to parse diff: %w", err)
+		}
+
+		// --- One request per file, or per part of a file too large for one ---
+		repoRoot, err := git.GetRepoRoot()
+		if err != nil {
+			return err
+		}
+		budget := diffBudget(cfg, cfg.LLM.Model)
+		count := tokenCounter()
+		var reqs []llm.Request
+		var reqFiles []*diff.File
+		for _, f := range files {
+			if skip := reviewSkipReason(f); skip != "" {
+				if verbose {
+					log.Printf("Skipping %s: %s", f.Path(), skip)
+				}
+				continue
+			}
+			section := diff.Section{Path: f.Path(), Header: strings.Join(f.Header, "\n")}
+			for _, h := range f.Hunks {
+				section.Hunks = append(section.Hunks, review.Annotate(h))
+			}
+			lang := language.Detect(f.Path())
+			for _, chunk := range diff.Chunks([]diff.Section{section}, budget, count) {
+				contextFiles, _ := gatherContext(repoRoot, []string{f.Path()}, min(100*1000, (budget-count(chunk.Diff))*charsPerToken), nil)
+				req, err := llm.CreateReviewPrompt(prompts.Data{
+					Diff:        chunk.Diff,
+					Files:       contextFiles,
+					Path:        f.Path(),
+					Language:    lang,
+					Standards:   standards.RulePrompts(f.Path(), lang),
+					Instruction: instruction,
+				})
+				if err != nil {
+					return err
+				}
+				reqs = append(reqs, req.WithModel(cfg.LLM.Model))
+				reqFiles = append(reqFiles, f)
+			}
+		}
+		if len(reqs) == 0 {
+			fmt.Fprintln(os.Stderr, "Nothing to review: every changed file is generated, binary or deleted.")
+			return writeFindings(format, outputPath, nil)
+		}
+
+		// --- Review ---
+		client, finishUsage, err := newCommandClient("review", cfg, !noCache)
+		if err != nil {
+			return fmt.Errorf("failed to initialize LLM client: %w", err)
+		}
+		defer finishUsage()
+
+		fmt.Fprintf(os.Stderr, "Reviewing %d file(s) in %d request(s) (Ctrl-C to cancel)...\n", countFiles(reqFiles), len(reqs))
+		replies, err := generateParallel(cmd.Context(), client, reqs)
+		if err == errInterrupted {
+			fmt.Fprintln(os.Stderr, "Review cancelled.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var findings []review.Finding
+		for i, reply := range replies {
+			parsed, err := review.ParseFindings(reply, reqFiles[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", reqFiles[i].Path(), err)
+			}
+			findings = append(findings, parsed...)
+		}
+		review.Sort(findings)
+
+		if err := writeFindings(format, outputPath, findings); err != nil {
+			return err
+		}
+		if failOn != "" {
+			if blocking := review.AtLeast(findings, failOn); len(blocking) > 0 {
+				// stdout may hold JSON or SARIF, so the reason goes to stderr only
+				fmt.Fprintf(os.Stderr, "Review failed: %d finding(s) of severity %s or higher.\n", len(blocking), failOn)
+				cmd.SilenceErrors, cmd.SilenceUsage = true, true
+				return exitError{code: 1}
+			}
+		}
+		return nil
+	},
+}
+
+// reviewSkipReason says why a file is not reviewed, or returns "" if it is.
+func reviewSkipReason(f *diff.File) string {
+	switch {
+	case f.Binary:
+		return "binary file"
+	case f.NewPath == "":
+		return "deleted"
+	case len(f.Hunks) == 0:
+		return "no content changes"
+	}
+	hunks := make([]string, len(f.Hunks))
+	for i, h := range f.Hunks {
+		hunks[i] = h.String()
+	}
+	if diff.IsGenerated(f.Path(), hunks) {
+		return "lockfile or generated file"
+	}
+	return ""
+}
+
+// writeFindings writes findings in format to outputPath, or stdout if it is empty.
+func writeFindings(format, outputPath string, findings []review.Finding) error {
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outputPath, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	switch format {
+	case "json":
+		err = review.WriteJSON(w, findings)
+	case "sarif":
+		err = review.WriteSARIF(w, findings)
+	default:
+		err = review.WriteText(w, findings)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write findings: %w", err)
+	}
+	if outputPath != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d finding(s) to %s\n", len(findings), outputPath)
+	}
+	return nil
+}
+
+// countFiles counts the distinct files in a list with repeats.
+func countFiles(files []*diff.File) int {
+	seen := make(map[*diff.File]bool)
+	for _, f := range files {
+		seen[f] = true
+	}
+	return len(seen)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	reviewCmd.Flags().Bool("staged", false, "Review the staged changes (the default)")
+	reviewCmd.Flags().String("base", "", "Review the changes since the merge base with this branch instead of the staged changes")
+	reviewCmd.Flags().String("head", "HEAD", "Branch or commit to review with --base")
+	reviewCmd.Flags().String("format", "text", "Output format: text, json or sarif")
+	reviewCmd.Flags().StringP("output", "o", "", "Write the findings to this file instead of stdout")
+	reviewCmd.Flags().String("fail-on", "", "Exit with an error if any finding has this severity or higher (low, medium, high, critical)")
+	reviewCmd.Flags().String("prompt", "", "Extra instruction for the review, e.g. \"focus on SQL injection\"")
+	reviewCmd.Flags().Bool("no-cache", false, "Always query the LLM instead of reusing cached responses")
+	reviewCmd.Flags().String("model", "", "Use this model (or provider:model) instead of the configured model and fallbacks")
+	reviewCmd.MarkFlagsMutuallyExclusive("staged", "base")
+	rootCmd.AddCommand(reviewCmd)
+}
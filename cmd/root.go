@@ -1,11 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/jake/llmify/internal/crawler"
+	"github.com/jake/llmify/internal/tokenizer"
 	"github.com/jake/llmify/internal/util"
 	"github.com/spf13/cobra"
 )
@@ -21,6 +23,11 @@ var (
 	excludeBinary bool
 	verbose       bool
 	includeHeader bool
+	maxTokens     int
+	tokenizerName string
+	overflowMode  string
+	tokenReport   bool
+	outputFormat  string
 )
 
 var rootCmd = &cobra.Command{
@@ -99,8 +106,30 @@ respecting .gitignore and .llmignore patterns.`,
 			return fmt.Errorf("crawling project: %w", err)
 		}
 
+		// Fit content into the token budget if requested
+		var report *crawler.TokenReport
+		if maxTokens > 0 || tokenReport {
+			tok, err := tokenizer.Get(tokenizerName)
+			if err != nil {
+				return err
+			}
+			report, err = crawler.ApplyTokenBudget(result, crawler.BudgetOptions{
+				MaxTokens:     maxTokens,
+				Tokenizer:     tok,
+				Overflow:      overflowMode,
+				IncludeHeader: includeHeader,
+				Format:        outputFormat,
+			})
+			if err != nil {
+				return fmt.Errorf("applying token budget: %w", err)
+			}
+		}
+
 		// Build output content
-		content := crawler.BuildOutputContent(result, includeHeader)
+		content, err := crawler.BuildOutputContent(result, outputFormat, includeHeader)
+		if err != nil {
+			return fmt.Errorf("building output: %w", err)
+		}
 
 		// Write to file
 		if outputFile == "" {
@@ -115,6 +144,12 @@ respecting .gitignore and .llmignore patterns.`,
 				outputFile, result.IncludedCount, result.ExcludedCount)
 		}
 
+		// Per-file token report to help tune .llmignore
+		if report != nil {
+			fmt.Println()
+			report.Write(os.Stdout)
+		}
+
 		// Copy output to clipboard if requested
 		if err := util.CopyToClipboard(content); err != nil {
 			return fmt.Errorf("copying to clipboard: %w", err)
@@ -136,13 +171,28 @@ func init() {
 	rootCmd.Flags().BoolVar(&excludeBinary, "exclude-binary", true, "Exclude binary files")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.Flags().BoolVar(&includeHeader, "include-header", true, "Include header in output")
+	rootCmd.Flags().StringVar(&outputFormat, "format", crawler.FormatText, "Output format: text, xml, json or markdown")
+	rootCmd.Flags().IntVar(&maxTokens, "max-tokens", 0, "Maximum tokens in the output (0 for unlimited)")
+	rootCmd.Flags().StringVar(&tokenizerName, "tokenizer", tokenizer.DefaultTokenizer, "Tokenizer used for counting (cl100k, o200k, approx)")
+	rootCmd.Flags().StringVar(&overflowMode, "overflow", crawler.OverflowTruncate, "How to handle files over the token budget: drop, truncate or summarize")
+	rootCmd.Flags().BoolVar(&tokenReport, "token-report", false, "Print a per-file token report even without --max-tokens")
 
 	// Add the commit command
 	rootCmd.AddCommand(CommitCmd)
 }
 
+// exitError ends llmify with an exit code and no further output, for commands
+// that have already reported why they fail, such as review --fail-on.
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageSinceDays int
+	usageGroupBy   string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report LLM token usage and estimated cost",
+	Long: `Summarizes the usage ledger written by commit, docs and refactor.
+Each run records the tokens used per model and their estimated cost, using
+built-in list prices or the prices configured under "pricing".`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.LoadConfig(); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		cfg := &config.GlobalConfig
+
+		path, err := usage.LedgerPath(cfg)
+		if err != nil {
+			return err
+		}
+
+		var since time.Time
+		if usageSinceDays > 0 {
+			since = time.Now().AddDate(0, 0, -usageSinceDays)
+		}
+		entries, err := usage.ReadLedger(path, since)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No usage recorded in %s\n", path)
+			return nil
+		}
+
+		fields, err := usageGroupFields(usageGroupBy)
+		if err != nil {
+			return err
+		}
+		writeUsageReport(os.Stdout, entries, fields)
+		return nil
+	},
+}
+
+// usageGroupFields parses --group-by into the fields each report row is keyed on.
+func usageGroupFields(groupBy string) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(groupBy, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch f {
+		case "day", "model", "command":
+			fields = append(fields, f)
+		case "":
+		default:
+			return nil, fmt.Errorf("invalid --group-by field %q (use day, model and/or command)", f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("--group-by needs at least one of day, model or command")
+	}
+	return fields, nil
+}
+
+func usageField(e usage.Entry, field string) string {
+	switch field {
+	case "day":
+		return e.Time.Local().Format("2006-01-02")
+	case "model":
+		return e.Model
+	default:
+		return e.Command
+	}
+}
+
+func writeUsageReport(out io.Writer, entries []usage.Entry, fields []string) {
+	type row struct {
+		key                     []string
+		calls, prompt, complete int
+		cost                    float64
+		estimated               bool
+	}
+	rows := map[string]*row{}
+	var keys []string
+	total := row{key: []string{"TOTAL"}}
+	for i := 1; i < len(fields); i++ {
+		total.key = append(total.key, "")
+	}
+	for _, e := range entries {
+		var key []string
+		for _, f := range fields {
+			key = append(key, usageField(e, f))
+		}
+		id := strings.Join(key, "\x00")
+		r, ok := rows[id]
+		if !ok {
+			r = &row{key: key}
+			rows[id] = r
+			keys = append(keys, id)
+		}
+		for _, agg := range []*row{r, &total} {
+			agg.calls += e.Calls
+			agg.prompt += e.PromptTokens
+			agg.complete += e.CompletionTokens
+			agg.cost += e.Cost
+			agg.estimated = agg.estimated || e.Estimated
+		}
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tCALLS\tPROMPT\tCOMPLETION\tCOST (USD)\n", strings.ToUpper(strings.Join(fields, "\t")))
+	printRow := func(r *row) {
+		mark := ""
+		if r.estimated {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.4f%s\n", strings.Join(r.key, "\t"), r.calls, r.prompt, r.complete, r.cost, mark)
+	}
+	for _, k := range keys {
+		printRow(rows[k])
+	}
+	printRow(&total)
+	w.Flush()
+	if total.estimated {
+		fmt.Fprintln(out, "* includes token counts estimated locally")
+	}
+}
+
+func init() {
+	usageCmd.Flags().IntVar(&usageSinceDays, "since", 30, "Only include usage from the last N days (0 for all)")
+	usageCmd.Flags().StringVar(&usageGroupBy, "group-by", "day,model,command", "Comma-separated fields to group usage by: day, model, command")
+	rootCmd.AddCommand(usageCmd)
+}
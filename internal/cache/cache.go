@@ -0,0 +1,292 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/llm"
+)
+
+const lockFileName = ".lock"
+
+// Store is an on-disk, content-addressed cache of LLM responses. Entries live
+// in <dir>/<first 2 hash chars>/<hash>.json. All access goes through a lock
+// file in dir, so several llmify processes can share one cache.
+type Store struct {
+	dir      string
+	ttl      time.Duration // 0 means entries never expire
+	maxBytes int64         // 0 means no size limit
+}
+
+// entry is the on-disk format of a cached response.
+type entry struct {
+	Created  time.Time    `json:"created"`
+	Provider string       `json:"provider"`
+	Model    string       `json:"model"`
+	Response llm.Response `json:"response"`
+}
+
+// Stats describes the contents of the cache.
+type Stats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// NewStore creates a cache in dir.
+func NewStore(dir string, ttl time.Duration, maxBytes int64) *Store {
+	return &Store{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+// Open returns the cache configured in cfg.
+func Open(cfg *config.Config) (*Store, error) {
+	dir, err := Dir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(dir, cfg.Cache.TTL, int64(cfg.Cache.MaxSizeMB)*1024*1024), nil
+}
+
+// Dir returns the cache directory from config, falling back to the user cache
+// directory (e.g. ~/.cache/llmify/responses).
+func Dir(cfg *config.Config) (string, error) {
+	if cfg.Cache.Dir != "" {
+		return cfg.Cache.Dir, nil
+	}
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory: %w", err)
+	}
+	return filepath.Join(base, "llmify", "responses"), nil
+}
+
+// Key returns the cache key of a request sent to provider. Prompts are
+// normalized (line endings and trailing whitespace) so that insignificant
+// differences still hit the cache.
+func Key(provider string, req llm.Request) string {
+	normalized := req
+	normalized.System = normalize(req.System)
+	normalized.Messages = make([]llm.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		normalized.Messages[i] = llm.Message{Role: m.Role, Content: normalize(m.Content)}
+	}
+	data, _ := json.Marshal(struct {
+		Provider string
+		Request  llm.Request
+	}{strings.ToLower(provider), normalized})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalize(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key[:2], key+".json")
+}
+
+// withLock runs fn while holding the cache lock.
+func (s *Store) withLock(exclusive bool, fn func() error) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open cache lock: %w", err)
+	}
+	defer f.Close()
+	if err := lockFile(f, exclusive); err != nil {
+		return fmt.Errorf("failed to lock cache: %w", err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+func (s *Store) expired(created time.Time) bool {
+	return s.ttl > 0 && time.Since(created) > s.ttl
+}
+
+// Get returns the cached response for key, or nil if there is none or it expired.
+func (s *Store) Get(key string) (*llm.Response, error) {
+	var result *llm.Response
+	err := s.withLock(false, func() error {
+		data, err := os.ReadFile(s.path(key))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil || s.expired(e.Created) {
+			return nil // Corrupt or expired entries are misses; eviction removes them
+		}
+		result = &e.Response
+		return nil
+	})
+	return result, err
+}
+
+// Put stores a response and evicts entries to stay within the size limit.
+func (s *Store) Put(key, provider string, req llm.Request, resp *llm.Response) error {
+	data, err := json.Marshal(entry{Created: time.Now(), Provider: provider, Model: req.Model, Response: *resp})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+	return s.withLock(true, func() error {
+		path := s.path(key)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		// Write to a temp file and rename so readers never see a partial entry
+		tmp, err := os.CreateTemp(filepath.Dir(path), "tmp-*")
+		if err != nil {
+			return fmt.Errorf("failed to write cache entry: %w", err)
+		}
+		_, writeErr := tmp.Write(data)
+		closeErr := tmp.Close()
+		if writeErr != nil || closeErr != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to write cache entry: %v", firstErr(writeErr, closeErr))
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to write cache entry: %w", err)
+		}
+		return s.evictLocked()
+	})
+}
+
+type fileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// listLocked returns all cache entries. The caller must hold the lock.
+func (s *Store) listLocked() ([]fileInfo, error) {
+	var files []fileInfo
+	err := filepath.WalkDir(s.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // Removed concurrently
+		}
+		files = append(files, fileInfo{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// evictLocked removes expired entries, then the oldest entries until the cache
+// fits in maxBytes. The caller must hold the exclusive lock.
+func (s *Store) evictLocked() error {
+	files, err := s.listLocked()
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	var total int64
+	var kept []fileInfo
+	for _, f := range files {
+		if s.expired(f.modTime) {
+			os.Remove(f.path)
+			continue
+		}
+		total += f.size
+		kept = append(kept, f)
+	}
+	for _, f := range kept {
+		if s.maxBytes <= 0 || total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+// Stats reports the number and size of cached entries.
+func (s *Store) Stats() (Stats, error) {
+	stats := Stats{Dir: s.dir}
+	err := s.withLock(false, func() error {
+		files, err := s.listLocked()
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			stats.Entries++
+			stats.Bytes += f.size
+			if s.expired(f.modTime) {
+				stats.Expired++
+			}
+			if stats.Oldest.IsZero() || f.modTime.Before(stats.Oldest) {
+				stats.Oldest = f.modTime
+			}
+			if f.modTime.After(stats.Newest) {
+				stats.Newest = f.modTime
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Clear removes cached entries and returns how many were removed. With
+// expiredOnly, only entries older than the TTL are removed.
+func (s *Store) Clear(expiredOnly bool) (int, error) {
+	removed := 0
+	err := s.withLock(true, func() error {
+		files, err := s.listLocked()
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if expiredOnly && !s.expired(f.modTime) {
+				continue
+			}
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", f.path, err)
+			}
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
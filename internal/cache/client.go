@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jake/llmify/internal/llm"
+	"github.com/spf13/viper"
+)
+
+// Wrap returns an LLMClient that answers repeated requests from the cache.
+// Cache failures are never fatal: the request simply goes to the provider.
+func (s *Store) Wrap(client llm.LLMClient, provider string) llm.LLMClient {
+	return &cachingClient{next: client, store: s, provider: provider}
+}
+
+// cachingClient decorates an LLMClient with the response cache.
+type cachingClient struct {
+	next     llm.LLMClient
+	store    *Store
+	provider string
+}
+
+func (c *cachingClient) lookup(req llm.Request) (string, *llm.Response) {
+	key := Key(c.provider, req)
+	resp, err := c.store.Get(key)
+	if err != nil {
+		c.warn(err)
+		return key, nil
+	}
+	if resp != nil && viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "Using cached response %s\n", key[:12])
+	}
+	return key, resp
+}
+
+func (c *cachingClient) save(key string, req llm.Request, resp *llm.Response) {
+	if resp.Text == "" {
+		return // Don't cache empty responses; they are usually transient failures
+	}
+	if err := c.store.Put(key, c.provider, req, resp); err != nil {
+		c.warn(err)
+	}
+}
+
+func (c *cachingClient) warn(err error) {
+	fmt.Fprintf(os.Stderr, "Warning: response cache unavailable: %v\n", err)
+}
+
+func (c *cachingClient) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	key, cached := c.lookup(req)
+	if cached != nil {
+		return cached, nil
+	}
+	resp, err := c.next.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.save(key, req, resp)
+	return resp, nil
+}
+
+func (c *cachingClient) GenerateStream(ctx context.Context, req llm.Request) (<-chan llm.Chunk, error) {
+	key, cached := c.lookup(req)
+	if cached != nil {
+		return llm.StreamResponse(ctx, cached), nil
+	}
+	in, err := c.next.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forward the stream, caching the assembled response only if it
+	// completes, so a cancelled or failed response is never served later
+	out := make(chan llm.Chunk)
+	go func() {
+		defer close(out)
+		resp, err := llm.Relay(ctx, in, out)
+		if err == nil && resp.FinishReason != "" {
+			c.save(key, req, resp)
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jake/llmify/internal/llm"
+)
+
+// scriptedClient streams a fixed list of chunks.
+type scriptedClient struct {
+	chunks []llm.Chunk
+}
+
+func (c *scriptedClient) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return nil, errors.New("not used")
+}
+
+func (c *scriptedClient) GenerateStream(ctx context.Context, req llm.Request) (<-chan llm.Chunk, error) {
+	ch := make(chan llm.Chunk, len(c.chunks))
+	for _, chunk := range c.chunks {
+		ch <- chunk
+	}
+	close(ch)
+	return ch, nil
+}
+
+func TestStreamCachedOnlyWhenComplete(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []llm.Chunk
+		cancel  bool
+		cached  bool
+		wantErr bool
+	}{
+		{
+			name:   "complete",
+			chunks: []llm.Chunk{{Text: "hello "}, {Text: "world"}, {FinishReason: "stop"}},
+			cached: true,
+		},
+		{
+			name:    "cancelled",
+			chunks:  []llm.Chunk{{Text: "hel"}, {Err: context.Canceled}},
+			cancel:  true,
+			wantErr: true,
+		},
+		{
+			name:    "closed after cancel without an error",
+			chunks:  []llm.Chunk{{Text: "hel"}},
+			cancel:  true,
+			wantErr: true,
+		},
+		{
+			name:    "failed",
+			chunks:  []llm.Chunk{{Text: "hel"}, {Err: errors.New("connection reset")}},
+			wantErr: true,
+		},
+		{
+			name:   "no finish reason",
+			chunks: []llm.Chunk{{Text: "hello"}},
+		},
+	}
+	req := llm.Request{Model: "m", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(t.TempDir(), time.Hour, 1<<20)
+			client := store.Wrap(&scriptedClient{chunks: tt.chunks}, "test")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			ch, err := client.GenerateStream(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := llm.CollectStream(ctx, ch, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, tt.wantErr)
+			}
+
+			// The entry is written before the stream is closed
+			resp, err := store.Get(Key("test", req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (resp != nil) != tt.cached {
+				t.Fatalf("cached = %v, want %v", resp != nil, tt.cached)
+			}
+			if tt.cached && resp.Text != "hello world" {
+				t.Errorf("cached text = %q", resp.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Package changelog builds Keep a Changelog (https://keepachangelog.com)
+// sections from git history. Commits are pre-sorted by their Conventional
+// Commits type; the LLM rewrites them in user-facing terms, classifies the
+// rest and, for large ranges, merges the per-batch results.
+package changelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/llm"
+)
+
+// Keep a Changelog categories, in the order they are written.
+const (
+	Added      = "Added"
+	Changed    = "Changed"
+	Deprecated = "Deprecated"
+	Removed    = "Removed"
+	Fixed      = "Fixed"
+	Security   = "Security"
+)
+
+// Categories lists the categories in the order they are written.
+var Categories = []string{Added, Changed, Deprecated, Removed, Fixed, Security}
+
+// Internal marks commits that do not belong in a changelog, such as CI or
+// test changes.
+const Internal = "Internal"
+
+// typeCategories maps Conventional Commits types to categories.
+var typeCategories = map[string]string{
+	"feat":      Added,
+	"fix":       Fixed,
+	"perf":      Changed,
+	"revert":    Changed,
+	"security":  Security,
+	"deprecate": Deprecated,
+	"remove":    Removed,
+	"refactor":  Internal,
+	"chore":     Internal,
+	"ci":        Internal,
+	"build":     Internal,
+	"test":      Internal,
+	"tests":     Internal,
+	"style":     Internal,
+	"docs":      Internal,
+}
+
+var conventionalRe = regexp.MustCompile(`^(\w+)(\([^)]*\))?(!)?: `)
+
+// Commit is a commit to describe, with the category its message implies.
+type Commit struct {
+	git.LogEntry
+	Category string // Category implied by the commit type, or "" if the LLM must decide
+	Breaking bool
+}
+
+// Classify sorts commits by their Conventional Commits type. Breaking changes
+// always count as Changed, even for internal types.
+func Classify(entries []git.LogEntry) []Commit {
+	commits := make([]Commit, len(entries))
+	for i, e := range entries {
+		c := Commit{LogEntry: e}
+		if m := conventionalRe.FindStringSubmatch(e.Subject); m != nil {
+			c.Category = typeCategories[strings.ToLower(m[1])]
+			c.Breaking = m[3] == "!"
+		}
+		if strings.Contains(e.Body, "BREAKING CHANGE") || strings.Contains(e.Body, "BREAKING-CHANGE") {
+			c.Breaking = true
+		}
+		if c.Breaking {
+			c.Category = Changed
+		}
+		commits[i] = c
+	}
+	return commits
+}
+
+// String renders the commit for a prompt: hash, category hint, subject and body.
+func (c Commit) String() string {
+	hint := c.Category
+	if hint == "" {
+		hint = "classify"
+	}
+	if c.Breaking {
+		hint += ", breaking"
+	}
+	s := fmt.Sprintf("%s [%s] %s", c.Hash, hint, c.Subject)
+	if c.Body != "" {
+		s += "\n" + c.Body
+	}
+	return s
+}
+
+// Batches splits commits into groups of at most maxCommits commits and
+// roughly maxChars characters, for the map step.
+func Batches(commits []Commit, maxCommits, maxChars int) [][]Commit {
+	var batches [][]Commit
+	var current []Commit
+	size := 0
+	for _, c := range commits {
+		n := len(c.String())
+		if len(current) > 0 && (len(current) >= maxCommits || size+n > maxChars) {
+			batches = append(batches, current)
+			current, size = nil, 0
+		}
+		current = append(current, c)
+		size += n
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// Entry is one changelog line.
+type Entry struct {
+	Category string `json:"category"`
+	Text     string `json:"text"`
+}
+
+// String renders the entry for a prompt.
+func (e Entry) String() string {
+	return e.Category + ": " + e.Text
+}
+
+// ParseEntries reads the LLM's JSON reply. Entries with an unknown or internal
+// category are dropped.
+func ParseEntries(text string) ([]Entry, error) {
+	var reply struct {
+		Entries []Entry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(llm.ExtractJSON(text)), &reply); err != nil {
+		return nil, fmt.Errorf("could not parse changelog entries: %w", err)
+	}
+	var entries []Entry
+	for _, e := range reply.Entries {
+		category := normalizeCategory(e.Category)
+		text := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(e.Text), "-* "))
+		if category == "" || text == "" {
+			continue
+		}
+		entries = append(entries, Entry{Category: category, Text: text})
+	}
+	return entries, nil
+}
+
+func normalizeCategory(category string) string {
+	for _, c := range Categories {
+		if strings.EqualFold(strings.TrimSpace(category), c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// Heading returns the section heading for version, e.g. "## [1.3.0] - 2026-01-31".
+// An empty version is written as Unreleased.
+func Heading(version, date string) string {
+	if version == "" {
+		return "## [Unreleased]"
+	}
+	heading := fmt.Sprintf("## [%s]", strings.TrimPrefix(version, "v"))
+	if date != "" {
+		heading += " - " + date
+	}
+	return heading
+}
+
+// Render writes a changelog section with the entries grouped by category.
+func Render(heading string, entries []Entry) string {
+	var b strings.Builder
+	b.WriteString(heading + "\n")
+	for _, category := range Categories {
+		var lines []string
+		for _, e := range entries {
+			if e.Category == category {
+				lines = append(lines, "- "+e.Text)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n%s\n", category, strings.Join(lines, "\n"))
+	}
+	return b.String()
+}
+
+// header starts a new changelog file.
+const header = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// Insert adds section to an existing changelog. A section with the same
+// version is replaced. Otherwise the new section goes above the newest one,
+// except that an Unreleased section stays on top, as Keep a Changelog orders
+// them. Headings inside code fences are ignored. An empty existing changelog
+// gets a standard header.
+func Insert(existing, section string) string {
+	section = strings.TrimRight(section, "\n")
+	if strings.TrimSpace(existing) == "" {
+		return header + "\n" + section + "\n"
+	}
+
+	lines := strings.Split(existing, "\n")
+	heading := strings.SplitN(section, "\n", 2)[0]
+	headings := sectionHeadings(lines)
+	for k, i := range headings {
+		if !sameVersion(lines[i], heading) {
+			continue
+		}
+		// Regenerating a version replaces its section up to the next one
+		end := len(lines)
+		if k+1 < len(headings) {
+			end = headings[k+1]
+		}
+		for end > i+1 && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		return splice(lines, i, end, section)
+	}
+
+	released := headingVersion(heading) != unreleased
+	for _, i := range headings {
+		if released && headingVersion(lines[i]) == unreleased {
+			continue
+		}
+		return splice(lines, i, i, section+"\n")
+	}
+	// No versions yet, or only an Unreleased section: append
+	return strings.TrimRight(existing, "\n") + "\n\n" + section + "\n"
+}
+
+// unreleased is the version of an Unreleased heading, see headingVersion.
+const unreleased = "unreleased"
+
+// sectionHeadings returns the indexes of the "## " lines outside code fences.
+func sectionHeadings(lines []string) []int {
+	var headings []int
+	fenced := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fenced = !fenced
+		case !fenced && strings.HasPrefix(line, "## "):
+			headings = append(headings, i)
+		}
+	}
+	return headings
+}
+
+// splice replaces lines[start:end] with text.
+func splice(lines []string, start, end int, text string) string {
+	parts := append(append([]string{}, lines[:start]...), text)
+	return strings.Join(append(parts, lines[end:]...), "\n")
+}
+
+// sameVersion reports whether two headings name the same version, ignoring
+// dates and brackets, so "## v1.2.0 (2025-04-01)" matches "## [1.2.0] - 2025-04-02".
+func sameVersion(a, b string) bool {
+	return headingVersion(a) != "" && headingVersion(a) == headingVersion(b)
+}
+
+func headingVersion(heading string) string {
+	fields := strings.Fields(strings.TrimPrefix(heading, "## "))
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(strings.Trim(fields[0], "[]")), "v")
+}
@@ -0,0 +1,116 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+)
+
+const released = "## [1.2.0] - 2026-03-01\n\n### Added\n\n- Export to CSV"
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name, existing, section, want string
+	}{
+		{
+			name:     "empty file",
+			existing: "\n",
+			section:  released + "\n",
+			want:     header + "\n" + released + "\n",
+		},
+		{
+			name:     "intro only",
+			existing: "# Changelog\n\nNotes.\n\n",
+			section:  released,
+			want:     "# Changelog\n\nNotes.\n\n" + released + "\n",
+		},
+		{
+			name:     "above the newest version",
+			existing: "# Changelog\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+			section:  released,
+			want:     "# Changelog\n\n" + released + "\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+		},
+		{
+			name:     "below Unreleased",
+			existing: "# Changelog\n\n## [Unreleased]\n\n- Pending\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+			section:  released,
+			want:     "# Changelog\n\n## [Unreleased]\n\n- Pending\n\n" + released + "\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+		},
+		{
+			name:     "below an Unreleased section with no versions",
+			existing: "# Changelog\n\n## Unreleased\n\n- Pending\n",
+			section:  released,
+			want:     "# Changelog\n\n## Unreleased\n\n- Pending\n\n" + released + "\n",
+		},
+		{
+			name:     "Unreleased stays on top",
+			existing: "# Changelog\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+			section:  "## [Unreleased]\n\n- New",
+			want:     "# Changelog\n\n## [Unreleased]\n\n- New\n\n## [1.1.0] - 2026-01-01\n\n- Old\n",
+		},
+		{
+			name:     "Unreleased regenerated",
+			existing: "# Changelog\n\n## [Unreleased]\n\n- Stale\n- Stale too\n\n## [1.1.0]\n\n- Old\n",
+			section:  "## [Unreleased]\n\n- Fresh\n",
+			want:     "# Changelog\n\n## [Unreleased]\n\n- Fresh\n\n## [1.1.0]\n\n- Old\n",
+		},
+		{
+			name:     "same version with another date and brackets",
+			existing: "# Changelog\n\n## [Unreleased]\n\n## v1.2.0 (2026-02-27)\n\n- Draft\n\n## [1.1.0]\n\n- Old\n",
+			section:  released,
+			want:     "# Changelog\n\n## [Unreleased]\n\n" + released + "\n\n## [1.1.0]\n\n- Old\n",
+		},
+		{
+			name:     "same version last in the file",
+			existing: "# Changelog\n\n## 1.2.0\n\n- Draft\n\n\n",
+			section:  released,
+			want:     "# Changelog\n\n" + released + "\n\n\n",
+		},
+		{
+			name:     "headings in code fences",
+			existing: "# Changelog\n\nExample:\n\n```markdown\n## [9.9.9]\n- Not a release\n```\n\n~~~\n## [1.2.0]\n~~~\n\n## [1.1.0]\n\n- Old\n",
+			section:  released,
+			want:     "# Changelog\n\nExample:\n\n```markdown\n## [9.9.9]\n- Not a release\n```\n\n~~~\n## [1.2.0]\n~~~\n\n" + released + "\n\n## [1.1.0]\n\n- Old\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := Insert(tt.existing, tt.section); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSameVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"## [1.2.0] - 2026-03-01", "## [1.2.0] - 2026-03-02", true},
+		{"## v1.2.0 (2026-03-01)", "## [1.2.0]", true},
+		{"## [V1.2.0]", "## 1.2.0", true},
+		{"## [Unreleased]", "## Unreleased", true},
+		{"## [1.2.0]", "## [1.2.1]", false},
+		{"## [1.2.0]", "## [1.2.0-rc.1]", false},
+		{"## ", "## ", false},
+	}
+	for _, tt := range tests {
+		if got := sameVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameVersion(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHeading(t *testing.T) {
+	for _, tt := range []struct{ version, date, want string }{
+		{"", "2026-03-01", "## [Unreleased]"},
+		{"v1.2.0", "2026-03-01", "## [1.2.0] - 2026-03-01"},
+		{"1.2.0", "", "## [1.2.0]"},
+	} {
+		if got := Heading(tt.version, tt.date); got != tt.want {
+			t.Errorf("Heading(%q, %q) = %q, want %q", tt.version, tt.date, got, tt.want)
+		}
+	}
+	if !strings.HasPrefix(Render(Heading("1.0.0", ""), []Entry{{Category: Fixed, Text: "Crash"}, {Category: Added, Text: "CSV"}}),
+		"## [1.0.0]\n\n### Added\n\n- CSV\n\n### Fixed\n\n- Crash") {
+		t.Error("Render does not group entries in category order")
+	}
+}
@@ -0,0 +1,192 @@
+// Package commitlint checks generated commit messages against commitlint-style rules.
+package commitlint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules configures the checks. Zero values disable the corresponding check.
+type Rules struct {
+	RequireType      bool     // Subject must start with "type(scope): "
+	Types            []string // Allowed types; empty allows any
+	Scopes           []string // Allowed scopes; empty allows any
+	SubjectMaxLength int      // Maximum length of the subject line
+	Imperative       bool     // Subject must use the imperative mood ("add", not "added")
+	BodyWrap         int      // Maximum length of body lines
+	Trailers         []string // Required trailers, e.g. "Signed-off-by"
+}
+
+// Violation is a rule the message breaks.
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+var (
+	headerRe  = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:(?: (.*))?$`)
+	trailerRe = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*): .+$`)
+	urlRe     = regexp.MustCompile(`https?://\S+`)
+)
+
+// Lint returns the violations of message, or nil if it passes.
+func Lint(message string, rules Rules) []Violation {
+	var violations []Violation
+	add := func(rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	message = strings.TrimSpace(message)
+	if message == "" {
+		add("empty", "the commit message is empty")
+		return violations
+	}
+	if strings.Contains(message, "```") {
+		add("no-code-fences", "the message must not contain triple backticks or markdown code blocks")
+	}
+
+	lines := strings.Split(message, "\n")
+	subject := strings.TrimSpace(lines[0])
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		add("body-leading-blank", "the subject must be followed by a blank line before the body")
+	}
+
+	// Subject prefix
+	text := subject
+	if m := headerRe.FindStringSubmatch(subject); m != nil {
+		typ, scope := strings.ToLower(m[1]), m[2]
+		text = m[4]
+		if len(rules.Types) > 0 && !contains(rules.Types, typ) {
+			add("type-enum", "type %q is not allowed (use one of: %s)", m[1], strings.Join(rules.Types, ", "))
+		}
+		if scope != "" && len(rules.Scopes) > 0 && !contains(rules.Scopes, scope) {
+			add("scope-enum", "scope %q is not allowed (use one of: %s)", scope, strings.Join(rules.Scopes, ", "))
+		}
+	} else if rules.RequireType {
+		add("type-empty", "the subject must start with a type, e.g. \"feat: ...\" or \"fix(scope): ...\"")
+	}
+
+	if rules.SubjectMaxLength > 0 {
+		if n := utf8.RuneCountInString(subject); n > rules.SubjectMaxLength {
+			add("subject-max-length", "the subject line is %d characters; keep it at most %d", n, rules.SubjectMaxLength)
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		add("subject-empty", "the subject must describe the change")
+	} else if rules.Imperative {
+		if word, ok := nonImperative(text); ok {
+			add("subject-imperative", "use the imperative mood (%q instead of %q)", imperativeOf(word), word)
+		}
+	}
+
+	// Body and trailers; the body starts after the blank line below the subject
+	var body []string
+	if len(lines) > 1 {
+		body = lines[1:]
+		if strings.TrimSpace(body[0]) == "" {
+			body = body[1:]
+		}
+	}
+	if rules.BodyWrap > 0 {
+		for _, line := range body {
+			// URLs can't be wrapped, so don't count them
+			if n := utf8.RuneCountInString(urlRe.ReplaceAllString(line, "")); n > rules.BodyWrap {
+				add("body-max-line-length", "body lines must be wrapped at %d characters (found a line of %d)", rules.BodyWrap, n)
+				break
+			}
+		}
+	}
+	if len(rules.Trailers) > 0 {
+		present := map[string]bool{}
+		for _, line := range trailerBlock(body) {
+			if m := trailerRe.FindStringSubmatch(line); m != nil {
+				present[strings.ToLower(m[1])] = true
+			}
+		}
+		for _, trailer := range rules.Trailers {
+			if !present[strings.ToLower(trailer)] {
+				add("trailer-exists", "the message must end with a %q trailer", trailer+": ...")
+			}
+		}
+	}
+	return violations
+}
+
+// trailerBlock returns the last paragraph of body if it consists of trailers,
+// "Key: value" lines that may be continued by indented lines, as git
+// interpret-trailers reads them. Otherwise the message has no trailers.
+func trailerBlock(body []string) []string {
+	block := body
+	for i := len(body) - 1; i >= 0; i-- {
+		if strings.TrimSpace(body[i]) == "" {
+			block = body[i+1:]
+			break
+		}
+	}
+	for i, line := range block {
+		continued := i > 0 && line != strings.TrimLeft(line, " \t")
+		if !continued && !trailerRe.MatchString(line) {
+			return nil
+		}
+	}
+	return block
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// commonVerbs are verbs that commit subjects often start with. The imperative
+// check only flags inflected forms of these, to avoid false positives on nouns.
+var commonVerbs = []string{
+	"add", "allow", "avoid", "bump", "change", "clean", "convert", "create",
+	"delete", "deprecate", "disable", "document", "drop", "enable", "ensure",
+	"extract", "fix", "handle", "implement", "improve", "introduce", "make",
+	"merge", "move", "optimize", "prevent", "refactor", "remove", "rename",
+	"replace", "restore", "revert", "rewrite", "set", "simplify", "split",
+	"support", "update", "upgrade", "use",
+}
+
+// inflections maps inflected verb forms ("added", "fixes", "updating") to their base form.
+var inflections = func() map[string]string {
+	m := map[string]string{}
+	for _, verb := range commonVerbs {
+		stem := strings.TrimSuffix(verb, "e")
+		for _, form := range []string{verb + "s", verb + "es", verb + "ed", verb + "d", verb + "ing", stem + "ing", stem + "ed"} {
+			if form != verb {
+				m[form] = verb
+			}
+		}
+		// Verbs ending in a single consonant double it: set -> setting, drop -> dropped
+		last := verb[len(verb)-1:]
+		m[verb+last+"ed"], m[verb+last+"ing"] = verb, verb
+	}
+	m["made"], m["rewrote"], m["rewritten"] = "make", "rewrite", "rewrite"
+	return m
+}()
+
+// nonImperative returns the first word of text if it is an inflected verb.
+func nonImperative(text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	word := strings.ToLower(strings.Trim(fields[0], ".,:;!"))
+	_, ok := inflections[word]
+	return word, ok
+}
+
+func imperativeOf(word string) string {
+	return inflections[word]
+}
@@ -0,0 +1,116 @@
+package commitlint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	strict := Rules{
+		RequireType:      true,
+		Types:            []string{"feat", "fix", "docs"},
+		Scopes:           []string{"api", "cli"},
+		SubjectMaxLength: 50,
+		Imperative:       true,
+		BodyWrap:         40,
+		Trailers:         []string{"Signed-off-by"},
+	}
+	long := strings.Repeat("word ", 10) // 50 characters
+
+	tests := []struct {
+		name    string
+		message string
+		rules   Rules
+		want    []string // Violated rules, in order
+	}{
+		{"empty", " \n\n ", strict, []string{"empty"}},
+		{"valid", "feat(api): add paging\n\nPages are 50 items.\n\nSigned-off-by: A <a@example.com>", strict, nil},
+		{"subject only without rules", "Added stuff", Rules{}, nil},
+		{"code fence", "fix: quote paths\n\n```go\nx()\n```", Rules{}, []string{"no-code-fences"}},
+		{"missing blank line", "fix: quote paths\nBecause spaces.", Rules{}, []string{"body-leading-blank"}},
+		{"missing type", "quote paths", strict, []string{"type-empty", "trailer-exists"}},
+		{"type not allowed", "chore: bump deps", Rules{Types: strict.Types}, []string{"type-enum"}},
+		{"type case", "Fix: quote paths", Rules{Types: strict.Types}, nil},
+		{"scope not allowed", "fix(db): quote paths", Rules{Scopes: strict.Scopes}, []string{"scope-enum"}},
+		{"breaking change", "feat(cli)!: rename flags", Rules{RequireType: true, Scopes: strict.Scopes}, nil},
+		{"subject too long", "fix: " + long, Rules{SubjectMaxLength: 50}, []string{"subject-max-length"}},
+		{"subject at the limit", "fix: " + long[:45], Rules{SubjectMaxLength: 50}, nil},
+		{"subject length in characters", "fix: " + strings.Repeat("é", 45), Rules{SubjectMaxLength: 50}, nil},
+		{"empty subject", "fix: ", Rules{}, []string{"subject-empty"}},
+		{"past tense", "fix: added retries", Rules{Imperative: true}, []string{"subject-imperative"}},
+		{"third person", "Updates the README", Rules{Imperative: true}, []string{"subject-imperative"}},
+		{"noun", "fix: settings page crash", Rules{Imperative: true}, nil},
+		{"body too wide", "fix: x\n\n" + long, Rules{BodyWrap: 40}, []string{"body-max-line-length"}},
+		{"body URL", "fix: x\n\nSee https://example.com/" + strings.Repeat("x", 50), Rules{BodyWrap: 40}, nil},
+		{"no body", "fix: x", Rules{BodyWrap: 40, Trailers: []string{"Signed-off-by"}}, []string{"trailer-exists"}},
+		{"trailer after subject", "fix: x\n\nSigned-off-by: A", Rules{Trailers: []string{"signed-off-by"}}, nil},
+		{"trailer in the body", "fix: x\n\nSigned-off-by: A\n\nMore text.", Rules{Trailers: []string{"Signed-off-by"}}, []string{"trailer-exists"}},
+		{"prose ending the body", "fix: x\n\nWhy: reasons\nand more reasons.", Rules{Trailers: []string{"Why"}}, []string{"trailer-exists"}},
+		{"subject is not a trailer", "Signed-off-by: A", Rules{Trailers: []string{"Signed-off-by"}}, []string{"trailer-exists"}},
+		{
+			"trailer block",
+			"fix: x\n\nBody.\n\nReviewed-by: B\nSigned-off-by: A\n  continued",
+			Rules{Trailers: []string{"Signed-off-by", "Reviewed-by"}},
+			nil,
+		},
+		{
+			"missing one trailer",
+			"fix: x\n\nReviewed-by: B",
+			Rules{Trailers: []string{"Signed-off-by", "Reviewed-by"}},
+			[]string{"trailer-exists"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range Lint(tt.message, tt.rules) {
+				got = append(got, v.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint(%q) = %v, want %v", tt.message, Lint(tt.message, tt.rules), tt.want)
+			}
+		})
+	}
+}
+
+func TestLintMessages(t *testing.T) {
+	violations := Lint("fix(db): fixed quoting\n\n"+strings.Repeat("x", 30), Rules{
+		Scopes:     []string{"api"},
+		Imperative: true,
+		BodyWrap:   20,
+		Trailers:   []string{"Signed-off-by"},
+	})
+	want := []string{
+		`scope-enum: scope "db" is not allowed (use one of: api)`,
+		`subject-imperative: use the imperative mood ("fix" instead of "fixed")`,
+		"body-max-line-length: body lines must be wrapped at 20 characters (found a line of 30)",
+		`trailer-exists: the message must end with a "Signed-off-by: ..." trailer`,
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestInflections(t *testing.T) {
+	for word, base := range map[string]string{
+		"added": "add", "adds": "add", "adding": "add",
+		"fixes": "fix", "fixed": "fix",
+		"updated": "update", "updating": "update",
+		"dropped": "drop", "setting": "set",
+		"made": "make", "rewrote": "rewrite",
+	} {
+		if got, ok := nonImperative(word + " things"); !ok || imperativeOf(got) != base {
+			t.Errorf("%q: got %q (%v), want the base form %q", word, imperativeOf(got), ok, base)
+		}
+	}
+	for _, text := range []string{"add things", "settings page", "update", ""} {
+		if word, ok := nonImperative(text); ok {
+			t.Errorf("%q flagged as non-imperative (%q)", text, word)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package commitsplit turns a mix of staged changes into several commits. The
+// LLM proposes which hunks belong together; Apply then builds each commit's
+// index state with git apply --cached and rolls everything back on failure.
+package commitsplit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/llm"
+)
+
+// Group is one planned commit.
+type Group struct {
+	Message string
+	Changes []diff.Change
+}
+
+// Plan is the ordered list of commits to create. Changes the LLM did not
+// assign to any commit are left staged.
+type Plan struct {
+	Groups     []Group
+	Unassigned []diff.Change
+	Warnings   []string // Problems in the LLM's answer that were worked around
+}
+
+type planJSON struct {
+	Commits []struct {
+		Message string   `json:"message"`
+		Changes []string `json:"changes"`
+	} `json:"commits"`
+}
+
+// ParsePlan reads the LLM's JSON answer and resolves change IDs against
+// changes. Unknown or repeated IDs are ignored with a warning.
+func ParsePlan(text string, changes []diff.Change) (*Plan, error) {
+	var raw planJSON
+	if err := json.Unmarshal([]byte(llm.ExtractJSON(text)), &raw); err != nil {
+		return nil, fmt.Errorf("could not parse the proposed commits: %w", err)
+	}
+
+	index := make(map[string]int, len(changes))
+	for i, c := range changes {
+		index[c.ID] = i
+	}
+	assigned := make(map[string]bool, len(changes))
+	plan := &Plan{}
+	for n, commit := range raw.Commits {
+		message := strings.TrimSpace(commit.Message)
+		var positions []int
+		for _, id := range commit.Changes {
+			id = strings.ToUpper(strings.TrimSpace(id))
+			i, ok := index[id]
+			switch {
+			case !ok:
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("commit %d refers to unknown change %q", n+1, id))
+			case assigned[id]:
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("change %s was assigned to more than one commit; keeping the first", id))
+			default:
+				assigned[id] = true
+				positions = append(positions, i)
+			}
+		}
+		if len(positions) == 0 {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("commit %d has no changes and was dropped", n+1))
+			continue
+		}
+		if message == "" {
+			return nil, fmt.Errorf("commit %d has no message", n+1)
+		}
+		sort.Ints(positions) // Keep hunks in diff order
+		group := Group{Message: message}
+		for _, i := range positions {
+			group.Changes = append(group.Changes, changes[i])
+		}
+		plan.Groups = append(plan.Groups, group)
+	}
+	if len(plan.Groups) == 0 {
+		return nil, fmt.Errorf("no commits were proposed")
+	}
+	for _, c := range changes {
+		if !assigned[c.ID] {
+			plan.Unassigned = append(plan.Unassigned, c)
+		}
+	}
+	return plan, nil
+}
+
+// Apply creates the planned commits in order on top of HEAD. Each commit's
+// index is built from HEAD with git apply --cached, so the working tree is
+// never touched. Unassigned changes stay staged afterwards. If any step fails
+// or ctx is cancelled, HEAD and the index are restored to where they started.
+// It returns the number of commits created.
+func Apply(ctx context.Context, plan *Plan) (int, error) {
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		head = "" // No commits yet
+	}
+	staged, err := git.WriteTree()
+	if err != nil {
+		return 0, err
+	}
+
+	committed := 0
+	rollback := func(cause error) error {
+		var resetErr error
+		if committed > 0 {
+			resetErr = git.ResetHead(head)
+		}
+		readErr := git.ReadTree(staged)
+		if resetErr != nil || readErr != nil {
+			return fmt.Errorf("%w; rolling back also failed, restore the original state with '%s'", cause, restoreCommand(head, staged))
+		}
+		return fmt.Errorf("%w; HEAD and the staged changes were restored", cause)
+	}
+
+	if err := git.ReadTree(head); err != nil {
+		return 0, rollback(err)
+	}
+	for i, group := range plan.Groups {
+		if err := ctx.Err(); err != nil {
+			return 0, rollback(fmt.Errorf("cancelled before commit %d: %w", i+1, err))
+		}
+		if err := git.ApplyToIndex(diff.BuildPatch(group.Changes)); err != nil {
+			return 0, rollback(fmt.Errorf("commit %d: %w", i+1, err))
+		}
+		if err := git.Commit(group.Message); err != nil {
+			return 0, rollback(fmt.Errorf("commit %d: %w", i+1, err))
+		}
+		committed++
+	}
+
+	// The index goes back to the full staged state, so whatever was not
+	// committed is still staged relative to the new HEAD
+	if err := git.ReadTree(staged); err != nil {
+		return committed, fmt.Errorf("commits were created but the remaining changes could not be restaged (run 'git read-tree %s'): %w", staged, err)
+	}
+	return committed, nil
+}
+
+// restoreCommand is the git command line that undoes a partial split by hand.
+func restoreCommand(head, staged string) string {
+	if head == "" {
+		return "git update-ref -d HEAD && git read-tree " + staged
+	}
+	return fmt.Sprintf("git reset --soft %s && git read-tree %s", head, staged)
+}
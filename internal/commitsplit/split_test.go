@@ -0,0 +1,257 @@
+package commitsplit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/git"
+	"github.com/jake/llmify/internal/gittest"
+)
+
+func numbered(prefix string, n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%s%d\n", prefix, i)
+	}
+	return b.String()
+}
+
+// stagedRepo commits two files and stages three changes to a.txt (near its
+// start, middle and end) and one to b.txt. It returns the staged changes.
+func stagedRepo(t *testing.T) []diff.Change {
+	t.Helper()
+	gittest.Repo(t)
+	gittest.WriteFile(t, "a.txt", numbered("a", 40))
+	gittest.WriteFile(t, "b.txt", numbered("b", 5))
+	gittest.Commit(t, "Initial commit")
+
+	a := numbered("a", 40)
+	a = strings.Replace(a, "a2\n", "a2\nnew after a2\nand another\n", 1)
+	a = strings.Replace(a, "a20\n", "", 1)
+	a = strings.Replace(a, "a38\n", "A38\n", 1)
+	gittest.WriteFile(t, "a.txt", a)
+	gittest.WriteFile(t, "b.txt", numbered("b", 6))
+	gittest.Run(t, "add", "-A")
+
+	patch, err := git.GetStagedPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := diff.Parse(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diff.Changes(files)
+	if len(changes) != 4 {
+		t.Fatalf("got %d changes, want 4", len(changes))
+	}
+	return changes
+}
+
+// state is what a split must leave alone or restore.
+type state struct {
+	head, index, status string
+}
+
+func currentState(t *testing.T) state {
+	t.Helper()
+	head := ""
+	if h, err := git.GetHeadCommit(); err == nil {
+		head = h
+	}
+	return state{head: head, index: gittest.Run(t, "write-tree"), status: gittest.Run(t, "status", "--porcelain")}
+}
+
+func TestParsePlan(t *testing.T) {
+	changes := diff.Changes(mustParse(t))
+	tests := []struct {
+		name       string
+		reply      string
+		groups     [][]string // Change IDs per commit
+		unassigned []string
+		warnings   int
+		wantErr    bool
+	}{
+		{
+			name:       "fenced, lower case, out of order",
+			reply:      "```json\n{\"commits\": [{\"message\": \"feat: two\", \"changes\": [\"c3\", \" C1 \"]}, {\"message\": \"fix: one\", \"changes\": [\"C2\"]}]}\n```",
+			groups:     [][]string{{"C1", "C3"}, {"C2"}},
+			unassigned: []string{"C4"},
+		},
+		{
+			name:       "unknown ID",
+			reply:      `{"commits": [{"message": "fix: x", "changes": ["C1", "C9"]}]}`,
+			groups:     [][]string{{"C1"}},
+			unassigned: []string{"C2", "C3", "C4"},
+			warnings:   1,
+		},
+		{
+			name:       "duplicate ID",
+			reply:      `{"commits": [{"message": "fix: x", "changes": ["C1", "C2"]}, {"message": "fix: y", "changes": ["C2", "C3", "c3"]}]}`,
+			groups:     [][]string{{"C1", "C2"}, {"C3"}},
+			unassigned: []string{"C4"},
+			warnings:   2,
+		},
+		{
+			name:       "commit without known changes",
+			reply:      `{"commits": [{"message": "fix: x", "changes": ["C7"]}, {"message": "", "changes": []}, {"message": "fix: y", "changes": ["C4"]}]}`,
+			groups:     [][]string{{"C4"}},
+			unassigned: []string{"C1", "C2", "C3"},
+			warnings:   3,
+		},
+		{name: "missing message", reply: `{"commits": [{"message": " ", "changes": ["C1"]}]}`, wantErr: true},
+		{name: "no commits", reply: `{"commits": []}`, wantErr: true},
+		{name: "only unknown IDs", reply: `{"commits": [{"message": "fix: x", "changes": ["C0"]}]}`, wantErr: true},
+		{name: "not JSON", reply: "I would make two commits.", wantErr: true},
+	}
+	for _, tt := range tests {
+		plan, err := ParsePlan(tt.reply, changes)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: accepted %+v", tt.name, plan)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var groups [][]string
+		for _, g := range plan.Groups {
+			groups = append(groups, ids(g.Changes))
+		}
+		if fmt.Sprint(groups) != fmt.Sprint(tt.groups) || fmt.Sprint(ids(plan.Unassigned)) != fmt.Sprint(tt.unassigned) {
+			t.Errorf("%s: groups %v, unassigned %v; want %v, %v", tt.name, groups, ids(plan.Unassigned), tt.groups, tt.unassigned)
+		}
+		if len(plan.Warnings) != tt.warnings {
+			t.Errorf("%s: warnings %q, want %d", tt.name, plan.Warnings, tt.warnings)
+		}
+	}
+}
+
+func mustParse(t *testing.T) []*diff.File {
+	t.Helper()
+	files, err := diff.Parse("diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n" +
+		"@@ -1 +1 @@\n-x\n+y\n@@ -10 +10 @@\n-x\n+y\n@@ -20 +20 @@\n-x\n+y\n" +
+		"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-x\n+y\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func ids(changes []diff.Change) []string {
+	var result []string
+	for _, c := range changes {
+		result = append(result, c.ID)
+	}
+	return result
+}
+
+func TestApply(t *testing.T) {
+	changes := stagedRepo(t)
+	before := currentState(t)
+	plan := &Plan{
+		Groups: []Group{
+			{Message: "Change the end and b", Changes: []diff.Change{changes[2], changes[3]}},
+			{Message: "Change the start", Changes: []diff.Change{changes[0]}},
+		},
+		Unassigned: []diff.Change{changes[1]},
+	}
+	n, err := Apply(context.Background(), plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("created %d commits, want 2", n)
+	}
+	if got := gittest.Run(t, "rev-parse", "HEAD~2"); got != before.head {
+		t.Errorf("HEAD~2 = %s, want the original HEAD %s", got, before.head)
+	}
+	if got := gittest.Run(t, "log", "--format=%s", "-2"); got != "Change the start\nChange the end and b" {
+		t.Errorf("log = %q", got)
+	}
+	// Everything staged before is still in the index; only the deletion of a20 is left uncommitted
+	if got := gittest.Run(t, "write-tree"); got != before.index {
+		t.Errorf("index tree %s, want %s", got, before.index)
+	}
+	if got := gittest.Run(t, "diff", "--cached", "--numstat"); got != "0\t1\ta.txt" {
+		t.Errorf("left staged: %q", got)
+	}
+	if got := gittest.Run(t, "diff", "--numstat"); got != "" {
+		t.Errorf("the working tree changed: %q", got)
+	}
+}
+
+func TestApplyRollsBackFailedPatch(t *testing.T) {
+	changes := stagedRepo(t)
+	before := currentState(t)
+
+	// A change whose context does not match a.txt, so git apply --cached fails
+	bogus, err := diff.Parse("diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -5,2 +5,2 @@\n no such line\n-a6\n+A6\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := &Plan{Groups: []Group{
+		{Message: "First", Changes: []diff.Change{changes[0], changes[3]}},
+		{Message: "Second", Changes: []diff.Change{changes[1]}},
+		{Message: "Broken", Changes: diff.Changes(bogus)},
+	}}
+	n, err := Apply(context.Background(), plan)
+	if err == nil || !strings.Contains(err.Error(), "commit 3") || !strings.Contains(err.Error(), "restored") {
+		t.Fatalf("err = %v, want a restored failure in commit 3", err)
+	}
+	if n != 0 {
+		t.Errorf("reported %d commits after a rollback", n)
+	}
+	if after := currentState(t); after != before {
+		t.Errorf("state after rollback = %+v, want %+v", after, before)
+	}
+}
+
+func TestApplyRollsBackWithoutHead(t *testing.T) {
+	gittest.Repo(t)
+	gittest.WriteFile(t, "a.txt", "one\n")
+	gittest.WriteFile(t, "b.txt", "two\n")
+	gittest.Run(t, "add", "-A")
+	before := currentState(t)
+	patch, err := git.GetStagedPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := diff.Parse(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := diff.Changes(files)
+	plan := &Plan{Groups: []Group{
+		{Message: "Add a", Changes: changes[:1]},
+		{Message: "Add a again", Changes: changes[:1]}, // Already applied, so it fails
+	}}
+	if _, err := Apply(context.Background(), plan); err == nil {
+		t.Fatal("applying a change twice succeeded")
+	}
+	if after := currentState(t); after != before {
+		t.Errorf("state after rollback = %+v, want %+v", after, before)
+	}
+	if _, err := git.GetHeadCommit(); err == nil {
+		t.Error("HEAD exists after rolling back the first commit")
+	}
+}
+
+func TestApplyCancelled(t *testing.T) {
+	changes := stagedRepo(t)
+	before := currentState(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Apply(ctx, &Plan{Groups: []Group{{Message: "First", Changes: changes}}})
+	if err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("err = %v, want a cancellation", err)
+	}
+	if after := currentState(t); after != before {
+		t.Errorf("state after cancelling = %+v, want %+v", after, before)
+	}
+}
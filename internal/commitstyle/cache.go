@@ -0,0 +1,68 @@
+package commitstyle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jake/llmify/internal/git"
+)
+
+// DefaultSamples is the number of recent commits analysed by default.
+const DefaultSamples = 50
+
+// cacheEntry is the cached profile, valid while HEAD and the sample size are unchanged.
+type cacheEntry struct {
+	Head    string   `json:"head"`
+	Samples int      `json:"samples"`
+	Profile *Profile `json:"profile"`
+}
+
+// cachePath keeps the cache inside .git so it never shows up in the working tree.
+func cachePath() (string, error) {
+	gitDir, err := git.GetGitDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(gitDir, "llmify", "commit-style.json"), nil
+}
+
+// Load returns the style profile of the current repository, inferred from the
+// last samples commits. The profile is cached until HEAD moves. The second
+// result reports whether the cached profile was used.
+func Load(samples int) (*Profile, bool, error) {
+	if samples <= 0 {
+		samples = DefaultSamples
+	}
+	head, err := git.GetHeadCommit()
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot learn commit style without history: %w", err)
+	}
+
+	path, pathErr := cachePath()
+	if pathErr == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			var entry cacheEntry
+			if json.Unmarshal(data, &entry) == nil && entry.Head == head && entry.Samples == samples && entry.Profile != nil {
+				return entry.Profile, true, nil
+			}
+		}
+	}
+
+	messages, err := git.GetRecentCommitMessages(samples)
+	if err != nil {
+		return nil, false, err
+	}
+	profile := Infer(messages)
+
+	// The cache is an optimisation; failing to write it is not an error
+	if pathErr == nil {
+		if data, err := json.MarshalIndent(cacheEntry{Head: head, Samples: samples, Profile: profile}, "", "  "); err == nil {
+			if os.MkdirAll(filepath.Dir(path), 0755) == nil {
+				os.WriteFile(path, data, 0644)
+			}
+		}
+	}
+	return profile, false, nil
+}
@@ -0,0 +1,66 @@
+package commitstyle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jake/llmify/internal/gittest"
+)
+
+func TestLoadCachesUntilHeadMoves(t *testing.T) {
+	dir := gittest.Repo(t)
+	if _, _, err := Load(10); err == nil {
+		t.Error("inferred a style without any commits")
+	}
+
+	gittest.Commit(t, "feat: add login")
+	gittest.Commit(t, "fix(auth): expire sessions")
+	load := func(samples int, wantCached bool) *Profile {
+		t.Helper()
+		profile, cached, err := Load(samples)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cached != wantCached {
+			t.Errorf("Load(%d) cached = %v, want %v", samples, cached, wantCached)
+		}
+		return profile
+	}
+
+	if p := load(10, false); p.Convention != Conventional || p.Samples != 2 {
+		t.Errorf("profile = %+v, want 2 conventional samples", p)
+	}
+	cacheFile := filepath.Join(dir, ".git", "llmify", "commit-style.json")
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("no cache inside .git: %v", err)
+	}
+	load(10, true)
+	load(1, false) // A different sample size is inferred again
+	load(1, true)
+
+	// New commits move HEAD, so the cached profile is stale
+	gittest.Commit(t, "[ABC-1] Add export")
+	gittest.Commit(t, "[ABC-2] Fix export")
+	gittest.Commit(t, "[ABC-3] Document export")
+	if p := load(1, false); p.Convention != Ticket || p.Samples != 1 {
+		t.Errorf("profile = %+v, want 1 ticket sample", p)
+	}
+	if p := load(10, false); p.Convention != Ticket || p.Samples != 5 {
+		t.Errorf("profile = %+v, want ticket convention over 5 samples", p)
+	}
+	load(10, true)
+
+	// Amending changes HEAD without adding commits
+	gittest.Run(t, "commit", "-q", "--amend", "--allow-empty", "-m", "feat: document export")
+	if p := load(10, false); p.Convention != Conventional {
+		t.Errorf("convention %s after amending, want conventional", p.Convention)
+	}
+
+	// A corrupt cache is ignored and replaced
+	if err := os.WriteFile(cacheFile, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	load(10, false)
+	load(10, true)
+}
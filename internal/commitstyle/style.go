@@ -0,0 +1,312 @@
+// Package commitstyle infers a repository's commit message convention from its
+// history so generated messages match what the team already writes.
+package commitstyle
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Conventions recognised in commit subjects.
+const (
+	Conventional = "conventional" // type(scope): subject
+	Ticket       = "ticket"       // [JIRA-123] Subject, JIRA-123: Subject
+	Gitmoji      = "gitmoji"      // ✨ Subject, :sparkles: Subject
+	Freeform     = "freeform"     // Anything else
+)
+
+const maxExamples = 5
+
+var (
+	conventionalRe = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]+)\))?!?: \S`)
+	bracketRe      = regexp.MustCompile(`^\[([^\]\s]+)\]:?\s+\S`)
+	ticketRe       = regexp.MustCompile(`^([A-Z][A-Z0-9]+-\d+):?\s+\S`)
+	shortcodeRe    = regexp.MustCompile(`^:[a-z0-9_+-]+:\s*\S`)
+	ticketIDRe     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*-\d+$`)
+)
+
+// Count is a value with the number of commits it appeared in.
+type Count struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Profile describes the prevailing commit message convention of a repository.
+type Profile struct {
+	Samples          int      `json:"samples"`            // Commits analysed
+	Convention       string   `json:"convention"`         // Prevailing convention
+	ConventionShare  float64  `json:"convention_share"`   // Fraction of commits following it
+	Types            []Count  `json:"types,omitempty"`    // Conventional types, most used first
+	Scopes           []Count  `json:"scopes,omitempty"`   // Conventional scopes, most used first
+	TicketFormat     string   `json:"ticket_format"`      // e.g. "[ABC-123]" or "ABC-123:"
+	TicketPrefixes   []Count  `json:"ticket_prefixes"`    // Project keys such as "ABC-", or tags such as "docs"
+	AvgSubjectLength int      `json:"avg_subject_length"` // Characters, without the prefix
+	MaxSubjectLength int      `json:"max_subject_length"` // 90th percentile of full subject lines
+	Capitalized      bool     `json:"capitalized"`        // Subjects start with an upper-case letter
+	TrailingPeriod   bool     `json:"trailing_period"`    // Subjects end with a period
+	BodyShare        float64  `json:"body_share"`         // Fraction of commits with a body
+	Examples         []string `json:"examples"`           // Representative messages for few-shot prompting
+}
+
+// parsed is a commit message split into its convention-relevant parts.
+type parsed struct {
+	message    string
+	subject    string
+	text       string // Subject without the convention prefix
+	convention string
+	typ, scope string
+	ticket     string // Ticket or bracketed tag, e.g. ABC-123
+	bracketed  bool
+	hasBody    bool
+}
+
+func parse(message string) parsed {
+	subject, body, _ := strings.Cut(message, "\n")
+	p := parsed{
+		message: message,
+		subject: strings.TrimSpace(subject),
+		hasBody: strings.TrimSpace(body) != "",
+	}
+	p.text = p.subject
+
+	if m := conventionalRe.FindStringSubmatch(p.subject); m != nil {
+		p.convention = Conventional
+		p.typ, p.scope = strings.ToLower(m[1]), m[2]
+		p.text = strings.TrimSpace(p.subject[strings.Index(p.subject, ":")+1:])
+	} else if m := bracketRe.FindStringSubmatch(p.subject); m != nil {
+		p.convention = Ticket
+		p.ticket, p.bracketed = m[1], true
+		p.text = strings.TrimSpace(strings.TrimPrefix(p.subject[len(m[1])+2:], ":"))
+	} else if m := ticketRe.FindStringSubmatch(p.subject); m != nil {
+		p.convention = Ticket
+		p.ticket = m[1]
+		p.text = strings.TrimSpace(strings.TrimPrefix(p.subject[len(m[1]):], ":"))
+	} else if isGitmoji(p.subject) {
+		p.convention = Gitmoji
+		if shortcodeRe.MatchString(p.subject) {
+			p.text = strings.TrimSpace(p.subject[strings.Index(p.subject[1:], ":")+2:])
+		} else {
+			_, size := utf8.DecodeRuneInString(p.subject)
+			p.text = strings.TrimSpace(strings.TrimLeftFunc(p.subject[size:], isEmojiModifier))
+		}
+	} else {
+		p.convention = Freeform
+	}
+	return p
+}
+
+func isGitmoji(subject string) bool {
+	if shortcodeRe.MatchString(subject) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(subject)
+	return r >= 0x1F300 || (r >= 0x2600 && r <= 0x27BF)
+}
+
+// isEmojiModifier matches variation selectors and joiners that follow an emoji.
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0x200D || unicode.IsSpace(r)
+}
+
+// Infer builds a profile from commit messages, newest first.
+func Infer(messages []string) *Profile {
+	profile := &Profile{Samples: len(messages), Convention: Freeform}
+	if len(messages) == 0 {
+		return profile
+	}
+
+	var commits []parsed
+	conventions := map[string]int{}
+	for _, msg := range messages {
+		p := parse(msg)
+		commits = append(commits, p)
+		conventions[p.convention]++
+	}
+
+	// Prevailing convention; ties go to the more structured convention
+	profile.Convention = Conventional
+	for _, c := range []string{Ticket, Gitmoji, Freeform} {
+		if conventions[c] > conventions[profile.Convention] {
+			profile.Convention = c
+		}
+	}
+	profile.ConventionShare = float64(conventions[profile.Convention]) / float64(len(commits))
+
+	types, scopes, tickets := map[string]int{}, map[string]int{}, map[string]int{}
+	bracketed := 0
+	var subjectLengths []int
+	textLength, capitalized, periods, bodies := 0, 0, 0, 0
+	var matching []parsed
+	for _, p := range commits {
+		if p.hasBody {
+			bodies++
+		}
+		if p.convention != profile.Convention {
+			continue
+		}
+		matching = append(matching, p)
+		subjectLengths = append(subjectLengths, utf8.RuneCountInString(p.subject))
+		textLength += utf8.RuneCountInString(p.text)
+		if r, _ := utf8.DecodeRuneInString(p.text); unicode.IsUpper(r) {
+			capitalized++
+		}
+		if strings.HasSuffix(p.text, ".") {
+			periods++
+		}
+		if p.typ != "" {
+			types[p.typ]++
+		}
+		if p.scope != "" {
+			scopes[p.scope]++
+		}
+		if p.ticket != "" {
+			prefix := p.ticket // A tag such as "docs"
+			if ticketIDRe.MatchString(p.ticket) {
+				prefix = p.ticket[:strings.LastIndex(p.ticket, "-")+1] // A project key such as "ABC-"
+			}
+			tickets[prefix]++
+			if p.bracketed {
+				bracketed++
+			}
+		}
+	}
+
+	n := len(matching)
+	profile.AvgSubjectLength = textLength / n
+	sort.Ints(subjectLengths)
+	profile.MaxSubjectLength = subjectLengths[(9*n+9)/10-1] // 90th percentile
+
+	profile.Capitalized = capitalized*2 > n
+	profile.TrailingPeriod = periods*2 > n
+	profile.BodyShare = float64(bodies) / float64(len(commits))
+	profile.Types = topCounts(types, 10)
+	profile.Scopes = topCounts(scopes, 15)
+	profile.TicketPrefixes = topCounts(tickets, 5)
+	if profile.Convention == Ticket && len(profile.TicketPrefixes) > 0 {
+		example := profile.TicketPrefixes[0].Value
+		if strings.HasSuffix(example, "-") {
+			example += "123"
+		}
+		if bracketed*2 > n {
+			example = "[" + example + "]"
+		} else {
+			example += ":"
+		}
+		profile.TicketFormat = example
+	}
+	profile.Examples = pickExamples(matching, profile)
+	return profile
+}
+
+func topCounts(counts map[string]int, limit int) []Count {
+	var result []Count
+	for v, c := range counts {
+		result = append(result, Count{Value: v, Count: c})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Value < result[j].Value
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// pickExamples chooses representative messages: recent ones of typical length,
+// preferring a variety of types and scopes, and including bodies in about the
+// same proportion as the history does.
+func pickExamples(commits []parsed, profile *Profile) []string {
+	var examples []string
+	seen := map[string]bool{}
+	wantBodies := int(profile.BodyShare*maxExamples + 0.5)
+	bodies := 0
+	for pass := 0; pass < 2 && len(examples) < maxExamples; pass++ {
+		for _, p := range commits {
+			if len(examples) == maxExamples {
+				break
+			}
+			if seen[p.message] || utf8.RuneCountInString(p.subject) > profile.MaxSubjectLength {
+				continue
+			}
+			variety := p.typ + "|" + p.scope + "|" + p.ticket
+			if pass == 0 && (seen[variety] || (p.hasBody && bodies >= wantBodies)) {
+				continue
+			}
+			seen[p.message], seen[variety] = true, true
+			if p.hasBody {
+				bodies++
+			}
+			examples = append(examples, trimBody(p.message, 12))
+		}
+	}
+	return examples
+}
+
+// trimBody limits a message to maxLines lines.
+func trimBody(message string, maxLines int) string {
+	lines := strings.Split(message, "\n")
+	if len(lines) <= maxLines {
+		return message
+	}
+	return strings.Join(lines[:maxLines], "\n") + "\n..."
+}
+
+// Guidance describes the convention as instructions for the LLM.
+func (p *Profile) Guidance() string {
+	var b strings.Builder
+	switch p.Convention {
+	case Conventional:
+		b.WriteString("- Use Conventional Commits: <type>(<scope>): <subject>.\n")
+		if len(p.Types) > 0 {
+			fmt.Fprintf(&b, "- Types used in this repository: %s.\n", joinValues(p.Types))
+		}
+		if len(p.Scopes) > 0 {
+			fmt.Fprintf(&b, "- Prefer these existing scopes when they fit: %s.\n", joinValues(p.Scopes))
+		}
+	case Ticket:
+		if p.TicketFormat != "" {
+			fmt.Fprintf(&b, "- Start the subject with a reference formatted like %s, as in the examples.\n", p.TicketFormat)
+		} else {
+			b.WriteString("- Start the subject with a bracketed tag as in the examples.\n")
+		}
+	case Gitmoji:
+		b.WriteString("- Start the subject with a gitmoji matching the kind of change, as in the examples.\n")
+	default:
+		b.WriteString("- Write a plain subject line without type prefixes or tags.\n")
+	}
+	if p.Capitalized {
+		b.WriteString("- Capitalize the first word of the subject.\n")
+	} else {
+		b.WriteString("- Start the subject in lower case.\n")
+	}
+	if p.TrailingPeriod {
+		b.WriteString("- End the subject with a period.\n")
+	} else {
+		b.WriteString("- Do not end the subject with a period.\n")
+	}
+	fmt.Fprintf(&b, "- Keep the subject line under %d characters (typically about %d after any prefix).\n", p.MaxSubjectLength+1, p.AvgSubjectLength)
+	switch {
+	case p.BodyShare >= 0.6:
+		b.WriteString("- Add a body after a blank line explaining what changed and why.\n")
+	case p.BodyShare >= 0.2:
+		b.WriteString("- Add a short body after a blank line only if the change needs explanation.\n")
+	default:
+		b.WriteString("- Write the subject line only, without a body.\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func joinValues(counts []Count) string {
+	values := make([]string, len(counts))
+	for i, c := range counts {
+		values[i] = c.Value
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,171 @@
+package commitstyle
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInferConvention(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   []string
+		convention string
+		share      float64
+		types      []Count
+		scopes     []Count
+		format     string
+		prefixes   []Count
+	}{
+		{
+			name:       "conventional",
+			messages:   []string{"feat(api): add pagination", "fix: handle nil config", "feat(cli)!: rename flags", "docs: describe caching", "Merge branch main"},
+			convention: Conventional,
+			share:      0.8,
+			types:      []Count{{"feat", 2}, {"docs", 1}, {"fix", 1}},
+			scopes:     []Count{{"api", 1}, {"cli", 1}},
+		},
+		{
+			name:       "bracket ticket",
+			messages:   []string{"[ABC-12] Add login", "[ABC-13]: Fix logout", "[OPS-7] Bump base image", "Tidy up"},
+			convention: Ticket,
+			share:      0.75,
+			format:     "[ABC-123]",
+			prefixes:   []Count{{"ABC-", 2}, {"OPS-", 1}},
+		},
+		{
+			name:       "colon ticket",
+			messages:   []string{"ABC-1: Add login", "ABC-2 Fix logout", "[ABC-3] Bump"},
+			convention: Ticket,
+			share:      1,
+			format:     "ABC-123:",
+			prefixes:   []Count{{"ABC-", 3}},
+		},
+		{
+			name:       "bracket tag",
+			messages:   []string{"[docs] Update readme", "[docs] Fix typo", "[ci] Cache modules"},
+			convention: Ticket,
+			share:      1,
+			format:     "[docs]",
+			prefixes:   []Count{{"docs", 2}, {"ci", 1}},
+		},
+		{
+			name:       "gitmoji",
+			messages:   []string{"✨ Add export", ":bug: Fix crash on start", "♻️ Simplify parser", "Release 1.2"},
+			convention: Gitmoji,
+			share:      0.75,
+		},
+		{
+			name:       "freeform",
+			messages:   []string{"Add export", "fix crash: on start", "Release 1.2"},
+			convention: Freeform,
+			share:      1,
+		},
+		{
+			name:       "tie goes to the structured convention",
+			messages:   []string{"Add export", "feat: add import"},
+			convention: Conventional,
+			share:      0.5,
+			types:      []Count{{"feat", 1}},
+		},
+		{
+			name:       "no history",
+			convention: Freeform,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Infer(tt.messages)
+			if p.Samples != len(tt.messages) || p.Convention != tt.convention || p.ConventionShare != tt.share {
+				t.Errorf("got %d samples, %s at %v; want %d, %s at %v",
+					p.Samples, p.Convention, p.ConventionShare, len(tt.messages), tt.convention, tt.share)
+			}
+			if !reflect.DeepEqual(p.Types, tt.types) || !reflect.DeepEqual(p.Scopes, tt.scopes) {
+				t.Errorf("types %v, scopes %v; want %v, %v", p.Types, p.Scopes, tt.types, tt.scopes)
+			}
+			if p.TicketFormat != tt.format || !reflect.DeepEqual(p.TicketPrefixes, tt.prefixes) {
+				t.Errorf("ticket format %q with %v; want %q with %v", p.TicketFormat, p.TicketPrefixes, tt.format, tt.prefixes)
+			}
+		})
+	}
+}
+
+func TestInferSubjectText(t *testing.T) {
+	tests := []struct {
+		message string
+		text    string
+	}{
+		{"feat(api): Add pagination.", "Add pagination."},
+		{"[ABC-12]: Add login", "Add login"},
+		{"ABC-12 Add login", "Add login"},
+		{":sparkles: Add export", "Add export"},
+		{"♻️ Simplify parser", "Simplify parser"},
+		{"  Plain subject  \n\nBody", "Plain subject"},
+	}
+	for _, tt := range tests {
+		if got := parse(tt.message).text; got != tt.text {
+			t.Errorf("parse(%q).text = %q, want %q", tt.message, got, tt.text)
+		}
+	}
+}
+
+// subjects returns n freeform subjects of the given lengths, cycling through them.
+func subjects(n int, lengths ...int) []string {
+	var messages []string
+	for i := 0; i < n; i++ {
+		messages = append(messages, "S"+strings.Repeat("x", lengths[i%len(lengths)]-1))
+	}
+	return messages
+}
+
+func TestInferSubjectLength(t *testing.T) {
+	tests := []struct {
+		messages []string
+		avg, max int
+	}{
+		{subjects(1, 40), 40, 40},
+		{subjects(3, 10, 20, 90), 40, 90},
+		{subjects(10, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100), 55, 90}, // The longest 10% is ignored
+		{subjects(20, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100), 55, 90},
+		{subjects(11, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 200), 68, 100},
+		{[]string{"fix: " + strings.Repeat("y", 45)}, 45, 50}, // The average leaves out the prefix
+	}
+	for i, tt := range tests {
+		p := Infer(tt.messages)
+		if p.AvgSubjectLength != tt.avg || p.MaxSubjectLength != tt.max {
+			t.Errorf("case %d: avg %d, max %d; want %d, %d", i, p.AvgSubjectLength, p.MaxSubjectLength, tt.avg, tt.max)
+		}
+	}
+}
+
+func TestInferHabits(t *testing.T) {
+	p := Infer([]string{
+		"fix: handle nil config.\n\nThe loader returned nil when the file was empty.",
+		"feat: add export.",
+		"feat: Add import",
+		"chore: bump deps.",
+	})
+	if p.Capitalized || !p.TrailingPeriod || p.BodyShare != 0.25 {
+		t.Errorf("capitalized %v, trailing period %v, body share %v", p.Capitalized, p.TrailingPeriod, p.BodyShare)
+	}
+	guidance := p.Guidance()
+	for _, want := range []string{"Conventional Commits", "lower case", "End the subject with a period", "short body"} {
+		if !strings.Contains(guidance, want) {
+			t.Errorf("guidance does not mention %q:\n%s", want, guidance)
+		}
+	}
+}
+
+func TestInferExamples(t *testing.T) {
+	var messages []string
+	for i := 0; i < 9; i++ {
+		messages = append(messages, fmt.Sprintf("feat(area%d): change %d", i%3, i))
+	}
+	messages = append(messages, "feat: "+strings.Repeat("very long ", 20)) // Longer than 90% of subjects
+	p := Infer(messages)
+	want := []string{"feat(area0): change 0", "feat(area1): change 1", "feat(area2): change 2", "feat(area0): change 3", "feat(area1): change 4"}
+	if !reflect.DeepEqual(p.Examples, want) {
+		t.Errorf("examples = %q, want %q", p.Examples, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -14,12 +15,49 @@ type LLMConfig struct {
 	Provider string `mapstructure:"provider"`
 	Model    string `mapstructure:"model"`
 	// Add provider-specific fields if needed, e.g.:
-	OllamaBaseURL string `mapstructure:"ollama_base_url"`
+	OllamaBaseURL    string `mapstructure:"ollama_base_url"`
+	OllamaStream     bool   `mapstructure:"ollama_stream"`      // Read Ollama responses as a stream
+	AnthropicBaseURL string `mapstructure:"anthropic_base_url"` // Optional, defaults to the public API
+	// OpenAI-compatible endpoints (vLLM, LM Studio, LiteLLM, Azure OpenAI)
+	BaseURL          string            `mapstructure:"base_url"`
+	APIType          string            `mapstructure:"api_type"`          // "openai" (default) or "azure"
+	APIVersion       string            `mapstructure:"api_version"`       // Required for Azure
+	Organization     string            `mapstructure:"organization"`      // OpenAI organization ID
+	AzureDeployments map[string]string `mapstructure:"azure_deployments"` // Model name -> deployment name
+	Headers          map[string]string `mapstructure:"headers"`           // Extra HTTP headers
+	ContextWindow    int               `mapstructure:"context_window"`    // Model context window in tokens; 0 uses the built-in table
+	// Routes tried in order when the primary provider fails
+	Fallbacks []FallbackConfig `mapstructure:"fallbacks"`
+	// Offline testing
+	FakeFixtures string `mapstructure:"fake_fixtures"` // Fixtures file for the "fake" provider
+	RecordMode   string `mapstructure:"record_mode"`   // "record" or "replay"; empty disables
+	RecordDir    string `mapstructure:"record_dir"`    // Where recorded responses are stored
 	// API keys are typically handled via environment variables
 }
 
+// FallbackConfig is a provider and model to fall back to.
+type FallbackConfig struct {
+	Provider string `mapstructure:"provider"` // Defaults to llm.provider
+	Model    string `mapstructure:"model"`    // Defaults to the provider's default model
+}
+
 type CommitConfig struct {
-	Model string `mapstructure:"model"` // Optional override
+	Model        string           `mapstructure:"model"`         // Optional override
+	Style        string           `mapstructure:"style"`         // "conventional" (default) or "learn" from git history
+	StyleSamples int              `mapstructure:"style_samples"` // Recent commits analysed when learning the style
+	Lint         CommitLintConfig `mapstructure:"lint"`
+}
+
+// CommitLintConfig configures validation of generated commit messages.
+type CommitLintConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	Types            []string `mapstructure:"types"`              // Allowed Conventional Commit types; empty allows any
+	Scopes           []string `mapstructure:"scopes"`             // Allowed scopes; empty allows any
+	SubjectMaxLength int      `mapstructure:"subject_max_length"` // 0 disables the check
+	Imperative       bool     `mapstructure:"imperative"`         // Require "add", not "added"/"adds"
+	BodyWrap         int      `mapstructure:"body_wrap"`          // Maximum body line length; 0 disables the check
+	Trailers         []string `mapstructure:"trailers"`           // Required trailers, e.g. Signed-off-by
+	MaxAttempts      int      `mapstructure:"max_attempts"`       // Times the LLM is asked to fix violations
 }
 
 type DocsConfig struct {
@@ -28,21 +66,142 @@ type DocsConfig struct {
 	// Patterns []string `mapstructure:"patterns"`
 }
 
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `mapstructure:"input"`
+	Output float64 `mapstructure:"output"`
+}
+
+type UsageConfig struct {
+	LedgerPath string `mapstructure:"ledger_path"` // Defaults to $XDG_DATA_HOME/llmify/usage.jsonl
+	Disabled   bool   `mapstructure:"disabled"`    // Don't record usage to the ledger
+}
+
+type CacheConfig struct {
+	Dir       string        `mapstructure:"dir"`         // Defaults to the user cache directory
+	TTL       time.Duration `mapstructure:"ttl"`         // How long responses stay valid, e.g. "168h"; 0 keeps them forever
+	MaxSizeMB int           `mapstructure:"max_size_mb"` // Oldest entries are evicted above this size; 0 for no limit
+	Disabled  bool          `mapstructure:"disabled"`    // Never use the response cache
+}
+
+type RefactorConfig struct {
+	Verify VerifyConfig `mapstructure:"verify"`
+}
+
+// VerifyConfig configures the checks a refactoring must pass before it is applied.
+type VerifyConfig struct {
+	Enabled     bool                `mapstructure:"enabled"`      // Run Commands instead of only the defaults
+	Commands    map[string][]string `mapstructure:"commands"`     // Language -> commands run from the repository root; {file} is the refactored file
+	MaxAttempts int                 `mapstructure:"max_attempts"` // Times the LLM is asked to fix a refactoring that fails the checks
+	Timeout     time.Duration       `mapstructure:"timeout"`      // Limit for each command, e.g. "5m"
+	PatchDir    string              `mapstructure:"patch_dir"`    // Where refactorings that fail the checks are saved as patches
+}
+
+type PromptsConfig struct {
+	Dir       string            `mapstructure:"dir"`       // Directory of <name>.tmpl overrides
+	Templates map[string]string `mapstructure:"templates"` // Inline overrides by template name
+}
+
 type Config struct {
-	LLM    LLMConfig    `mapstructure:"llm"`
-	Commit CommitConfig `mapstructure:"commit"`
-	Docs   DocsConfig   `mapstructure:"docs"`
+	LLM      LLMConfig             `mapstructure:"llm"`
+	Commit   CommitConfig          `mapstructure:"commit"`
+	Docs     DocsConfig            `mapstructure:"docs"`
+	Usage    UsageConfig           `mapstructure:"usage"`
+	Cache    CacheConfig           `mapstructure:"cache"`
+	Prompts  PromptsConfig         `mapstructure:"prompts"`
+	Refactor RefactorConfig        `mapstructure:"refactor"`
+	Pricing  map[string]ModelPrice `mapstructure:"pricing"` // Model name -> price, overrides built-in prices
 }
 
 var GlobalConfig Config
 
+// defaultModels maps each provider to the model used when llm.model is not set.
+var defaultModels = map[string]string{
+	"openai":    "gpt-4o",
+	"anthropic": "claude-3-5-sonnet-latest",
+	"ollama":    "llama3.1",
+	"fake":      "fake",
+}
+
+// DefaultModel returns the default model for a provider.
+func DefaultModel(provider string) string {
+	if model, ok := defaultModels[strings.ToLower(provider)]; ok {
+		return model
+	}
+	return defaultModels["openai"]
+}
+
+var defaultVerifyCommands = map[string][]string{
+	"go":         {"go build -o " + os.DevNull + " ./...", "go vet ./..."}, // Discard binaries of main packages
+	"typescript": {"tsc --noEmit"},
+	"python":     {"mypy {file}"},
+}
+
+// DefaultVerifyCommands returns the default verification commands for a
+// language. They only run its compiler or type checker, so they run even
+// when refactor.verify.enabled is off.
+func DefaultVerifyCommands(lang string) []string {
+	return defaultVerifyCommands[strings.ToLower(lang)]
+}
+
+// Providers lists the supported values of llm.provider.
+var Providers = []string{"openai", "anthropic", "ollama", "openai-compatible", "azure", "fake"}
+
+// ApplyModelOverride applies a --model flag: "model" switches the model of the
+// configured provider, "provider:model" (e.g. "ollama:llama3.1") switches both.
+// An override replaces routing, so fallbacks are disabled.
+func ApplyModelOverride(cfg *Config, spec string) {
+	if spec == "" {
+		return
+	}
+	model := spec
+	if provider, rest, ok := strings.Cut(spec, ":"); ok {
+		// Ollama tags also contain ':' (llama3.1:8b), so only split on known providers
+		for _, p := range Providers {
+			if strings.EqualFold(provider, p) {
+				cfg.LLM.Provider = p
+				model = rest
+				break
+			}
+		}
+	}
+	if model == "" {
+		model = DefaultModel(cfg.LLM.Provider)
+	}
+	cfg.LLM.Model = model
+	cfg.Commit.Model = model
+	cfg.Docs.Model = model
+	cfg.LLM.Fallbacks = nil
+}
+
 func LoadConfig() error {
 	v := viper.New()
 
 	// 1. Set Defaults
 	v.SetDefault("llm.provider", "openai")
-	v.SetDefault("llm.model", "gpt-4o")
+	v.SetDefault("llm.model", "") // Resolved per provider after unmarshalling
 	v.SetDefault("llm.ollama_base_url", "http://localhost:11434")
+	v.SetDefault("llm.ollama_stream", true)
+	v.SetDefault("llm.context_window", 0) // Look the model up in the built-in table
+	v.SetDefault("llm.fake_fixtures", filepath.Join(".llmify", "fixtures.json"))
+	v.SetDefault("llm.record_mode", "")
+	v.SetDefault("llm.record_dir", filepath.Join(".llmify", "recordings"))
+	v.SetDefault("commit.style", "conventional")
+	v.SetDefault("commit.style_samples", 50)
+	v.SetDefault("commit.lint.enabled", true)
+	v.SetDefault("commit.lint.types", []string{"feat", "fix", "refactor", "chore", "docs", "style", "test", "perf", "build", "ci", "revert"})
+	v.SetDefault("commit.lint.subject_max_length", 72)
+	v.SetDefault("commit.lint.imperative", true)
+	v.SetDefault("commit.lint.body_wrap", 100)
+	v.SetDefault("commit.lint.max_attempts", 2)
+	v.SetDefault("prompts.dir", filepath.Join(".llmify", "prompts"))
+	v.SetDefault("refactor.verify.enabled", false) // Configured commands run arbitrary tools, so they are opt-in
+	v.SetDefault("refactor.verify.commands", defaultVerifyCommands)
+	v.SetDefault("refactor.verify.max_attempts", 2)
+	v.SetDefault("refactor.verify.timeout", "5m")
+	v.SetDefault("refactor.verify.patch_dir", filepath.Join(".llmify", "refactor"))
+	v.SetDefault("cache.ttl", "168h") // One week
+	v.SetDefault("cache.max_size_mb", 100)
 	// Defaults for Commit and Docs models will inherit from llm.model if not set
 
 	// 2. Set config file paths
@@ -101,11 +260,14 @@ func LoadConfig() error {
 	}
 
 	// 5. Set environment variable binding
-	v.SetEnvPrefix("LLMIFY") // e.g., LLMIFY_LLM_PROVIDER
+	v.SetEnvPrefix("LLMIFY")                           // e.g., LLMIFY_LLM_PROVIDER
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_")) // llm.record_mode -> LLMIFY_LLM_RECORD_MODE
 	v.AutomaticEnv()
 	// Allow specific API keys to be picked up directly
 	v.BindEnv("llm.api_key.openai", "OPENAI_API_KEY")
 	v.BindEnv("llm.api_key.anthropic", "ANTHROPIC_API_KEY")
+	v.BindEnv("llm.api_key.azure", "AZURE_OPENAI_API_KEY")
+	v.BindEnv("llm.base_url", "LLMIFY_LLM_BASE_URL", "OPENAI_BASE_URL")
 	// Add others as needed
 
 	// 6. Unmarshal into GlobalConfig
@@ -115,6 +277,9 @@ func LoadConfig() error {
 	}
 
 	// Apply overrides if specific models aren't set
+	if GlobalConfig.LLM.Model == "" {
+		GlobalConfig.LLM.Model = DefaultModel(GlobalConfig.LLM.Provider)
+	}
 	if GlobalConfig.Commit.Model == "" {
 		GlobalConfig.Commit.Model = GlobalConfig.LLM.Model
 	}
@@ -146,6 +311,13 @@ func GetAPIKey(provider string) string {
 			key = os.Getenv("OPENAI_API_KEY")
 		case "anthropic":
 			key = os.Getenv("ANTHROPIC_API_KEY")
+		case "azure":
+			key = os.Getenv("AZURE_OPENAI_API_KEY")
+		case "openai-compatible":
+			key = os.Getenv("LLMIFY_OPENAI_COMPATIBLE_API_KEY")
+			if key == "" {
+				key = os.Getenv("OPENAI_API_KEY")
+			}
 			// Add other cases
 		}
 	}
@@ -0,0 +1,308 @@
+package crawler
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jake/llmify/internal/tokenizer"
+)
+
+// Overflow strategies used when the token budget is exceeded.
+const (
+	OverflowDrop      = "drop"
+	OverflowTruncate  = "truncate"
+	OverflowSummarize = "summarize"
+)
+
+// File actions recorded in the token report.
+const (
+	ActionIncluded   = "included"
+	ActionTruncated  = "truncated"
+	ActionSummarized = "summarized"
+	ActionDropped    = "dropped"
+)
+
+// minPartialTokens is the smallest slice of a file worth keeping when truncating.
+const minPartialTokens = 64
+
+// BudgetOptions controls how CrawlResult content is fitted into a token budget.
+type BudgetOptions struct {
+	MaxTokens     int                 // 0 disables the budget and only counts tokens
+	Tokenizer     tokenizer.Tokenizer // Tokenizer used for counting
+	Overflow      string              // drop, truncate or summarize
+	IncludeHeader bool                // Whether the project tree header is part of the output
+	Format        string              // Output format, used to account for per-file framing
+}
+
+// FileTokens records the token cost of a single file.
+type FileTokens struct {
+	Path           string
+	OriginalTokens int    // Tokens in the full file content
+	Tokens         int    // Tokens actually emitted (0 if dropped)
+	Action         string // included, truncated, summarized or dropped
+	Priority       int
+}
+
+// TokenReport summarizes the token usage of a crawl.
+type TokenReport struct {
+	Tokenizer      string
+	MaxTokens      int
+	HeaderTokens   int
+	TotalTokens    int // Tokens emitted, including header and per-file framing
+	OriginalTokens int // Tokens the output would have used without a budget
+	Files          []FileTokens
+}
+
+// ApplyTokenBudget counts tokens for every included file and, when MaxTokens is set,
+// drops, truncates or summarizes the lowest-priority files until the output fits.
+// The result is updated in place: dropped files are removed from IncludedFiles and
+// rewritten content is stored in Contents.
+func ApplyTokenBudget(result *CrawlResult, opts BudgetOptions) (*TokenReport, error) {
+	tok := opts.Tokenizer
+	if tok == nil {
+		var err error
+		tok, err = tokenizer.Get(tokenizer.DefaultTokenizer)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch opts.Overflow {
+	case "":
+		opts.Overflow = OverflowTruncate
+	case OverflowDrop, OverflowTruncate, OverflowSummarize:
+	default:
+		return nil, fmt.Errorf("unknown overflow strategy %q (use drop, truncate or summarize)", opts.Overflow)
+	}
+
+	f, err := formatterFor(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result.loadContents()
+	order := append([]string(nil), result.IncludedFiles...)
+	original := make(map[string]string, len(result.IncludedFiles))
+	for _, path := range result.IncludedFiles {
+		original[path] = result.Contents[path]
+	}
+
+	report := &TokenReport{Tokenizer: tok.Name(), MaxTokens: opts.MaxTokens}
+	report.HeaderTokens = tok.Count(f.header(result, opts.IncludeHeader) + f.footer(opts.IncludeHeader))
+
+	// cost is the number of tokens a file adds to the output. Framing depends
+	// on the content (longer Markdown fences, split CDATA sections, JSON
+	// escapes), so it is measured on the rendered file. Rendering it as a
+	// later file includes the separator JSON puts before all but the first.
+	cost := func(path, content string) int {
+		return tok.Count(f.file(path, content, 1))
+	}
+
+	files := make([]FileTokens, 0, len(result.IncludedFiles))
+	fullCost := make(map[string]int, len(result.IncludedFiles))
+	for _, path := range result.IncludedFiles {
+		fullCost[path] = cost(path, original[path])
+		files = append(files, FileTokens{
+			Path:           path,
+			OriginalTokens: tok.Count(original[path]),
+			Priority:       filePriority(path),
+		})
+	}
+	report.OriginalTokens = report.HeaderTokens
+	for _, f := range files {
+		report.OriginalTokens += fullCost[f.Path]
+	}
+
+	// Highest priority first; among equals prefer smaller files so more of them fit.
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Priority != files[j].Priority {
+			return files[i].Priority > files[j].Priority
+		}
+		return files[i].OriginalTokens < files[j].OriginalTokens
+	})
+
+	// fit chooses an action for every file so that the files cost at most
+	// limit tokens, and returns the contents to emit for the files it keeps.
+	fit := func(limit int) map[string]string {
+		contents := make(map[string]string, len(files))
+		remaining := limit
+		for i := range files {
+			f := &files[i]
+			content := original[f.Path]
+			if opts.MaxTokens <= 0 || fullCost[f.Path] <= remaining {
+				f.Action, f.Tokens = ActionIncluded, f.OriginalTokens
+				contents[f.Path] = content
+				remaining -= fullCost[f.Path]
+				continue
+			}
+
+			f.Action, f.Tokens = ActionDropped, 0
+			switch opts.Overflow {
+			case OverflowSummarize:
+				summary := summarizeContent(f.Path, content)
+				if c := cost(f.Path, summary); c <= remaining {
+					f.Action, f.Tokens = ActionSummarized, tok.Count(summary)
+					contents[f.Path] = summary
+					remaining -= c
+				}
+			case OverflowTruncate:
+				if truncated, c, ok := truncateToFit(tok, f, content, remaining, cost); ok {
+					f.Action, f.Tokens = ActionTruncated, tok.Count(truncated)
+					contents[f.Path] = truncated
+					remaining -= c
+				}
+			}
+		}
+		return contents
+	}
+
+	// Files are measured one at a time, and tokens can merge across the
+	// boundaries between them, so the assembled output is counted as well.
+	// If it is still over budget, the files are fitted again with less room.
+	budget := opts.MaxTokens - report.HeaderTokens
+	for attempt := 0; ; attempt++ {
+		contents := fit(budget)
+		result.IncludedFiles = result.IncludedFiles[:0] // Kept files, in their original order
+		for _, path := range order {
+			if _, ok := contents[path]; ok {
+				result.IncludedFiles = append(result.IncludedFiles, path)
+			}
+		}
+		result.Contents = contents
+		output, err := BuildOutputContent(result, opts.Format, opts.IncludeHeader)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalTokens = tok.Count(output)
+		over := report.TotalTokens - opts.MaxTokens
+		if opts.MaxTokens <= 0 || over <= 0 || attempt == 3 {
+			break
+		}
+		budget -= over
+	}
+
+	sort.SliceStable(files, func(i, j int) bool { return files[i].OriginalTokens > files[j].OriginalTokens })
+	report.Files = files
+	return report, nil
+}
+
+// truncateToFit cuts content so that, with a marker saying how much was
+// left out, the file costs at most available tokens. It fails if less than
+// minPartialTokens of the content would be kept.
+func truncateToFit(tok tokenizer.Tokenizer, f *FileTokens, content string, available int, cost func(path, content string) int) (string, int, bool) {
+	// Start from the framing of the whole file; each miss shrinks the slice
+	// by the number of tokens it was over
+	keep := available - (cost(f.Path, content) - f.OriginalTokens)
+	for keep >= minPartialTokens {
+		kept := tok.Truncate(content, keep)
+		keptTokens := tok.Count(kept)
+		truncated := kept + fmt.Sprintf("\n... (truncated, %d tokens omitted)", f.OriginalTokens-keptTokens)
+		c := cost(f.Path, truncated)
+		if c <= available {
+			return truncated, c, true
+		}
+		keep = min(keep, keptTokens) - (c - available)
+	}
+	return "", 0, false
+}
+
+// Write prints the per-file token report, largest files first.
+func (r *TokenReport) Write(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "TOKENS\tORIGINAL\tACTION\t FILE")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%d\t%d\t%s\t %s\n", f.Tokens, f.OriginalTokens, f.Action, f.Path)
+	}
+	tw.Flush()
+
+	counts := map[string]int{}
+	for _, f := range r.Files {
+		counts[f.Action]++
+	}
+	fmt.Fprintf(w, "\nTokenizer: %s | Header: %d tokens | Total: %d tokens", r.Tokenizer, r.HeaderTokens, r.TotalTokens)
+	if r.MaxTokens > 0 {
+		fmt.Fprintf(w, " of %d budget (unbudgeted: %d)", r.MaxTokens, r.OriginalTokens)
+	}
+	fmt.Fprintf(w, "\nFiles: %d included, %d truncated, %d summarized, %d dropped\n",
+		counts[ActionIncluded], counts[ActionTruncated], counts[ActionSummarized], counts[ActionDropped])
+}
+
+// filePriority scores how useful a file is as LLM context. Higher is more important.
+func filePriority(relPath string) int {
+	path := strings.ToLower(filepath.ToSlash(relPath))
+	base := filepath.Base(path)
+	priority := 50
+
+	switch {
+	case strings.HasPrefix(base, "readme"):
+		priority += 40
+	case base == "main.go" || strings.HasPrefix(base, "index.") || strings.HasPrefix(base, "main.") || strings.HasPrefix(base, "app."):
+		priority += 20
+	case base == "go.mod" || base == "package.json" || base == "cargo.toml" || base == "pyproject.toml":
+		priority += 20
+	}
+
+	switch {
+	case strings.HasSuffix(base, ".lock") || strings.HasSuffix(base, ".sum") || strings.HasSuffix(base, "-lock.json") ||
+		strings.HasSuffix(base, "-lock.yaml") || strings.Contains(base, ".min."):
+		priority -= 40
+	case strings.Contains(path, "generated") || strings.HasSuffix(base, ".pb.go") || strings.Contains(path, "testdata/") ||
+		strings.Contains(path, "fixtures/") || strings.Contains(path, "vendor/"):
+		priority -= 30
+	case strings.Contains(base, "_test.") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.Contains(path, "test/") || strings.Contains(path, "tests/") || strings.Contains(path, "__tests__/"):
+		priority -= 20
+	}
+
+	switch filepath.Ext(base) {
+	case ".json", ".csv", ".txt", ".xml", ".svg":
+		priority -= 10
+	}
+
+	// Deeply nested files are usually less central to the project
+	priority -= 2 * strings.Count(path, "/")
+	return priority
+}
+
+// summarizeContent reduces a file to an outline of its declarations and headings.
+// It is a local, model-free summary that keeps the file's shape visible to the LLM.
+func summarizeContent(relPath, content string) string {
+	lines := strings.Split(content, "\n")
+	var kept []string
+	for _, line := range lines {
+		if isOutlineLine(relPath, line) {
+			kept = append(kept, strings.TrimRight(line, " \t{"))
+		}
+	}
+	return fmt.Sprintf("(summarized: %d of %d lines kept)\n%s", len(kept), len(lines), strings.Join(kept, "\n"))
+}
+
+var outlinePrefixes = []string{
+	"package ", "import ", "from ", "func ", "type ", "const ", "var ",
+	"class ", "def ", "async def ", "interface ", "enum ", "struct ", "trait ", "impl ", "fn ", "pub ", "mod ",
+	"export ", "function ", "async function ", "module.exports", "public ", "protected ", "private ",
+}
+
+func isOutlineLine(relPath, line string) bool {
+	if strings.HasSuffix(strings.ToLower(relPath), ".md") {
+		return strings.HasPrefix(line, "#")
+	}
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return false
+	}
+	// Only top-level or singly-indented declarations
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	if indent > 4 {
+		return false
+	}
+	for _, prefix := range outlinePrefixes {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
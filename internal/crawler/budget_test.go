@@ -0,0 +1,141 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jake/llmify/internal/tokenizer"
+)
+
+func TestBudgetHoldsWithAdversarialContent(t *testing.T) {
+	tok, err := tokenizer.Get("cl100k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files whose framing grows with their content: long backtick runs,
+	// many CDATA terminators and characters JSON escapes
+	contents := map[string]string{
+		"README.md": strings.Repeat("Some text with ```` fences ````` inside.\n", 40),
+		"main.go":   strings.Repeat("s := \"]]>]]>\" // \x01\x02\"\\\n", 60),
+		"data.json": strings.Repeat("{\"k\": \"\\u0000 ]]> `````\"}\n", 80),
+		"notes.txt": strings.Repeat("plain words ", 200),
+	}
+	for _, format := range []string{FormatText, FormatXML, FormatJSON, FormatMarkdown} {
+		for _, overflow := range []string{OverflowDrop, OverflowTruncate, OverflowSummarize} {
+			for _, maxTokens := range []int{150, 400, 900, 2000} {
+				name := fmt.Sprintf("%s/%s/%d", format, overflow, maxTokens)
+				result := &CrawlResult{FileTree: "tree", Contents: map[string]string{}}
+				for _, path := range []string{"README.md", "data.json", "main.go", "notes.txt"} {
+					result.IncludedFiles = append(result.IncludedFiles, path)
+					result.Contents[path] = contents[path]
+				}
+				report, err := ApplyTokenBudget(result, BudgetOptions{
+					MaxTokens:     maxTokens,
+					Tokenizer:     tok,
+					Overflow:      overflow,
+					IncludeHeader: true,
+					Format:        format,
+				})
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				output, err := BuildOutputContent(result, format, true)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				got := tok.Count(output)
+				if got > maxTokens {
+					t.Errorf("%s: output has %d tokens, over the budget", name, got)
+				}
+				if report.TotalTokens != got {
+					t.Errorf("%s: report says %d tokens, output has %d", name, report.TotalTokens, got)
+				}
+			}
+		}
+	}
+}
+
+func TestBudgetActions(t *testing.T) {
+	tok, err := tokenizer.Get("cl100k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("func handler(w http.ResponseWriter) { w.Write(nil) }\n", 100)
+	newResult := func() *CrawlResult {
+		return &CrawlResult{
+			IncludedFiles: []string{"big.go", "README.md"},
+			Contents:      map[string]string{"big.go": big, "README.md": "# Project\n\nShort readme.\n"},
+		}
+	}
+
+	t.Run("unbudgeted", func(t *testing.T) {
+		report, err := ApplyTokenBudget(newResult(), BudgetOptions{Tokenizer: tok})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.TotalTokens != report.OriginalTokens {
+			t.Errorf("total %d != original %d without a budget", report.TotalTokens, report.OriginalTokens)
+		}
+		for _, f := range report.Files {
+			if f.Action != ActionIncluded || f.Tokens != f.OriginalTokens {
+				t.Errorf("%s: %s with %d of %d tokens", f.Path, f.Action, f.Tokens, f.OriginalTokens)
+			}
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		result := newResult()
+		report, err := ApplyTokenBudget(result, BudgetOptions{Tokenizer: tok, MaxTokens: 300, Overflow: OverflowTruncate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions := map[string]FileTokens{}
+		for _, f := range report.Files {
+			actions[f.Path] = f
+		}
+		if a := actions["README.md"].Action; a != ActionIncluded {
+			t.Errorf("README.md is %s; the highest priority file should be included", a)
+		}
+		f := actions["big.go"]
+		if f.Action != ActionTruncated {
+			t.Fatalf("big.go is %s, want truncated", f.Action)
+		}
+		// The marker counts the tokens left out, not the whole file
+		content := result.Contents["big.go"]
+		i := strings.LastIndex(content, "\n... (truncated, ")
+		if i < 0 {
+			t.Fatalf("no truncation marker in %q", content)
+		}
+		var omitted int
+		if _, err := fmt.Sscanf(content[i:], "\n... (truncated, %d tokens omitted)", &omitted); err != nil {
+			t.Fatal(err)
+		}
+		if want := f.OriginalTokens - tok.Count(content[:i]); omitted != want {
+			t.Errorf("marker says %d tokens omitted, want %d", omitted, want)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		result := newResult()
+		report, err := ApplyTokenBudget(result, BudgetOptions{Tokenizer: tok, MaxTokens: 300, Overflow: OverflowDrop})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.IncludedFiles) != 1 || result.IncludedFiles[0] != "README.md" {
+			t.Errorf("kept %v, want only README.md", result.IncludedFiles)
+		}
+		if _, ok := result.Contents["big.go"]; ok {
+			t.Error("the dropped file's content was kept")
+		}
+		if report.TotalTokens > 300 {
+			t.Errorf("total %d over budget", report.TotalTokens)
+		}
+	})
+
+	t.Run("unknown overflow", func(t *testing.T) {
+		if _, err := ApplyTokenBudget(newResult(), BudgetOptions{Tokenizer: tok, Overflow: "shrink"}); err == nil {
+			t.Error("accepted an unknown overflow strategy")
+		}
+	})
+}
@@ -12,10 +12,12 @@ import (
 
 // CrawlResult represents the results of crawling a project
 type CrawlResult struct {
+	Root          string // Directory the crawl started from; IncludedFiles are relative to it
 	IncludedFiles []string
 	FileTree      string
 	ExcludedCount int
 	IncludedCount int
+	Contents      map[string]string // Loaded (and possibly truncated) file contents, keyed by relative path
 }
 
 // LoadIgnoreMatcher loads ignore patterns from .gitignore and .llmignore files
@@ -152,7 +154,7 @@ func CreateDefaultLLMIgnoreFile(projectRoot string) error {
 
 // CrawlProject crawls the project directory and returns a CrawlResult
 func CrawlProject(projectRoot string, matcher *ignore.IgnoreMatcher, maxDepth int, excludeBinary bool) (*CrawlResult, error) {
-	result := &CrawlResult{}
+	result := &CrawlResult{Root: projectRoot}
 
 	// Generate file tree
 	tree, err := generateFileTree(projectRoot, matcher, maxDepth)
@@ -215,27 +217,52 @@ func CrawlProject(projectRoot string, matcher *ignore.IgnoreMatcher, maxDepth in
 }
 
 // BuildOutputContent builds the final output content from the crawl results
-func BuildOutputContent(result *CrawlResult, includeHeader bool) string {
+// in the requested format (text, xml, json or markdown).
+func BuildOutputContent(result *CrawlResult, format string, includeHeader bool) (string, error) {
+	f, err := formatterFor(format)
+	if err != nil {
+		return "", err
+	}
+
 	var content strings.Builder
+	content.WriteString(f.header(result, includeHeader))
 
-	if includeHeader {
-		content.WriteString("# Project Structure\n\n")
-		content.WriteString(result.FileTree)
-		content.WriteString("\n\n# File Contents\n\n")
+	for i, file := range result.IncludedFiles {
+		fileContent, err := result.fileContent(file)
+		if err != nil {
+			fileContent = fmt.Sprintf("Error reading file: %v", err)
+		}
+		content.WriteString(f.file(file, fileContent, i))
 	}
 
-	for _, file := range result.IncludedFiles {
-		content.WriteString(fmt.Sprintf("## %s\n\n", file))
-		fileContent, err := util.ReadFileContent(file)
-		if err != nil {
-			content.WriteString(fmt.Sprintf("Error reading file: %v\n\n", err))
+	content.WriteString(f.footer(includeHeader))
+	return content.String(), nil
+}
+
+// fileContent returns the loaded content of a file, reading it from disk if needed
+func (r *CrawlResult) fileContent(file string) (string, error) {
+	if content, ok := r.Contents[file]; ok {
+		return content, nil
+	}
+	return util.ReadFileContent(filepath.Join(r.Root, file))
+}
+
+// loadContents reads every included file into Contents. Unreadable files keep
+// their error message as content, matching BuildOutputContent.
+func (r *CrawlResult) loadContents() {
+	if r.Contents == nil {
+		r.Contents = make(map[string]string, len(r.IncludedFiles))
+	}
+	for _, file := range r.IncludedFiles {
+		if _, ok := r.Contents[file]; ok {
 			continue
 		}
-		content.WriteString(fileContent)
-		content.WriteString("\n\n")
+		content, err := util.ReadFileContent(filepath.Join(r.Root, file))
+		if err != nil {
+			content = fmt.Sprintf("Error reading file: %v", err)
+		}
+		r.Contents[file] = content
 	}
-
-	return content.String()
 }
 
 // generateFileTree generates a tree representation of the directory structure
@@ -0,0 +1,221 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jake/llmify/internal/language"
+)
+
+// Supported output formats for BuildOutputContent.
+const (
+	FormatText     = "text"
+	FormatXML      = "xml"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// outputFormatter renders the pieces of an llm.txt file in a specific format.
+type outputFormatter interface {
+	// header returns everything before the first file, including the project tree if requested
+	header(result *CrawlResult, includeHeader bool) string
+	// file renders a single file; index is the file's position in the output
+	file(path, content string, index int) string
+	// footer returns everything after the last file
+	footer(includeHeader bool) string
+}
+
+// formatterFor returns the formatter for the named output format
+func formatterFor(format string) (outputFormatter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return textFormatter{}, nil
+	case FormatXML:
+		return xmlFormatter{}, nil
+	case FormatJSON:
+		return jsonFormatter{}, nil
+	case FormatMarkdown, "md":
+		return markdownFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (use text, xml, json or markdown)", format)
+	}
+}
+
+// textFormatter writes the original "## path" layout.
+type textFormatter struct{}
+
+func (textFormatter) header(result *CrawlResult, includeHeader bool) string {
+	if !includeHeader {
+		return ""
+	}
+	return "# Project Structure\n\n" + result.FileTree + "\n\n# File Contents\n\n"
+}
+
+func (textFormatter) file(path, content string, index int) string {
+	return fmt.Sprintf("## %s\n\n%s\n\n", path, content)
+}
+
+func (textFormatter) footer(includeHeader bool) string { return "" }
+
+// xmlFormatter wraps each file in <file path="..." lang="..."> with CDATA content.
+type xmlFormatter struct{}
+
+func (xmlFormatter) header(result *CrawlResult, includeHeader bool) string {
+	var b strings.Builder
+	b.WriteString("<project>\n")
+	if includeHeader {
+		b.WriteString("<structure>")
+		b.WriteString(xmlCDATA(result.FileTree))
+		b.WriteString("</structure>\n")
+	}
+	return b.String()
+}
+
+func (xmlFormatter) file(path, content string, index int) string {
+	return fmt.Sprintf("<file path=\"%s\" lang=\"%s\" size=\"%d\">%s</file>\n",
+		xmlAttr(path), xmlAttr(language.Detect(path)), len(content), xmlCDATA(content))
+}
+
+func (xmlFormatter) footer(includeHeader bool) string { return "</project>\n" }
+
+// xmlAttr escapes a string for use inside a double-quoted XML attribute
+func xmlAttr(s string) string {
+	var b strings.Builder
+	for _, r := range xmlSanitize(s) {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\'':
+			b.WriteString("&apos;")
+		case '\t':
+			b.WriteString("&#x9;")
+		case '\n':
+			b.WriteString("&#xA;")
+		case '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// xmlCDATA wraps s in a CDATA section. A literal "]]>" cannot appear inside CDATA,
+// so it is split across two adjacent sections.
+func xmlCDATA(s string) string {
+	s = strings.ReplaceAll(xmlSanitize(s), "]]>", "]]]]><![CDATA[>")
+	return "<![CDATA[" + s + "]]>"
+}
+
+// xmlSanitize replaces characters that are not allowed anywhere in an XML 1.0
+// document (most control characters, surrogates, U+FFFE/U+FFFF) with U+FFFD.
+func xmlSanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r >= 0xD800 && r <= 0xDFFF, r == 0xFFFE, r == 0xFFFF:
+			return utf8.RuneError
+		}
+		return r
+	}, s)
+}
+
+// jsonFormatter writes an array of path/lang/size/content objects. With a header,
+// the array is wrapped in an object alongside the project structure.
+type jsonFormatter struct{}
+
+type jsonFile struct {
+	Path    string `json:"path"`
+	Lang    string `json:"lang"`
+	Size    int    `json:"size"`
+	Content string `json:"content"`
+}
+
+func (jsonFormatter) header(result *CrawlResult, includeHeader bool) string {
+	if !includeHeader {
+		return "["
+	}
+	return "{\n\"structure\": " + jsonString(result.FileTree) + ",\n\"files\": ["
+}
+
+func (jsonFormatter) file(path, content string, index int) string {
+	sep := "\n"
+	if index > 0 {
+		sep = ",\n"
+	}
+	return sep + jsonString(jsonFile{
+		Path:    path,
+		Lang:    language.Detect(path),
+		Size:    len(content),
+		Content: content,
+	})
+}
+
+func (jsonFormatter) footer(includeHeader bool) string {
+	if !includeHeader {
+		return "\n]\n"
+	}
+	return "\n]\n}\n"
+}
+
+// jsonString encodes v as compact JSON without HTML escaping. Invalid UTF-8 is
+// replaced with U+FFFD by the encoder.
+func jsonString(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		// Only reachable for unsupported types, which we never pass
+		return "null"
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// markdownFormatter writes each file as a fenced code block tagged with its language.
+type markdownFormatter struct{}
+
+func (markdownFormatter) header(result *CrawlResult, includeHeader bool) string {
+	if !includeHeader {
+		return ""
+	}
+	fence := markdownFence(result.FileTree)
+	return "# Project Structure\n\n" + fence + "text\n" + result.FileTree + "\n" + fence + "\n\n# File Contents\n\n"
+}
+
+func (markdownFormatter) file(path, content string, index int) string {
+	fence := markdownFence(content)
+	return fmt.Sprintf("## `%s`\n\n%s%s\n%s\n%s\n\n",
+		strings.ReplaceAll(path, "`", "'"), fence, language.Detect(path), content, fence)
+}
+
+func (markdownFormatter) footer(includeHeader bool) string { return "" }
+
+// markdownFence returns a backtick fence longer than any backtick run in content,
+// so the content can never close the block early.
+func markdownFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// adversarialFiles hold content that collides with the framing of each format.
+var adversarialFiles = map[string]string{
+	"cdata.xml":  "<a><![CDATA[x]]></a>\nend ]]> and ]]]]> too\n",
+	"fences.md":  "```go\nfunc f() {}\n```\n\n````\nnested\n````\n",
+	"control.go": "package p\n\nvar s = \"\x00\x01\x1b\t\\\" \"\n",
+	"edges.txt":  "\n  leading and trailing whitespace  \n\n",
+}
+
+func adversarialResult() *CrawlResult {
+	result := &CrawlResult{FileTree: "tree ]]> ``` \x01", Contents: map[string]string{}}
+	for _, path := range []string{"cdata.xml", "control.go", "edges.txt", "fences.md"} {
+		result.IncludedFiles = append(result.IncludedFiles, path)
+		result.Contents[path] = adversarialFiles[path]
+	}
+	return result
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	output, err := BuildOutputContent(adversarialResult(), FormatXML, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var project struct {
+		Structure string `xml:"structure"`
+		Files     []struct {
+			Path    string `xml:"path,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal([]byte(output), &project); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, output)
+	}
+	if len(project.Files) != len(adversarialFiles) {
+		t.Fatalf("got %d files, want %d", len(project.Files), len(adversarialFiles))
+	}
+	for _, f := range project.Files {
+		want := xmlSanitize(adversarialFiles[f.Path])
+		if f.Content != want {
+			t.Errorf("%s: content = %q, want %q", f.Path, f.Content, want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	output, err := BuildOutputContent(adversarialResult(), FormatJSON, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []jsonFile
+	if err := json.Unmarshal([]byte(output), &files); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	for _, f := range files {
+		if want := adversarialFiles[f.Path]; f.Content != want {
+			t.Errorf("%s: content = %q, want %q", f.Path, f.Content, want)
+		}
+	}
+}
+
+func TestMarkdownFenceOutlastsContent(t *testing.T) {
+	for path, content := range adversarialFiles {
+		rendered := markdownFormatter{}.file(path, content, 0)
+		fence := markdownFence(content)
+		if strings.Contains(content, fence) {
+			t.Errorf("%s: fence %q occurs in the content", path, fence)
+		}
+		body, ok := strings.CutSuffix(rendered, "\n"+fence+"\n\n")
+		if !ok || !strings.HasSuffix(body, "\n"+content) {
+			t.Errorf("%s: content not enclosed verbatim:\n%s", path, rendered)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package diff
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Section is one file's part of a diff. Unlike Parse, Sections splits at the
+// "diff --git" and "@@" lines without checking line counts, so it also accepts
+// diffs whose trailing whitespace was trimmed.
+type Section struct {
+	Path      string
+	Header    string   // Lines from "diff --git" up to the first hunk
+	Hunks     []string // Each hunk with its "@@" header
+	Added     int
+	Removed   int
+	Generated bool // Lockfile, vendored or generated code, see IsGenerated
+}
+
+// Sections splits a git diff into one section per file.
+func Sections(text string) []Section {
+	var sections []Section
+	var header, hunk []string
+	var current *Section
+	flushHunk := func() {
+		if current != nil && len(hunk) > 0 {
+			current.Hunks = append(current.Hunks, strings.Join(hunk, "\n"))
+		}
+		hunk = nil
+	}
+	flush := func() {
+		if current == nil {
+			return
+		}
+		flushHunk()
+		current.Header = strings.Join(header, "\n")
+		current.Generated = IsGenerated(current.Path, current.Hunks)
+		sections = append(sections, *current)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath := gitHeaderPaths(line)
+			current = &Section{Path: newPath}
+			if current.Path == "" {
+				current.Path = oldPath
+			}
+			header = []string{line}
+		case current == nil:
+			continue // Anything before the first file, e.g. a commit header
+		case strings.HasPrefix(line, "@@ "):
+			flushHunk()
+			hunk = []string{line}
+		case hunk == nil:
+			header = append(header, line)
+		default:
+			hunk = append(hunk, line)
+			if strings.HasPrefix(line, "+") {
+				current.Added++
+			} else if strings.HasPrefix(line, "-") {
+				current.Removed++
+			}
+		}
+	}
+	flush()
+	return sections
+}
+
+// String renders the section as it appeared in the diff.
+func (s Section) String() string {
+	if len(s.Hunks) == 0 {
+		return s.Header
+	}
+	return s.Header + "\n" + strings.Join(s.Hunks, "\n")
+}
+
+// Stat summarizes the section in one line, e.g. "go.sum (generated, +12 -3)".
+func (s Section) Stat() string {
+	kind := "changed"
+	if s.Generated {
+		kind = "generated"
+	}
+	return fmt.Sprintf("%s (%s, +%d -%d)", s.Path, kind, s.Added, s.Removed)
+}
+
+// generatedNames are lockfiles and other files that are written by tools.
+var generatedNames = map[string]bool{
+	"package-lock.json":   true,
+	"npm-shrinkwrap.json": true,
+	"yarn.lock":           true,
+	"pnpm-lock.yaml":      true,
+	"bun.lockb":           true,
+	"go.sum":              true,
+	"Cargo.lock":          true,
+	"poetry.lock":         true,
+	"Pipfile.lock":        true,
+	"uv.lock":             true,
+	"Gemfile.lock":        true,
+	"composer.lock":       true,
+	"mix.lock":            true,
+	"pubspec.lock":        true,
+	"Podfile.lock":        true,
+	"flake.lock":          true,
+}
+
+// generatedSuffixes match minified, compiled and snapshot files.
+var generatedSuffixes = []string{
+	".min.js", ".min.css", ".map", ".pb.go", "_pb2.py", ".pb.ts", ".snap", "_generated.go", ".gen.go",
+}
+
+// generatedDirs hold vendored or built code.
+var generatedDirs = []string{"vendor/", "node_modules/", "dist/", "build/"}
+
+// IsGenerated reports whether a file is a lockfile, vendored or built output,
+// or generated code. hunks are checked for the standard "Code generated ...
+// DO NOT EDIT." and "@generated" markers.
+func IsGenerated(filePath string, hunks []string) bool {
+	if generatedNames[path.Base(filePath)] {
+		return true
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(filePath, suffix) {
+			return true
+		}
+	}
+	for _, dir := range generatedDirs {
+		if strings.HasPrefix(filePath, dir) || strings.Contains(filePath, "/"+dir) {
+			return true
+		}
+	}
+	if len(hunks) == 0 {
+		return false
+	}
+	// The markers sit at the top of the file, so only a first hunk that
+	// starts there is checked
+	first := hunks[0]
+	if m := hunkHeaderRe.FindStringSubmatch(strings.SplitN(first, "\n", 2)[0]); m == nil || m[3] != "1" {
+		return false
+	}
+	if len(first) > 2000 {
+		first = first[:2000]
+	}
+	return strings.Contains(first, "Code generated") && strings.Contains(first, "DO NOT EDIT") ||
+		strings.Contains(first, "@generated")
+}
+
+// Chunk is a part of a diff small enough to send in one request.
+type Chunk struct {
+	Paths []string
+	Diff  string
+}
+
+// Chunks packs sections into chunks of at most budget tokens, as measured by
+// count. Files stay whole where they fit; larger files are split between
+// hunks, and hunks that do not fit on their own are split between lines. Every
+// part of a split file repeats its header.
+func Chunks(sections []Section, budget int, count func(string) int) []Chunk {
+	var chunks []Chunk
+	var current Chunk
+	var parts []string
+	used := 0
+	flush := func() {
+		if len(parts) > 0 {
+			current.Diff = strings.Join(parts, "\n")
+			chunks = append(chunks, current)
+		}
+		current, parts, used = Chunk{}, nil, 0
+	}
+	add := func(filePath, text string, tokens int) {
+		if used > 0 && used+tokens > budget {
+			flush()
+		}
+		if n := len(current.Paths); n == 0 || current.Paths[n-1] != filePath {
+			current.Paths = append(current.Paths, filePath)
+		}
+		parts = append(parts, text)
+		used += tokens
+	}
+
+	for _, s := range sections {
+		text := s.String()
+		if tokens := count(text); tokens <= budget {
+			add(s.Path, text, tokens)
+			continue
+		}
+		headerTokens := count(s.Header)
+		var pieces []string
+		for _, h := range s.Hunks {
+			pieces = append(pieces, splitHunk(h, budget-headerTokens, count)...)
+		}
+		if len(pieces) == 0 {
+			pieces = splitHunk(s.Header, budget, count)
+			headerTokens = 0
+		}
+		for i, piece := range pieces {
+			tokens := count(piece)
+			n := len(current.Paths)
+			if i > 0 && n > 0 && current.Paths[n-1] == s.Path && used+tokens <= budget {
+				add(s.Path, piece, tokens)
+				continue
+			}
+			// The piece starts a new part of the file, which needs its header
+			if headerTokens > 0 {
+				piece = s.Header + "\n" + piece
+			}
+			add(s.Path, piece, headerTokens+tokens)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitHunk cuts h at line boundaries into pieces of at most budget tokens.
+// Pieces after the first start with the hunk header marked as continued. A
+// single line longer than the budget is truncated.
+func splitHunk(h string, budget int, count func(string) int) []string {
+	if count(h) <= budget {
+		return []string{h}
+	}
+	lines := strings.Split(h, "\n")
+	continued := lines[0] + " (continued)"
+	var pieces []string
+	var piece []string
+	used := 0
+	for _, line := range lines {
+		n := count(line) + 1
+		if n > budget/2 {
+			line = truncateLine(line, budget/2, count)
+			n = count(line) + 1
+		}
+		if len(piece) > 0 && used+n > budget {
+			pieces = append(pieces, strings.Join(piece, "\n"))
+			piece, used = []string{continued}, count(continued)+1
+		}
+		piece = append(piece, line)
+		used += n
+	}
+	if len(piece) > 0 {
+		pieces = append(pieces, strings.Join(piece, "\n"))
+	}
+	return pieces
+}
+
+// truncateLine cuts line to about budget tokens.
+func truncateLine(line string, budget int, count func(string) int) string {
+	const note = " ... (line truncated)"
+	lo, hi := 0, len(line)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if count(line[:mid]) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return strings.ToValidUTF8(line[:lo], "") + note
+}
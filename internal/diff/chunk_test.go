@@ -0,0 +1,230 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fileDiff returns the diff of a file that gains one hunk of n added lines
+// for each n in hunkLines.
+func fileDiff(path string, hunkLines ...int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\nindex 1111111..2222222 100644\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	for i, n := range hunkLines {
+		start := i*100 + 1
+		fmt.Fprintf(&b, "@@ -%d,0 +%d,%d @@\n", start, start, n)
+		for j := 0; j < n; j++ {
+			fmt.Fprintf(&b, "+%s line %d.%d\n", path, i, j)
+		}
+	}
+	return b.String()
+}
+
+// countLines counts lines as tokens, so budgets are easy to reason about.
+func countLines(s string) int {
+	return strings.Count(s, "\n") + 1
+}
+
+func TestSections(t *testing.T) {
+	files := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var a = 1
++var a = 2
+@@ -10,2 +10,3 @@ func main() {
+ 	run()
++	stop()
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 3333333..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..4444444
+Binary files /dev/null and b/logo.png differ
+diff --git a/web/yarn.lock b/web/yarn.lock
+index 5555555..6666666 100644
+--- a/web/yarn.lock
++++ b/web/yarn.lock
+@@ -1 +1 @@
+-a@1
++a@2`
+	// A commit header before the first file is not part of any section
+	sections := Sections("commit abc\nAuthor: Test\n\n    Message\n\n" + files + "\n")
+	want := []Section{
+		{
+			Path:   "main.go",
+			Header: "diff --git a/main.go b/main.go\nindex 1111111..2222222 100644\n--- a/main.go\n+++ b/main.go",
+			Hunks: []string{
+				"@@ -1,3 +1,3 @@\n package main\n-var a = 1\n+var a = 2",
+				"@@ -10,2 +10,3 @@ func main() {\n \trun()\n+\tstop()",
+			},
+			Added:   2,
+			Removed: 1,
+		},
+		{
+			Path:    "old.txt",
+			Header:  "diff --git a/old.txt b/old.txt\ndeleted file mode 100644\nindex 3333333..0000000\n--- a/old.txt\n+++ /dev/null",
+			Hunks:   []string{"@@ -1,2 +0,0 @@\n-one\n-two"},
+			Removed: 2,
+		},
+		{
+			Path:   "logo.png",
+			Header: "diff --git a/logo.png b/logo.png\nnew file mode 100644\nindex 0000000..4444444\nBinary files /dev/null and b/logo.png differ",
+		},
+		{
+			Path:      "web/yarn.lock",
+			Header:    "diff --git a/web/yarn.lock b/web/yarn.lock\nindex 5555555..6666666 100644\n--- a/web/yarn.lock\n+++ b/web/yarn.lock",
+			Hunks:     []string{"@@ -1 +1 @@\n-a@1\n+a@2"},
+			Added:     1,
+			Removed:   1,
+			Generated: true,
+		},
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Fatalf("Sections() = %#v, want %#v", sections, want)
+	}
+
+	var parts []string
+	for _, s := range sections {
+		parts = append(parts, s.String())
+	}
+	if got := strings.Join(parts, "\n"); got != files {
+		t.Errorf("the sections do not add up to the diff:\n%s", got)
+	}
+	if got := sections[3].Stat(); got != "web/yarn.lock (generated, +1 -1)" {
+		t.Errorf("Stat() = %q", got)
+	}
+	if got := sections[0].Stat(); got != "main.go (changed, +2 -1)" {
+		t.Errorf("Stat() = %q", got)
+	}
+	if Sections("not a diff") != nil {
+		t.Error("text without files has sections")
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	marker := "@@ -0,0 +1,3 @@\n+// Code generated by protoc-gen-go. DO NOT EDIT.\n+\n+package pb"
+	tests := []struct {
+		path  string
+		hunks []string
+		want  bool
+	}{
+		{"go.sum", nil, true},
+		{"web/package-lock.json", nil, true},
+		{"static/app.min.js", nil, true},
+		{"api/service.pb.go", nil, true},
+		{"vendor/github.com/x/y.go", nil, true},
+		{"web/node_modules/left-pad/index.js", nil, true},
+		{"src/vendorized.go", nil, false},
+		{"builder/main.go", nil, false},
+		{"go.summary", nil, false},
+		{"api/types.go", []string{marker}, true},
+		{"api/types.go", []string{"@@ -1,2 +1,2 @@\n-// @generated by tool\n+// @generated by tool v2"}, true},
+		{"api/types.go", []string{"@@ -40,3 +40,3 @@\n // Code generated by hand. DO NOT EDIT the next line.\n-a\n+b"}, false},
+		{"api/types.go", []string{"@@ -1,2 +1,2 @@\n-a\n+b", marker}, false},
+		{"api/types.go", []string{"@@ -1,2 +1,2 @@\n // Code generated or not\n-a\n+b"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsGenerated(tt.path, tt.hunks); got != tt.want {
+			t.Errorf("IsGenerated(%q, %q) = %v, want %v", tt.path, tt.hunks, got, tt.want)
+		}
+	}
+}
+
+func TestChunks(t *testing.T) {
+	tests := []struct {
+		name   string
+		diff   string
+		budget int
+		want   [][]string // Paths of each chunk
+	}{
+		{"everything fits", fileDiff("a.go", 5) + fileDiff("b.go", 5) + fileDiff("c.go", 5), 100, [][]string{{"a.go", "b.go", "c.go"}}},
+		{"files are packed in order", fileDiff("a.go", 5) + fileDiff("b.go", 5) + fileDiff("c.go", 5), 25, [][]string{{"a.go", "b.go"}, {"c.go"}}},
+		{"a file exactly at the budget", fileDiff("a.go", 15) + fileDiff("b.go", 1), 20, [][]string{{"a.go"}, {"b.go"}}},
+		{"a large file is split between hunks", fileDiff("a.go", 2) + fileDiff("big.go", 8, 8, 8) + fileDiff("c.go", 2), 25, [][]string{{"a.go", "big.go"}, {"big.go"}, {"c.go"}}},
+		{"a large hunk is split between lines", fileDiff("big.go", 40), 25, [][]string{{"big.go"}, {"big.go"}, {"big.go"}}},
+	}
+	for _, tt := range tests {
+		sections := Sections(tt.diff)
+		chunks := Chunks(sections, tt.budget, countLines)
+		var paths [][]string
+		for _, c := range chunks {
+			paths = append(paths, c.Paths)
+			if n := countLines(c.Diff); n > tt.budget {
+				t.Errorf("%s: a chunk has %d tokens, over the budget of %d", tt.name, n, tt.budget)
+			}
+		}
+		if !reflect.DeepEqual(paths, tt.want) {
+			t.Errorf("%s: chunk paths = %q, want %q", tt.name, paths, tt.want)
+		}
+
+		// Every changed line is sent exactly once, in order, and every part
+		// of a file starts with its header
+		var lines []string
+		for _, c := range chunks {
+			for _, path := range c.Paths {
+				if !strings.Contains(c.Diff, "diff --git a/"+path+" b/"+path+"\n") {
+					t.Errorf("%s: a chunk of %s lacks its header", tt.name, path)
+				}
+			}
+			for _, line := range strings.Split(c.Diff, "\n") {
+				if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+					lines = append(lines, line)
+				}
+			}
+		}
+		var want []string
+		for _, line := range strings.Split(tt.diff, "\n") {
+			if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+				want = append(want, line)
+			}
+		}
+		if !reflect.DeepEqual(lines, want) {
+			t.Errorf("%s: the chunks hold %d changed lines, want the %d of the diff in order", tt.name, len(lines), len(want))
+		}
+	}
+}
+
+func TestSplitHunk(t *testing.T) {
+	hunk := "@@ -1,0 +1,4 @@\n+aaaaaaaaa\n+bbbbbbbbb\n+ccccccccc\n+ddddddddd"
+	count := func(s string) int { return len(s) }
+
+	if got := splitHunk(hunk, 100, count); !reflect.DeepEqual(got, []string{hunk}) {
+		t.Errorf("a hunk that fits was split: %q", got)
+	}
+
+	// Each line costs its length plus the newline
+	want := []string{
+		"@@ -1,0 +1,4 @@\n+aaaaaaaaa\n+bbbbbbbbb",
+		"@@ -1,0 +1,4 @@ (continued)\n+ccccccccc",
+		"@@ -1,0 +1,4 @@ (continued)\n+ddddddddd",
+	}
+	if got := splitHunk(hunk, 40, count); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitHunk() = %q, want %q", got, want)
+	}
+
+	// A line longer than half the budget is truncated
+	long := "@@ -1,0 +1,1 @@\n+" + strings.Repeat("x", 200)
+	got := splitHunk(long, 100, count)
+	if len(got) != 1 || !strings.HasPrefix(got[0], "@@ -1,0 +1,1 @@\n+xxx") || !strings.HasSuffix(got[0], "x ... (line truncated)") || len(got[0]) > 100 {
+		t.Errorf("splitHunk() = %q, want one piece with the line truncated", got)
+	}
+
+	// Truncation never splits a character
+	wide := "@@ -1,0 +1,1 @@\n+" + strings.Repeat("é", 60)
+	got = splitHunk(wide, 101, count)
+	if len(got) != 1 || strings.ContainsRune(got[0], '\uFFFD') || !strings.Contains(got[0], "é ... (line truncated)") || !utf8.ValidString(got[0]) {
+		t.Errorf("splitHunk() = %q, want one piece with the line cut between characters", got)
+	}
+}
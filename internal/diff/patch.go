@@ -0,0 +1,308 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// File is one file's section of a unified git diff.
+type File struct {
+	OldPath string   // Empty for added files
+	NewPath string   // Empty for deleted files
+	Header  []string // Lines from "diff --git" up to the first hunk
+	Hunks   []*Hunk
+	Binary  bool
+}
+
+// Hunk is one "@@" section of a file diff.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Section            string   // Text after the closing "@@", usually the enclosing function
+	Lines              []string // Body lines, each starting with ' ', '+', '-' or '\'
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// Parse splits the output of git diff into files and hunks. The diff should be
+// generated without color and with the default a/ and b/ prefixes.
+func Parse(text string) ([]*File, error) {
+	var files []*File
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if !strings.HasPrefix(line, "diff --git ") {
+			if line == "" {
+				i++
+				continue
+			}
+			return nil, fmt.Errorf("line %d: expected \"diff --git\", got %q", i+1, line)
+		}
+
+		f := &File{Header: []string{line}}
+		f.OldPath, f.NewPath = gitHeaderPaths(line)
+		i++
+		for ; i < len(lines) && !strings.HasPrefix(lines[i], "@@ ") && !strings.HasPrefix(lines[i], "diff --git "); i++ {
+			header := lines[i]
+			f.Header = append(f.Header, header)
+			switch {
+			case strings.HasPrefix(header, "new file mode"):
+				f.OldPath = ""
+			case strings.HasPrefix(header, "deleted file mode"):
+				f.NewPath = ""
+			case header == "GIT binary patch" || strings.HasPrefix(header, "Binary files "):
+				f.Binary = true
+			case strings.HasPrefix(header, "--- "):
+				f.OldPath = patchPath(header[4:], "a/")
+			case strings.HasPrefix(header, "+++ "):
+				f.NewPath = patchPath(header[4:], "b/")
+			}
+		}
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@ ") {
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Path(), err)
+			}
+			f.Hunks = append(f.Hunks, h)
+			i = next
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// parseHunk reads the hunk whose header is lines[start] and returns it with the
+// index of the line after it. The line counts in the header decide where the
+// hunk ends, so body lines that look like headers are read correctly.
+func parseHunk(lines []string, start int) (*Hunk, int, error) {
+	m := hunkHeaderRe.FindStringSubmatch(lines[start])
+	if m == nil {
+		return nil, 0, fmt.Errorf("line %d: malformed hunk header %q", start+1, lines[start])
+	}
+	h := &Hunk{
+		OldStart: atoi(m[1]), OldLines: countOrOne(m[2]),
+		NewStart: atoi(m[3]), NewLines: countOrOne(m[4]),
+		Section: m[5],
+	}
+
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	i := start + 1
+	for ; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		switch {
+		case line == "" || line[0] == ' ':
+			oldLeft--
+			newLeft--
+		case line[0] == '-':
+			oldLeft--
+		case line[0] == '+':
+			newLeft--
+		case line[0] == '\\':
+		default:
+			return nil, 0, fmt.Errorf("line %d: unexpected line in hunk: %q", i+1, line)
+		}
+		if line == "" {
+			line = " " // Some tools strip the space from blank context lines
+		}
+		h.Lines = append(h.Lines, line)
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return nil, 0, fmt.Errorf("line %d: hunk ends early", i+1)
+	}
+	// "\ No newline at end of file" follows the last line it applies to
+	for ; i < len(lines) && strings.HasPrefix(lines[i], "\\"); i++ {
+		h.Lines = append(h.Lines, lines[i])
+	}
+	return h, i, nil
+}
+
+// Path returns the file's path after the change, or before it for deleted files.
+func (f *File) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Splittable reports whether the file's hunks can be committed separately.
+// Added, deleted and binary files and mode changes must go in one piece.
+func (f *File) Splittable() bool {
+	if f.Binary || f.OldPath == "" || f.NewPath == "" || len(f.Hunks) < 2 {
+		return false
+	}
+	for _, line := range f.Header {
+		if strings.HasPrefix(line, "old mode ") {
+			return false
+		}
+	}
+	return true
+}
+
+// Patch renders the file header with only the given hunks, which must belong
+// to f. New-file line numbers are recomputed so the patch applies cleanly
+// without the hunks that were left out.
+func (f *File) Patch(hunks []*Hunk) string {
+	hunks = append([]*Hunk{}, hunks...)
+	sort.Slice(hunks, func(i, j int) bool { return hunks[i].OldStart < hunks[j].OldStart })
+
+	var b strings.Builder
+	for _, line := range f.Header {
+		b.WriteString(line + "\n")
+	}
+	delta := 0
+	for _, h := range hunks {
+		newStart := h.OldStart + delta
+		if h.NewLines == 0 && h.OldLines > 0 {
+			newStart-- // Like git, point at the line before a hunk that leaves nothing behind
+		} else if h.OldLines == 0 {
+			newStart++
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@%s\n", rangeSpec(h.OldStart, h.OldLines), rangeSpec(newStart, h.NewLines), h.Section)
+		for _, line := range h.Lines {
+			b.WriteString(line + "\n")
+		}
+		delta += h.NewLines - h.OldLines
+	}
+	return b.String()
+}
+
+// String renders the whole file diff.
+func (f *File) String() string {
+	return f.Patch(f.Hunks)
+}
+
+// String renders the hunk with its original header.
+func (h *Hunk) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "@@ -%s +%s @@%s\n", rangeSpec(h.OldStart, h.OldLines), rangeSpec(h.NewStart, h.NewLines), h.Section)
+	for _, line := range h.Lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// Change is the smallest part of a diff that can be committed on its own: one
+// hunk of a modified file, or a whole file that cannot be split.
+type Change struct {
+	ID    string // Stable label such as "C3", used to refer to the change in prompts
+	File  *File
+	Hunks []*Hunk
+}
+
+// Changes breaks files into independently committable changes, numbered in
+// diff order.
+func Changes(files []*File) []Change {
+	var changes []Change
+	add := func(f *File, hunks []*Hunk) {
+		changes = append(changes, Change{ID: fmt.Sprintf("C%d", len(changes)+1), File: f, Hunks: hunks})
+	}
+	for _, f := range files {
+		if !f.Splittable() {
+			add(f, f.Hunks)
+			continue
+		}
+		for _, h := range f.Hunks {
+			add(f, []*Hunk{h})
+		}
+	}
+	return changes
+}
+
+// String renders the change as a patch.
+func (c Change) String() string {
+	return c.File.Patch(c.Hunks)
+}
+
+// BuildPatch combines changes into one patch that git apply accepts, keeping
+// each file's hunks together and in order.
+func BuildPatch(changes []Change) string {
+	var order []*File
+	hunks := make(map[*File][]*Hunk)
+	for _, c := range changes {
+		if _, ok := hunks[c.File]; !ok {
+			order = append(order, c.File)
+			hunks[c.File] = nil
+		}
+		hunks[c.File] = append(hunks[c.File], c.Hunks...)
+	}
+	var b strings.Builder
+	for _, f := range order {
+		b.WriteString(f.Patch(hunks[f]))
+	}
+	return b.String()
+}
+
+// gitHeaderPaths extracts the paths from a "diff --git a/x b/x" line. Paths
+// containing " b/" are ambiguous there; the ---/+++ lines, when present,
+// override the result.
+func gitHeaderPaths(line string) (string, string) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if strings.HasPrefix(rest, `"`) {
+		if unquoted, tail, ok := cutQuoted(rest); ok {
+			return strings.TrimPrefix(unquoted, "a/"), patchPath(strings.TrimSpace(tail), "b/")
+		}
+	}
+	// Without renames both paths are the same, so split in the middle
+	if n := len(rest); n > 5 && (n-1)%2 == 0 {
+		half := (n - 1) / 2
+		if strings.HasPrefix(rest, "a/") && rest[half:half+3] == " b/" {
+			return rest[2:half], rest[half+3:]
+		}
+	}
+	if i := strings.Index(rest, " b/"); i >= 0 {
+		return strings.TrimPrefix(rest[:i], "a/"), rest[i+3:]
+	}
+	return rest, rest
+}
+
+// patchPath parses a path from a ---/+++ line, returning "" for /dev/null.
+func patchPath(s, prefix string) string {
+	s = strings.TrimRight(s, "\t") // git adds a tab after paths with spaces
+	if s == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(s, `"`) {
+		if unquoted, _, ok := cutQuoted(s); ok {
+			s = unquoted
+		}
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+// cutQuoted unquotes the C-style quoted string git uses for unusual paths at
+// the start of s and returns the remainder.
+func cutQuoted(s string) (string, string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			unquoted, err := strconv.Unquote(s[:i+1])
+			return unquoted, s[i+1:], err == nil
+		}
+	}
+	return "", s, false
+}
+
+func rangeSpec(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func countOrOne(s string) int {
+	if s == "" {
+		return 1
+	}
+	return atoi(s)
+}
@@ -0,0 +1,245 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+// contextDiff changes lines.txt (l1..l30) in three hunks: two lines replace
+// l3, l11 is deleted and Y is added after l20.
+const contextDiff = `diff --git a/lines.txt b/lines.txt
+index 1111111..2222222 100644
+--- a/lines.txt
++++ b/lines.txt
+@@ -2,3 +2,4 @@
+ l2
+-l3
++X3
++X3b
+ l4
+@@ -10,3 +11,2 @@ func b() {
+ l10
+-l11
+ l12
+@@ -20,2 +20,3 @@
+ l20
++Y
+ l21
+`
+
+// zeroContextDiff has a replacement, a pure delete and a pure add, as
+// produced by git diff -U0.
+const zeroContextDiff = `diff --git a/lines.txt b/lines.txt
+index 1111111..2222222 100644
+--- a/lines.txt
++++ b/lines.txt
+@@ -3 +3,2 @@
+-l3
++X3
++X3b
+@@ -5,2 +5,0 @@
+-l5
+-l6
+@@ -7,0 +7,2 @@ l7
++N1
++N2
+`
+
+const noNewlineDiff = `diff --git a/n.txt b/n.txt
+index 1111111..2222222 100644
+--- a/n.txt
++++ b/n.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`
+
+const addDeleteDiff = `diff --git a/new.txt b/new.txt
+new file mode 100644
+index 0000000..2222222
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++one
++two
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+index 1111111..0000000
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-one
+-two
+`
+
+func parseOne(t *testing.T, text string) *File {
+	t.Helper()
+	files, err := Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("parsed %d files, want 1", len(files))
+	}
+	return files[0]
+}
+
+// hunkHeaders returns the "@@" lines of a patch.
+func hunkHeaders(patch string) []string {
+	var headers []string
+	for _, line := range strings.Split(patch, "\n") {
+		if strings.HasPrefix(line, "@@ ") {
+			headers = append(headers, line)
+		}
+	}
+	return headers
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, text := range []string{contextDiff, zeroContextDiff, noNewlineDiff, addDeleteDiff} {
+		files, err := Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		for _, f := range files {
+			b.WriteString(f.String())
+		}
+		if b.String() != text {
+			t.Errorf("round trip changed the diff:\n%s\nwant\n%s", b.String(), text)
+		}
+	}
+}
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		hunks []int // Indexes of the hunks to keep, in the order passed to Patch
+		want  []string
+	}{
+		{"all", contextDiff, []int{0, 1, 2}, []string{"@@ -2,3 +2,4 @@", "@@ -10,3 +11,2 @@ func b() {", "@@ -20,2 +20,3 @@"}},
+		{"first dropped", contextDiff, []int{1, 2}, []string{"@@ -10,3 +10,2 @@ func b() {", "@@ -20,2 +19,3 @@"}},
+		{"middle dropped", contextDiff, []int{0, 2}, []string{"@@ -2,3 +2,4 @@", "@@ -20,2 +21,3 @@"}},
+		{"reversed", contextDiff, []int{2, 0}, []string{"@@ -2,3 +2,4 @@", "@@ -20,2 +21,3 @@"}},
+		{"only last", contextDiff, []int{2}, []string{"@@ -20,2 +20,3 @@"}},
+		{"pure delete alone", zeroContextDiff, []int{1}, []string{"@@ -5,2 +4,0 @@"}},
+		{"pure delete after growth", zeroContextDiff, []int{0, 1}, []string{"@@ -3 +3,2 @@", "@@ -5,2 +5,0 @@"}},
+		{"pure add alone", zeroContextDiff, []int{2}, []string{"@@ -7,0 +8,2 @@ l7"}},
+		{"pure add after growth", zeroContextDiff, []int{2, 0}, []string{"@@ -3 +3,2 @@", "@@ -7,0 +9,2 @@ l7"}},
+		{"pure add after delete", zeroContextDiff, []int{1, 2}, []string{"@@ -5,2 +4,0 @@", "@@ -7,0 +6,2 @@ l7"}},
+		{"all zero context", zeroContextDiff, []int{0, 1, 2}, []string{"@@ -3 +3,2 @@", "@@ -5,2 +5,0 @@", "@@ -7,0 +7,2 @@ l7"}},
+	}
+	for _, tt := range tests {
+		f := parseOne(t, tt.diff)
+		var hunks []*Hunk
+		for _, i := range tt.hunks {
+			hunks = append(hunks, f.Hunks[i])
+		}
+		patch := f.Patch(hunks)
+		if !strings.HasPrefix(patch, strings.Join(f.Header, "\n")+"\n") {
+			t.Errorf("%s: the header was not kept:\n%s", tt.name, patch)
+		}
+		if got := hunkHeaders(patch); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: hunk headers %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		name, diff       string
+		oldPath, newPath string
+		hunks            int
+		splittable       bool
+	}{
+		{"modified", contextDiff, "lines.txt", "lines.txt", 3, true},
+		{"one hunk", noNewlineDiff, "n.txt", "n.txt", 1, false},
+		{"added", addDeleteDiff[:strings.Index(addDeleteDiff, "diff --git a/old")], "", "new.txt", 1, false},
+		{"deleted", addDeleteDiff[strings.Index(addDeleteDiff, "diff --git a/old"):], "old.txt", "", 1, false},
+		{
+			"spaces", "diff --git a/my dir/a b.txt b/my dir/a b.txt\nindex 1..2 100644\n--- a/my dir/a b.txt\t\n+++ b/my dir/a b.txt\t\n@@ -1 +1 @@\n-x\n+y\n",
+			"my dir/a b.txt", "my dir/a b.txt", 1, false,
+		},
+		{
+			"quoted", "diff --git \"a/caf\\303\\251 \\\"menu\\\".txt\" \"b/caf\\303\\251 \\\"menu\\\".txt\"\nindex 1..2 100644\n--- \"a/caf\\303\\251 \\\"menu\\\".txt\"\n+++ \"b/caf\\303\\251 \\\"menu\\\".txt\"\n@@ -1 +1 @@\n-x\n+y\n",
+			"café \"menu\".txt", "café \"menu\".txt", 1, false,
+		},
+		{
+			"quoted without ---/+++", "diff --git \"a/tab\\there\" \"b/tab\\there\"\nold mode 100644\nnew mode 100755\n",
+			"tab\there", "tab\there", 0, false,
+		},
+		{
+			"renamed", "diff --git a/old name.txt b/new name.txt\nsimilarity index 100%\nrename from old name.txt\nrename to new name.txt\n",
+			"old name.txt", "new name.txt", 0, false,
+		},
+		{
+			"binary", "diff --git a/img.png b/img.png\nindex 1..2 100644\nBinary files a/img.png and b/img.png differ\n",
+			"img.png", "img.png", 0, false,
+		},
+	}
+	for _, tt := range tests {
+		f := parseOne(t, tt.diff)
+		if f.OldPath != tt.oldPath || f.NewPath != tt.newPath || len(f.Hunks) != tt.hunks || f.Splittable() != tt.splittable {
+			t.Errorf("%s: %q -> %q with %d hunks, splittable %v; want %q -> %q with %d, %v",
+				tt.name, f.OldPath, f.NewPath, len(f.Hunks), f.Splittable(), tt.oldPath, tt.newPath, tt.hunks, tt.splittable)
+		}
+		if f.String() != tt.diff {
+			t.Errorf("%s: round trip changed the diff:\n%s", tt.name, f.String())
+		}
+	}
+}
+
+func TestParseNoNewline(t *testing.T) {
+	h := parseOne(t, noNewlineDiff).Hunks[0]
+	want := []string{" a", "-b", `\ No newline at end of file`, "+c", `\ No newline at end of file`}
+	if strings.Join(h.Lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", h.Lines, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, text := range []string{
+		"not a diff\n",
+		"diff --git a/x b/x\n@@ -1,2 +1,2 @@\n a\n", // Ends early
+		"diff --git a/x b/x\n@@ -1 +1 @@\n*x\n",     // Not a hunk line
+		"diff --git a/x b/x\n@@ -a +1 @@\n-x\n+y\n", // Malformed header
+	} {
+		if files, err := Parse(text); err == nil {
+			t.Errorf("parsed %q into %d files", text, len(files))
+		}
+	}
+}
+
+func TestParseHeaderLikeBody(t *testing.T) {
+	// Removed lines that look like headers are read by the hunk's line counts
+	f := parseOne(t, "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1,2 +1 @@\n--- a/y\n-@@ -1 +1 @@\n+z\n")
+	if len(f.Hunks) != 1 || len(f.Hunks[0].Lines) != 3 {
+		t.Errorf("hunks = %+v", f.Hunks)
+	}
+}
+
+func TestChangesAndBuildPatch(t *testing.T) {
+	files, err := Parse(contextDiff + addDeleteDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := Changes(files)
+	var ids []string
+	for _, c := range changes {
+		ids = append(ids, c.ID+":"+c.File.Path())
+	}
+	if got := strings.Join(ids, " "); got != "C1:lines.txt C2:lines.txt C3:lines.txt C4:new.txt C5:old.txt" {
+		t.Errorf("changes = %s", got)
+	}
+
+	// Hunks of one file stay together and in order, files in first-use order
+	patch := BuildPatch([]Change{changes[3], changes[2], changes[0]})
+	want := changes[3].String() + files[0].Patch([]*Hunk{files[0].Hunks[0], files[0].Hunks[2]})
+	if patch != want {
+		t.Errorf("BuildPatch =\n%s\nwant\n%s", patch, want)
+	}
+}
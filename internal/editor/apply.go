@@ -153,22 +153,12 @@ func cleanLLMResponse(response string) string {
 	// Trim leading/trailing whitespace
 	cleaned := strings.TrimSpace(response)
 
-	// Remove markdown code fences if present
-	codeBlockPatterns := []string{
-		"```typescript",
-		"```tsx",
-		"```js",
-		"```javascript",
-		"```ts",
-		"```",
-	}
-
-	// Remove opening code fence
-	for _, pattern := range codeBlockPatterns {
-		if strings.HasPrefix(cleaned, pattern) {
-			cleaned = strings.TrimPrefix(cleaned, pattern)
-			cleaned = strings.TrimSpace(cleaned)
-			break
+	// Remove the opening code fence along with its language tag, if present
+	if strings.HasPrefix(cleaned, "```") {
+		if newline := strings.Index(cleaned, "\n"); newline >= 0 {
+			cleaned = strings.TrimSpace(cleaned[newline+1:])
+		} else {
+			cleaned = strings.TrimSpace(strings.TrimPrefix(cleaned, "```"))
 		}
 	}
 
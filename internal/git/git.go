@@ -40,16 +40,18 @@ func GetStagedDiff() (string, error) {
 	return diff, nil
 }
 
-// GetStagedFiles returns a list of relative paths of staged files.
+// GetStagedFiles returns the paths of the staged files relative to the
+// repository root, like the paths in the staged diff, wherever it is run from.
 func GetStagedFiles() ([]string, error) {
-	output, err := runGitCommand("diff", "--staged", "--name-only", "--relative")
+	// -z leaves unusual names unquoted
+	output, err := runGitCommand("diff", "--staged", "--name-only", "-z")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get staged files: %w", err)
 	}
 	if output == "" {
 		return []string{}, nil // No files staged
 	}
-	files := strings.Split(output, "\n")
+	files := strings.Split(output, "\x00")
 	// Filter out empty strings if any
 	result := []string{}
 	for _, f := range files {
@@ -185,3 +187,315 @@ func ReadFile(path string) ([]byte, error) {
 func WriteFile(path string, content []byte) error {
 	return os.WriteFile(path, content, 0644)
 }
+
+// GetRecentCommitMessages returns the full messages of the last n non-merge commits, newest first.
+func GetRecentCommitMessages(n int) ([]string, error) {
+	// Separate commits with a record separator since messages span multiple lines
+	output, err := runGitCommand("log", "--no-merges", "-n", fmt.Sprintf("%d", n), "--pretty=format:%B%x1e")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit messages: %w", err)
+	}
+	var messages []string
+	for _, msg := range strings.Split(output, "\x1e") {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// GetHeadCommit returns the hash of HEAD, or an error if there are no commits yet.
+func GetHeadCommit() (string, error) {
+	head, err := runGitCommand("rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return head, nil
+}
+
+// GetGitDir returns the absolute path of the .git directory.
+func GetGitDir() (string, error) {
+	dir, err := runGitCommand("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	return dir, nil
+}
+
+// runGitCommandInput executes a git command with input on stdin and returns its
+// untrimmed stdout, which matters for patches.
+func runGitCommandInput(input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git command failed: 'git %s': %v\nStderr: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// GetStagedPatch returns the staged changes as a patch that git apply accepts,
+// independent of the user's diff settings. Renames show as a delete and an add.
+func GetStagedPatch() (string, error) {
+	patch, err := runGitCommandInput("", "diff", "--staged", "--binary", "--no-color", "--no-ext-diff",
+		"--no-renames", "--src-prefix=a/", "--dst-prefix=b/")
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged patch: %w", err)
+	}
+	if strings.TrimSpace(patch) == "" {
+		return "", fmt.Errorf("no changes staged for commit")
+	}
+	return patch, nil
+}
+
+// GetPatchBetween returns the diff from one commit to another in the format
+// diff.Parse expects, independent of the user's diff settings.
+func GetPatchBetween(from, to string) (string, error) {
+	patch, err := runGitCommandInput("", "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", from, to)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff: %w", err)
+	}
+	return patch, nil
+}
+
+// ApplyToIndex applies patch to the index only, leaving the working tree alone.
+// Paths in the patch are relative to the repository root.
+func ApplyToIndex(patch string) error {
+	root, err := GetRepoRoot()
+	if err != nil {
+		return err
+	}
+	// git apply ignores paths outside the current directory, so run it from the root
+	if _, err := runGitCommandInput(patch, "-C", root, "apply", "--cached", "--whitespace=nowarn", "-"); err != nil {
+		return fmt.Errorf("failed to apply patch to the index: %w", err)
+	}
+	return nil
+}
+
+// WriteTree saves the index as a tree object and returns its hash.
+func WriteTree() (string, error) {
+	tree, err := runGitCommand("write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to save the index: %w", err)
+	}
+	return tree, nil
+}
+
+// ReadTree replaces the index with treeish without touching the working tree.
+// An empty treeish empties the index.
+func ReadTree(treeish string) error {
+	args := []string{"read-tree", treeish}
+	if treeish == "" {
+		args = []string{"read-tree", "--empty"}
+	}
+	if _, err := runGitCommand(args...); err != nil {
+		return fmt.Errorf("failed to reset the index: %w", err)
+	}
+	// read-tree drops cached file stats; refresh them so git status stays fast.
+	// This exits non-zero when files differ from the index, which is expected.
+	runGitCommand("update-index", "-q", "--refresh")
+	return nil
+}
+
+// ResetHead moves the current branch to commit, leaving the index and working
+// tree alone. An empty commit makes the branch unborn again, as it is in a
+// repository without commits.
+func ResetHead(commit string) error {
+	args := []string{"reset", "--soft", "-q", commit}
+	if commit == "" {
+		args = []string{"update-ref", "-d", "HEAD"}
+	}
+	if _, err := runGitCommand(args...); err != nil {
+		return fmt.Errorf("failed to reset HEAD: %w", err)
+	}
+	return nil
+}
+
+// GetHooksDir returns the absolute path of the directory git runs hooks from,
+// honouring core.hooksPath.
+func GetHooksDir() (string, error) {
+	dir, err := runGitCommand("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("failed to find hooks directory: %w", err)
+	}
+	return filepath.Abs(dir)
+}
+
+// GetConfigValue returns the value of a git config key, or "" if it is not set.
+func GetConfigValue(key string) string {
+	value, err := runGitCommand("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// GetDefaultBranch guesses the branch pull requests usually target: the
+// remote's default branch if known, otherwise main or master.
+func GetDefaultBranch() string {
+	if ref, err := runGitCommand("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return ref
+	}
+	for _, branch := range []string{"main", "master"} {
+		if _, err := runGitCommand("rev-parse", "--verify", "--quiet", branch); err == nil {
+			return branch
+		}
+	}
+	return "main"
+}
+
+// GetCurrentBranch returns the name of the checked-out branch.
+func GetCurrentBranch() (string, error) {
+	branch, err := runGitCommand("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to get current branch: %w", err)
+	}
+	return branch, nil
+}
+
+// GetMergeBase returns the best common ancestor of two commits.
+func GetMergeBase(a, b string) (string, error) {
+	base, err := runGitCommand("merge-base", a, b)
+	if err != nil {
+		return "", fmt.Errorf("failed to find merge base of %s and %s: %w", a, b, err)
+	}
+	return base, nil
+}
+
+// GetCommitMessagesBetween returns the full messages of the non-merge commits
+// reachable from to but not from, oldest first.
+func GetCommitMessagesBetween(from, to string) ([]string, error) {
+	output, err := runGitCommand("log", "--no-merges", "--reverse", "--pretty=format:%B%x1e", from+".."+to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	var messages []string
+	for _, msg := range strings.Split(output, "\x1e") {
+		if msg = strings.TrimSpace(msg); msg != "" {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// GetDiffBetween returns the diff from one commit to another, and a
+// --stat summary of it.
+func GetDiffBetween(from, to string) (string, string, error) {
+	diff, err := runGitCommand("diff", "--no-color", "--no-ext-diff", from, to)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get diff: %w", err)
+	}
+	stat, err := runGitCommand("diff", "--no-color", "--stat", from, to)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get diff stat: %w", err)
+	}
+	return diff, stat, nil
+}
+
+// LogEntry is one commit from GetLog.
+type LogEntry struct {
+	Hash    string // Abbreviated hash
+	Subject string
+	Body    string
+}
+
+// GetLog returns the non-merge commits reachable from to but not from, oldest
+// first. An empty from means all history up to to.
+func GetLog(from, to string) ([]LogEntry, error) {
+	rangeSpec := to
+	if from != "" {
+		rangeSpec = from + ".." + to
+	}
+	// Fields are separated by a unit separator and commits by a record separator
+	output, err := runGitCommand("log", "--no-merges", "--reverse", "--pretty=format:%h%x1f%s%x1f%b%x1e", rangeSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits in %s: %w", rangeSpec, err)
+	}
+	var entries []LogEntry
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		entry := LogEntry{Hash: fields[0], Subject: fields[1]}
+		if len(fields) == 3 {
+			entry.Body = strings.TrimSpace(fields[2])
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetLatestTag returns the most recent tag reachable from rev, or "" if there is none.
+func GetLatestTag(rev string) string {
+	tag, err := runGitCommand("describe", "--tags", "--abbrev=0", rev)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// DiffContents returns a patch that changes the file at path (relative to the
+// repository root) from before to after, in the format git apply accepts.
+// Neither version has to exist on disk; they are compared in a temporary
+// directory.
+func DiffContents(path, before, after string) (string, error) {
+	dir, err := os.MkdirTemp("", "llmify-diff-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	path = filepath.ToSlash(path)
+	for side, content := range map[string]string{"a": before, "b": after} {
+		file := filepath.Join(dir, side, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	// The a/ and b/ directories double as the usual prefixes
+	cmd := exec.Command("git", "diff", "--no-index", "--no-color", "--no-ext-diff", "--no-prefix", "--", "a/"+path, "b/"+path)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// Exit status 1 means the files differ
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return "", fmt.Errorf("git command failed: 'git %s': %v\nStderr: %s", strings.Join(cmd.Args[1:], " "), err, stderr.String())
+		}
+	}
+	return stdout.String(), nil
+}
+
+// ListFiles returns the paths, relative to root, of the files in the working
+// tree of the repository at root: tracked files and untracked files that are
+// not ignored. With ignored set it returns the ignored files instead, with
+// ignored directories listed once with a trailing slash.
+func ListFiles(root string, ignored bool) ([]string, error) {
+	args := []string{"-C", root, "ls-files", "-z", "--exclude-standard"}
+	if ignored {
+		args = append(args, "--others", "--ignored", "--directory")
+	} else {
+		args = append(args, "--cached", "--others")
+	}
+	output, err := runGitCommandInput("", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range strings.Split(output, "\x00") {
+		// Files with unmerged changes are listed once per stage
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/gittest"
+)
+
+func TestGetStagedFilesMatchesDiff(t *testing.T) {
+	gittest.Repo(t)
+	gittest.WriteFile(t, "go.sum", "old\n")
+	gittest.WriteFile(t, "sub/a.go", "package sub\n")
+	gittest.Commit(t, "Initial commit")
+	gittest.WriteFile(t, "go.sum", "new\n")
+	gittest.WriteFile(t, "sub/a.go", "package sub // changed\n")
+	gittest.WriteFile(t, "sub/ünïcode name.go", "package sub\n")
+	gittest.Run(t, "add", "-A")
+
+	// From a subdirectory, files outside it are listed too, and every path
+	// is relative to the repository root like the paths in the diff
+	if err := os.Chdir("sub"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"go.sum", "sub/a.go", "sub/ünïcode name.go"}
+	files, err := GetStagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GetStagedFiles() = %q, want %q", files, want)
+	}
+
+	text, err := GetStagedDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, s := range diff.Sections(text) {
+		paths = append(paths, s.Path)
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("diff paths = %q, want %q", paths, want)
+	}
+}
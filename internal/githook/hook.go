@@ -0,0 +1,137 @@
+// Package githook installs the prepare-commit-msg hook that lets plain
+// "git commit" (including commits made from an IDE) use llmify messages.
+package githook
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jake/llmify/internal/git"
+)
+
+// Name is the git hook llmify installs.
+const Name = "prepare-commit-msg"
+
+// chainedSuffix is appended to a hook that was already installed, which the
+// llmify hook then runs first.
+const chainedSuffix = ".pre-llmify"
+
+// marker identifies hooks written by llmify so they are never confused with
+// someone else's.
+const marker = "# Installed by llmify"
+
+// Status describes the hook in the current repository.
+type Status struct {
+	Dir       string // Hooks directory git uses
+	HooksPath string // core.hooksPath, if set
+	Path      string // Path of the prepare-commit-msg hook
+	Installed bool   // The hook is llmify's
+	Foreign   bool   // Another prepare-commit-msg hook is in the way
+	Chained   string // Path of the previous hook that llmify's hook runs first, if any
+}
+
+// Inspect reports the hook's current state.
+func Inspect() (*Status, error) {
+	dir, err := git.GetHooksDir()
+	if err != nil {
+		return nil, err
+	}
+	s := &Status{
+		Dir:       dir,
+		HooksPath: git.GetConfigValue("core.hooksPath"),
+		Path:      filepath.Join(dir, Name),
+	}
+	if content, err := os.ReadFile(s.Path); err == nil {
+		s.Installed = strings.Contains(string(content), marker)
+		s.Foreign = !s.Installed
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", s.Path, err)
+	}
+	if _, err := os.Stat(s.Path + chainedSuffix); err == nil {
+		s.Chained = s.Path + chainedSuffix
+	}
+	return s, nil
+}
+
+// Install writes the hook, which runs exe (the llmify binary) in hook mode.
+// An existing prepare-commit-msg hook is kept and run before llmify.
+func Install(exe string) (*Status, error) {
+	s, err := Inspect()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if s.Foreign {
+		if s.Chained != "" {
+			return nil, fmt.Errorf("cannot keep the existing %s hook: %s already exists", Name, s.Chained)
+		}
+		if err := os.Rename(s.Path, s.Path+chainedSuffix); err != nil {
+			return nil, fmt.Errorf("failed to move the existing hook aside: %w", err)
+		}
+		s.Chained = s.Path + chainedSuffix
+		s.Foreign = false
+	}
+	if err := os.WriteFile(s.Path, []byte(Script(exe)), 0755); err != nil {
+		return nil, fmt.Errorf("failed to write hook: %w", err)
+	}
+	s.Installed = true
+	return s, nil
+}
+
+// Uninstall removes llmify's hook and puts back the hook it replaced.
+func Uninstall() (*Status, error) {
+	s, err := Inspect()
+	if err != nil {
+		return nil, err
+	}
+	if s.Foreign {
+		return nil, fmt.Errorf("%s was not installed by llmify; leaving it alone", s.Path)
+	}
+	if !s.Installed {
+		return s, nil
+	}
+	if err := os.Remove(s.Path); err != nil {
+		return nil, fmt.Errorf("failed to remove hook: %w", err)
+	}
+	s.Installed = false
+	if s.Chained != "" {
+		if err := os.Rename(s.Chained, s.Path); err != nil {
+			return nil, fmt.Errorf("failed to restore the previous hook from %s: %w", s.Chained, err)
+		}
+		s.Chained = ""
+		s.Foreign = true
+	}
+	return s, nil
+}
+
+// Script returns the hook script. It runs any previous hook first, then llmify
+// in hook mode; llmify failures never block the commit. exe is tried first so
+// commits from IDEs with a minimal PATH work, falling back to llmify on PATH.
+func Script(exe string) string {
+	return `#!/bin/sh
+` + marker + ` ("llmify hook install"); remove it with "llmify hook uninstall".
+# Set LLMIFY_SKIP_HOOK=1 to commit without generating a message.
+
+previous="$0` + chainedSuffix + `"
+if [ -x "$previous" ]; then
+	"$previous" "$@" || exit $?
+fi
+
+[ -n "$LLMIFY_SKIP_HOOK" ] && exit 0
+
+llmify=` + shellQuote(filepath.ToSlash(exe)) + `
+if [ ! -x "$llmify" ]; then
+	llmify=$(command -v llmify) || exit 0
+fi
+"$llmify" commit --hook "$1" "$2" "$3" </dev/null || true
+`
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,198 @@
+package githook
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/jake/llmify/internal/gittest"
+)
+
+const previousHook = "#!/bin/sh\necho previous >> \"$1\"\n"
+
+// readFile returns the content of path, or "" if it does not exist.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestInstallChainsExistingHook(t *testing.T) {
+	root := gittest.Repo(t)
+	hook := filepath.Join(root, ".git", "hooks", Name)
+	chained := hook + chainedSuffix
+	gittest.WriteFile(t, hook, previousHook)
+
+	s, err := Inspect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Path != hook || s.Installed || !s.Foreign || s.Chained != "" {
+		t.Fatalf("before installing: %+v", s)
+	}
+
+	s, err = Install("/opt/llmify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Installed || s.Foreign || s.Chained != chained {
+		t.Errorf("after installing: %+v", s)
+	}
+	if got := readFile(t, hook); got != Script("/opt/llmify") {
+		t.Errorf("hook = %q", got)
+	}
+	if got := readFile(t, chained); got != previousHook {
+		t.Errorf("the previous hook was changed to %q", got)
+	}
+	if info, err := os.Stat(hook); runtime.GOOS != "windows" && (err != nil || info.Mode().Perm()&0111 == 0) {
+		t.Errorf("the hook is not executable: %v", err)
+	}
+
+	// Installing again rewrites llmify's hook and keeps the chained one
+	if s, err = Install("/usr/local/bin/llmify"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Chained != chained || readFile(t, chained) != previousHook {
+		t.Errorf("installing twice lost the previous hook: %+v", s)
+	}
+	if got := readFile(t, hook); got != Script("/usr/local/bin/llmify") {
+		t.Errorf("hook = %q after installing again", got)
+	}
+
+	// Uninstalling puts the previous hook back
+	if s, err = Uninstall(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Installed || !s.Foreign || s.Chained != "" {
+		t.Errorf("after uninstalling: %+v", s)
+	}
+	if got := readFile(t, hook); got != previousHook {
+		t.Errorf("hook = %q after uninstalling, want the previous one", got)
+	}
+	if _, err := os.Stat(chained); !os.IsNotExist(err) {
+		t.Errorf("%s is left behind", chained)
+	}
+	if _, err := Uninstall(); err == nil || !strings.Contains(err.Error(), "not installed by llmify") {
+		t.Errorf("uninstalling someone else's hook: err = %v", err)
+	}
+	if got := readFile(t, hook); got != previousHook {
+		t.Errorf("a failed uninstall changed the hook to %q", got)
+	}
+}
+
+func TestInstallWithoutExistingHook(t *testing.T) {
+	root := gittest.Repo(t)
+	hook := filepath.Join(root, ".git", "hooks", Name)
+	os.Remove(hook) // In case git's templates provide one
+
+	s, err := Install("/opt/llmify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Installed || s.Chained != "" {
+		t.Errorf("after installing: %+v", s)
+	}
+	if s, err = Uninstall(); err != nil || s.Installed || s.Foreign {
+		t.Errorf("after uninstalling: %+v, %v", s, err)
+	}
+	if _, err := os.Stat(hook); !os.IsNotExist(err) {
+		t.Error("uninstalling left the hook behind")
+	}
+	if _, err := Uninstall(); err != nil {
+		t.Errorf("uninstalling again: %v", err)
+	}
+}
+
+func TestInstallRefusesToOverwriteChainedHook(t *testing.T) {
+	root := gittest.Repo(t)
+	hook := filepath.Join(root, ".git", "hooks", Name)
+	gittest.WriteFile(t, hook, previousHook)
+	gittest.WriteFile(t, hook+chainedSuffix, "#!/bin/sh\n# older\n")
+
+	if _, err := Install("/opt/llmify"); err == nil {
+		t.Fatal("installed over two foreign hooks")
+	}
+	if readFile(t, hook) != previousHook || readFile(t, hook+chainedSuffix) != "#!/bin/sh\n# older\n" {
+		t.Error("a failed install changed the hooks")
+	}
+}
+
+func TestHooksDirectory(t *testing.T) {
+	root := gittest.Repo(t)
+	gittest.WriteFile(t, "sub/file.txt", "x\n")
+	gittest.Commit(t, "Initial commit")
+
+	// A relative core.hooksPath is relative to the top of the working tree,
+	// wherever llmify runs
+	gittest.Run(t, "config", "core.hooksPath", "shared-hooks")
+	if err := os.Chdir("sub"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Install("/opt/llmify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(root, "shared-hooks")
+	if s.Dir != want || s.HooksPath != "shared-hooks" || s.Path != filepath.Join(want, Name) {
+		t.Errorf("status = %+v, want the hook in %s", s, want)
+	}
+	if readFile(t, filepath.Join(want, Name)) == "" {
+		t.Error("the hook was not written to core.hooksPath")
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	gittest.Run(t, "config", "--unset", "core.hooksPath")
+
+	// Linked worktrees share the main repository's hooks
+	worktree := filepath.Join(t.TempDir(), "wt")
+	gittest.Run(t, "worktree", "add", "-q", worktree)
+	if err := os.Chdir(worktree); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Inspect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root, ".git", "hooks"); s.Dir != want {
+		t.Errorf("worktree hooks directory = %s, want %s", s.Dir, want)
+	}
+}
+
+func TestHookRunsChainedHookAndLlmify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hook is a shell script")
+	}
+	root := gittest.Repo(t)
+	gittest.WriteFile(t, filepath.Join(".git", "hooks", Name), previousHook)
+	if err := os.Chmod(filepath.Join(root, ".git", "hooks", Name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Stands in for llmify, which is called as "llmify commit --hook <file> ..."
+	exe := filepath.Join(t.TempDir(), "llmify")
+	if err := os.WriteFile(exe, []byte("#!/bin/sh\n[ \"$1 $2\" = \"commit --hook\" ] && echo llmify >> \"$3\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Install(exe); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LLMIFY_SKIP_HOOK", "")
+	gittest.Commit(t, "First")
+	if got := gittest.Run(t, "log", "-1", "--format=%B"); got != "First\nprevious\nllmify" {
+		t.Errorf("message = %q, want the previous hook's and llmify's lines", got)
+	}
+	t.Setenv("LLMIFY_SKIP_HOOK", "1")
+	gittest.Commit(t, "Second")
+	if got := gittest.Run(t, "log", "-1", "--format=%B"); got != "Second\nprevious" {
+		t.Errorf("message = %q with LLMIFY_SKIP_HOOK set", got)
+	}
+}
@@ -0,0 +1,75 @@
+// Package gittest creates throwaway git repositories for tests of code that
+// runs git in the current directory.
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Repo creates an empty repository, makes it the working directory until the
+// test ends and returns its path. Git runs with a fixed identity and without
+// the user's configuration. The test is skipped if git is not installed.
+func Repo(t testing.TB) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir()) // git reports resolved paths
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	for key, value := range map[string]string{
+		"GIT_CONFIG_GLOBAL":   os.DevNull,
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_AUTHOR_NAME":     "Test",
+		"GIT_AUTHOR_EMAIL":    "test@example.com",
+		"GIT_COMMITTER_NAME":  "Test",
+		"GIT_COMMITTER_EMAIL": "test@example.com",
+	} {
+		t.Setenv(key, value)
+	}
+	Run(t, "init", "-q")
+	return dir
+}
+
+// Run runs git in the working directory and returns its trimmed output,
+// failing the test if git fails.
+func Run(t testing.TB, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// WriteFile writes content to path, relative to the working directory,
+// creating parent directories as needed.
+func WriteFile(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Commit stages everything and commits it with message.
+func Commit(t testing.TB, message string) {
+	t.Helper()
+	Run(t, "add", "-A")
+	Run(t, "commit", "-q", "--allow-empty", "-m", message)
+}
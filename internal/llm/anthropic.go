@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultAnthropicBaseURL is the public Anthropic API endpoint.
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+)
+
+// AnthropicClient talks to the Anthropic Messages API.
+type AnthropicClient struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewAnthropicClient creates a client for the Messages API. An empty baseURL
+// uses the public endpoint; tests can point it at an httptest server.
+func NewAnthropicClient(apiKey string, baseURL string) *AnthropicClient {
+	if baseURL == "" {
+		baseURL = DefaultAnthropicBaseURL
+	}
+	return &AnthropicClient{
+		apiKey:  apiKey,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 180 * time.Second, // 3 minute timeout for HTTP requests
+		},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   float32            `json:"temperature"`
+	TopP          float32            `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+type anthropicErrorResponse struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// anthropicStreamEvent is the payload of a server-sent event in a streamed response.
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// messageRequest converts a Request into the request shared by Generate and GenerateStream.
+// Anthropic has no JSON mode, so JSON responses are requested through the system prompt.
+func (c *AnthropicClient) messageRequest(req Request) anthropicRequest {
+	model := req.Model
+	// Use a fallback model if the model is not specified
+	if model == "" {
+		model = config.DefaultModel("anthropic")
+		if viper.GetBool("verbose") {
+			log.Printf("No model specified, using default model: %s", model)
+		}
+	}
+
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+	}
+
+	return anthropicRequest{
+		Model:         model,
+		System:        req.systemWithFormat(),
+		Messages:      messages,
+		MaxTokens:     req.maxTokens(),
+		Temperature:   req.temperature(),
+		StopSequences: req.Stop,
+	}
+}
+
+func (c *AnthropicClient) Generate(ctx context.Context, request Request) (*Response, error) {
+	req := c.messageRequest(request)
+
+	var result *Response
+	err := retryWithBackoff(ctx, "Anthropic", 3, func() error {
+		resp, err := c.createMessage(ctx, req)
+		if err != nil {
+			return err
+		}
+		var text strings.Builder
+		for _, block := range resp.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+		if text.Len() == 0 {
+			return fmt.Errorf("Anthropic returned no text content (stop reason: %s)", resp.StopReason)
+		}
+		result = &Response{
+			Text: text.String(),
+			Usage: Usage{
+				PromptTokens:     resp.Usage.InputTokens,
+				CompletionTokens: resp.Usage.OutputTokens,
+			},
+			FinishReason: resp.StopReason,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *AnthropicClient) GenerateStream(ctx context.Context, request Request) (<-chan Chunk, error) {
+	req := c.messageRequest(request)
+	req.Stream = true
+
+	var httpResp *http.Response
+	err := retryWithBackoff(ctx, "Anthropic", 3, func() error {
+		var err error
+		httpResp, err = c.post(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Chunk)
+	go func() {
+		defer close(ch)
+		defer httpResp.Body.Close()
+
+		var usage Usage
+		var stopReason string
+		scanner := bufio.NewScanner(httpResp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue // Ignore "event:" lines and keep-alives; the payload repeats the type
+			}
+			var event anthropicStreamEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+				endStream(ctx, ch, fmt.Errorf("decoding Anthropic stream: %w", err))
+				return
+			}
+			switch event.Type {
+			case "message_start":
+				usage.PromptTokens = event.Message.Usage.InputTokens
+			case "message_delta":
+				usage.CompletionTokens = event.Usage.OutputTokens
+				stopReason = event.Delta.StopReason
+			case "content_block_delta":
+				if event.Delta.Type == "text_delta" && event.Delta.Text != "" {
+					if !sendChunk(ctx, ch, Chunk{Text: event.Delta.Text}) {
+						endStream(ctx, ch, nil)
+						return
+					}
+				}
+			case "error":
+				endStream(ctx, ch, &APIError{
+					Provider:   "Anthropic",
+					StatusCode: httpResp.StatusCode,
+					Type:       event.Error.Type,
+					Message:    event.Error.Message,
+				})
+				return
+			case "message_stop":
+				if !sendChunk(ctx, ch, Chunk{Usage: &usage, FinishReason: stopReason}) {
+					endStream(ctx, ch, nil)
+				}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			endStream(ctx, ch, fmt.Errorf("reading Anthropic stream: %w", err))
+			return
+		}
+		endStream(ctx, ch, fmt.Errorf("Anthropic stream ended before completion: %w", io.ErrUnexpectedEOF))
+	}()
+	return ch, nil
+}
+
+// post sends a request to the /v1/messages endpoint and returns the response
+// for the caller to read. Non-200 responses are converted into an *APIError.
+func (c *AnthropicClient) post(ctx context.Context, req anthropicRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding Anthropic request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating Anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", c.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		respBody, _ := io.ReadAll(httpResp.Body)
+		return nil, anthropicError(httpResp, respBody)
+	}
+	return httpResp, nil
+}
+
+// createMessage sends a single non-streaming request.
+func (c *AnthropicClient) createMessage(ctx context.Context, req anthropicRequest) (*anthropicResponse, error) {
+	httpResp, err := c.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	var resp anthropicResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding Anthropic response: %w", err)
+	}
+	return &resp, nil
+}
+
+// anthropicError converts a non-200 response into an *APIError.
+func anthropicError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{
+		Provider:   "Anthropic",
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header),
+	}
+	var errResp anthropicErrorResponse
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+		apiErr.Type = errResp.Error.Type
+		apiErr.Message = errResp.Error.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
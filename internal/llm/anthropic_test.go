@@ -0,0 +1,260 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+)
+
+// anthropicServer serves /v1/messages with handler, after checking the
+// headers every request must carry. It records the decoded request bodies.
+func anthropicServer(t *testing.T, handler func(w http.ResponseWriter, body anthropicRequest)) (*AnthropicClient, *[]anthropicRequest) {
+	t.Helper()
+	var requests []anthropicRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/messages" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		for header, want := range map[string]string{
+			"x-api-key":         "test-key",
+			"anthropic-version": anthropicAPIVersion,
+			"Content-Type":      "application/json",
+		} {
+			if got := r.Header.Get(header); got != want {
+				t.Errorf("header %s = %q, want %q", header, got, want)
+			}
+		}
+		var body anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		requests = append(requests, body)
+		handler(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return NewAnthropicClient("test-key", srv.URL+"/"), &requests
+}
+
+func TestAnthropicGenerate(t *testing.T) {
+	client, requests := anthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"Hello"},{"type":"tool_use"},{"type":"text","text":" there"}],
+			"stop_reason":"end_turn","usage":{"input_tokens":11,"output_tokens":3}}`)
+	})
+	resp, err := client.Generate(context.Background(), Request{
+		Model:          "claude-test",
+		System:         "Be brief.",
+		Messages:       []Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "yes?"}, {Role: "user", Content: "again"}},
+		Temperature:    0.5,
+		MaxTokens:      100,
+		Stop:           []string{"END"},
+		ResponseFormat: ResponseFormatJSON,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Response{Text: "Hello there", Usage: Usage{PromptTokens: 11, CompletionTokens: 3}, FinishReason: "end_turn"}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+
+	got := (*requests)[0]
+	wantReq := anthropicRequest{
+		Model:  "claude-test",
+		System: "Be brief.\n\n" + jsonInstruction, // No native JSON mode
+		Messages: []anthropicMessage{
+			{Role: "user", Content: "hi"}, {Role: "assistant", Content: "yes?"}, {Role: "user", Content: "again"},
+		},
+		MaxTokens:     100,
+		Temperature:   0.5,
+		StopSequences: []string{"END"},
+	}
+	if !reflect.DeepEqual(got, wantReq) {
+		t.Errorf("request = %+v, want %+v", got, wantReq)
+	}
+}
+
+func TestAnthropicDefaults(t *testing.T) {
+	client, requests := anthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		fmt.Fprint(w, `{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`)
+	})
+	if _, err := client.Generate(context.Background(), Request{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	got := (*requests)[0]
+	if got.Model != config.DefaultModel("anthropic") {
+		t.Errorf("model = %q, want the configured default %q", got.Model, config.DefaultModel("anthropic"))
+	}
+	if got.MaxTokens != defaultMaxTokens || got.Temperature != defaultTemperature || got.System != "" || got.Stream {
+		t.Errorf("request = %+v, want defaults", got)
+	}
+}
+
+func TestAnthropicGenerateStream(t *testing.T) {
+	client, requests := anthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, strings.Join([]string{
+			`event: message_start`,
+			`data: {"type":"message_start","message":{"usage":{"input_tokens":25}}}`,
+			``,
+			`event: ping`,
+			`data: {"type":"ping"}`,
+			``,
+			`event: content_block_delta`,
+			`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}`,
+			``,
+			`data:{"type":"content_block_delta","delta":{"type":"text_delta","text":"lo"}}`,
+			``,
+			`data: {"type":"content_block_delta","delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+			``,
+			`event: message_delta`,
+			`data: {"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":2}}`,
+			``,
+			`event: message_stop`,
+			`data: {"type":"message_stop"}`,
+			``,
+		}, "\n"))
+	})
+	ch, err := client.GenerateStream(context.Background(), testRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed strings.Builder
+	resp, err := CollectStream(context.Background(), ch, &streamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Response{Text: "Hello", Usage: Usage{PromptTokens: 25, CompletionTokens: 2}, FinishReason: "max_tokens"}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+	if streamed.String() != "Hello" {
+		t.Errorf("streamed %q", streamed.String())
+	}
+	if !(*requests)[0].Stream {
+		t.Error("the request did not ask for a stream")
+	}
+}
+
+func TestAnthropicStreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		check  func(error) bool
+	}{
+		{
+			name:   "error event",
+			events: "data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"x\"}}\n\nevent: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+			check:  func(err error) bool { return IsOverloaded(err) && Classify(err) == ErrorRetryable },
+		},
+		{
+			name:   "ended early",
+			events: "data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"x\"}}\n\n",
+			check:  func(err error) bool { return err != nil && strings.Contains(err.Error(), "ended before completion") },
+		},
+		{
+			name:   "malformed event",
+			events: "data: {not json\n\n",
+			check:  func(err error) bool { return err != nil && strings.Contains(err.Error(), "decoding Anthropic stream") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := anthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+				fmt.Fprint(w, tt.events)
+			})
+			ch, err := client.GenerateStream(context.Background(), testRequest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := CollectStream(context.Background(), ch, nil); !tt.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestAnthropicErrorMapping(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		header  http.Header
+		want    APIError
+		class   ErrorClass
+		limited bool
+	}{
+		{
+			status: 400,
+			body:   `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: too large"}}`,
+			want:   APIError{Provider: "Anthropic", StatusCode: 400, Type: "invalid_request_error", Message: "max_tokens: too large"},
+			class:  ErrorFatal,
+		},
+		{
+			status: 401,
+			body:   `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			want:   APIError{Provider: "Anthropic", StatusCode: 401, Type: "authentication_error", Message: "invalid x-api-key"},
+			class:  ErrorProvider,
+		},
+		{
+			status: 404,
+			body:   "no such route\n",
+			want:   APIError{Provider: "Anthropic", StatusCode: 404, Message: "no such route"},
+			class:  ErrorProvider,
+		},
+		{
+			status:  429,
+			body:    `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`,
+			header:  http.Header{"Retry-After": {"7"}},
+			want:    APIError{Provider: "Anthropic", StatusCode: 429, Type: "rate_limit_error", Message: "slow down", RetryAfter: 7 * time.Second},
+			class:   ErrorRetryable,
+			limited: true,
+		},
+		{
+			status: 529,
+			want:   APIError{Provider: "Anthropic", StatusCode: 529, Message: http.StatusText(529)},
+			class:  ErrorRetryable,
+		},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: tt.header}
+		if resp.Header == nil {
+			resp.Header = http.Header{}
+		}
+		err := anthropicError(resp, []byte(tt.body))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || *apiErr != tt.want {
+			t.Errorf("status %d: error = %#v, want %#v", tt.status, err, tt.want)
+			continue
+		}
+		if class := Classify(err); class != tt.class {
+			t.Errorf("status %d: class = %s, want %s", tt.status, class, tt.class)
+		}
+		if IsRateLimited(err) != tt.limited {
+			t.Errorf("status %d: rate limited = %v", tt.status, !tt.limited)
+		}
+	}
+}
+
+func TestAnthropicFatalErrorIsNotRetried(t *testing.T) {
+	client, requests := anthropicServer(t, func(w http.ResponseWriter, body anthropicRequest) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`)
+	})
+	_, err := client.Generate(context.Background(), testRequest)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want the 400 APIError", err)
+	}
+	if len(*requests) != 1 {
+		t.Errorf("sent %d requests, want 1", len(*requests))
+	}
+}
@@ -3,30 +3,114 @@ package llm
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/jake/llmify/internal/config" // Use the correct module path
+	"github.com/spf13/viper"
 )
 
 // LLMClient defines the interface for interacting with different LLM providers.
 type LLMClient interface {
-	Generate(ctx context.Context, prompt string, model string) (string, error)
+	Generate(ctx context.Context, req Request) (*Response, error)
+	// GenerateStream starts a completion and returns its fragments as they arrive.
+	// The channel is closed when the completion ends or ctx is cancelled.
+	GenerateStream(ctx context.Context, req Request) (<-chan Chunk, error)
 }
 
-// NewLLMClient creates a new LLM client based on the configuration.
+// NewLLMClient creates a new LLM client based on the configuration, wrapped
+// for recording or replay when llm.record_mode is set.
 func NewLLMClient(cfg *config.Config) (LLMClient, error) {
-	apiKey := config.GetAPIKey(cfg.LLM.Provider)
+	switch cfg.LLM.RecordMode {
+	case "":
+		return newRoutedClient(cfg)
+	case ModeReplay:
+		// Replays never reach the provider, so no API key is needed
+		return NewReplayClient(nil, cfg.LLM.RecordDir, ModeReplay)
+	default:
+		client, err := newRoutedClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayClient(client, cfg.LLM.RecordDir, cfg.LLM.RecordMode)
+	}
+}
+
+// newRoutedClient creates the client for the configured provider, wrapped in a
+// RouterClient when llm.fallbacks are configured. Fallbacks that cannot be
+// created (e.g. a missing API key) are skipped.
+func newRoutedClient(cfg *config.Config) (LLMClient, error) {
+	primary, err := newProviderClient(cfg, cfg.LLM.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.LLM.Fallbacks) == 0 {
+		return primary, nil
+	}
+
+	routes := []Route{{Provider: cfg.LLM.Provider, Client: primary}}
+	clients := map[string]LLMClient{cfg.LLM.Provider: primary}
+	for _, fb := range cfg.LLM.Fallbacks {
+		provider := fb.Provider
+		if provider == "" {
+			provider = cfg.LLM.Provider
+		}
+		model := fb.Model
+		if model == "" {
+			model = config.DefaultModel(provider)
+		}
+		client, ok := clients[provider]
+		if !ok {
+			client, err = newProviderClient(cfg, provider)
+			if err != nil {
+				if viper.GetBool("verbose") {
+					log.Printf("Skipping fallback %s/%s: %v", provider, model, err)
+				}
+				continue
+			}
+			clients[provider] = client
+		}
+		routes = append(routes, Route{Provider: provider, Model: model, Client: client})
+	}
+	return NewRouterClient(routes)
+}
+
+// newProviderClient creates the client for a provider.
+func newProviderClient(cfg *config.Config, provider string) (LLMClient, error) {
+	apiKey := config.GetAPIKey(provider)
 
-	switch cfg.LLM.Provider {
+	switch provider {
 	case "openai":
 		if apiKey == "" {
 			return nil, fmt.Errorf("OpenAI API key not found (set OPENAI_API_KEY or LLMIFY_LLM_API_KEY_OPENAI)")
 		}
 		return NewOpenAIClient(apiKey), nil
-	// case "anthropic":
-	//     // ... implementation ...
-	// case "ollama":
-	// 	   return NewOllamaClient(cfg.LLM.OllamaBaseURL)
+	case "openai-compatible", "azure":
+		// Local servers such as vLLM or LM Studio usually need no key
+		opts := OpenAICompatibleOptions{
+			BaseURL:          cfg.LLM.BaseURL,
+			APIType:          cfg.LLM.APIType,
+			APIVersion:       cfg.LLM.APIVersion,
+			Organization:     cfg.LLM.Organization,
+			AzureDeployments: cfg.LLM.AzureDeployments,
+			Headers:          cfg.LLM.Headers,
+		}
+		if provider == "azure" {
+			opts.APIType = "azure"
+			if apiKey == "" {
+				return nil, fmt.Errorf("Azure OpenAI API key not found (set AZURE_OPENAI_API_KEY or LLMIFY_LLM_API_KEY_AZURE)")
+			}
+		}
+		return NewOpenAICompatibleClient(apiKey, opts)
+	case "anthropic":
+		if apiKey == "" {
+			return nil, fmt.Errorf("Anthropic API key not found (set ANTHROPIC_API_KEY or LLMIFY_LLM_API_KEY_ANTHROPIC)")
+		}
+		return NewAnthropicClient(apiKey, cfg.LLM.AnthropicBaseURL), nil
+	case "ollama":
+		return NewOllamaClient(cfg.LLM.OllamaBaseURL, cfg.LLM.OllamaStream), nil
+	case "fake":
+		return LoadFakeClient(cfg.LLM.FakeFixtures)
 	default:
-		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.LLM.Provider)
+		return nil, fmt.Errorf("unsupported LLM provider: %s", provider)
 	}
 }
@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jake/llmify/internal/config"
+)
+
+// apiCall is a request received by providerServer.
+type apiCall struct {
+	Path   string
+	Query  string
+	Header http.Header
+	Model  string
+}
+
+// providerServer fakes the chat endpoints of the OpenAI (and Azure),
+// Anthropic and Ollama APIs, answering each with the name of the API that
+// was called.
+func providerServer(t *testing.T) (*httptest.Server, func() []apiCall) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		calls []apiCall
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			fmt.Fprint(w, `{"models":[{"name":"llama3.1:latest"},{"name":"llama3.1:8b"}]}`)
+			return
+		}
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request to %s: %v", r.URL.Path, err)
+		}
+		mu.Lock()
+		calls = append(calls, apiCall{Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header.Clone(), Model: body.Model})
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/v1/messages":
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"anthropic"}],"stop_reason":"end_turn"}`)
+		case "/api/chat":
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"ollama"},"done":true,"done_reason":"stop"}`)
+		default:
+			fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"openai"},"finish_reason":"stop"}]}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []apiCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]apiCall(nil), calls...)
+	}
+}
+
+// generateOnce creates the client for cfg and sends one request for
+// cfg.LLM.Model, returning the answer and the single call it made.
+func generateOnce(t *testing.T, cfg *config.Config, calls func() []apiCall) (string, apiCall) {
+	t.Helper()
+	before := len(calls())
+	client, err := NewLLMClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Generate(context.Background(), Request{
+		Model:    cfg.LLM.Model,
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	made := calls()[before:]
+	if len(made) != 1 {
+		t.Fatalf("made %d calls, want 1: %+v", len(made), made)
+	}
+	return resp.Text, made[0]
+}
+
+func TestNewLLMClientUsesConfiguredProvider(t *testing.T) {
+	srv, calls := providerServer(t)
+	t.Setenv("LLMIFY_OPENAI_COMPATIBLE_API_KEY", "compat-key")
+	t.Setenv("AZURE_OPENAI_API_KEY", "azure-key")
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+
+	tests := []struct {
+		name    string
+		llm     config.LLMConfig
+		answer  string
+		path    string
+		query   string
+		model   string
+		headers map[string]string
+	}{
+		{
+			name: "openai-compatible",
+			llm: config.LLMConfig{
+				Provider:     "openai-compatible",
+				Model:        "served-model",
+				BaseURL:      srv.URL + "/v1/",
+				Organization: "acme",
+				Headers:      map[string]string{"X-Gateway-Team": "tools"},
+			},
+			answer: "openai",
+			path:   "/v1/chat/completions",
+			model:  "served-model",
+			headers: map[string]string{
+				"Authorization":       "Bearer compat-key",
+				"OpenAI-Organization": "acme",
+				"X-Gateway-Team":      "tools",
+			},
+		},
+		{
+			name: "azure",
+			llm: config.LLMConfig{
+				Provider:         "azure",
+				Model:            "gpt-4o",
+				BaseURL:          srv.URL,
+				APIVersion:       "2024-06-01",
+				AzureDeployments: map[string]string{"gpt-4o": "prod-4o"},
+			},
+			answer:  "openai",
+			path:    "/openai/deployments/prod-4o/chat/completions",
+			query:   "api-version=2024-06-01",
+			model:   "gpt-4o",
+			headers: map[string]string{"api-key": "azure-key"},
+		},
+		{
+			name:    "anthropic",
+			llm:     config.LLMConfig{Provider: "anthropic", Model: "claude-test", AnthropicBaseURL: srv.URL},
+			answer:  "anthropic",
+			path:    "/v1/messages",
+			model:   "claude-test",
+			headers: map[string]string{"x-api-key": "anthropic-key"},
+		},
+		{
+			name:   "ollama",
+			llm:    config.LLMConfig{Provider: "ollama", Model: "llama3.1:8b", OllamaBaseURL: srv.URL},
+			answer: "ollama",
+			path:   "/api/chat",
+			model:  "llama3.1:8b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, call := generateOnce(t, &config.Config{LLM: tt.llm}, calls)
+			if answer != tt.answer || call.Path != tt.path || call.Query != tt.query || call.Model != tt.model {
+				t.Errorf("got %q from %s?%s for model %q; want %q from %s?%s for model %q",
+					answer, call.Path, call.Query, call.Model, tt.answer, tt.path, tt.query, tt.model)
+			}
+			for header, want := range tt.headers {
+				if got := call.Header.Get(header); got != want {
+					t.Errorf("header %s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLLMClientRequiresBaseURL(t *testing.T) {
+	_, err := NewLLMClient(&config.Config{LLM: config.LLMConfig{Provider: "openai-compatible"}})
+	if err == nil {
+		t.Error("created an openai-compatible client without llm.base_url")
+	}
+}
+
+func TestModelOverrideSelectsProvider(t *testing.T) {
+	srv, calls := providerServer(t)
+	t.Setenv("LLMIFY_OPENAI_COMPATIBLE_API_KEY", "compat-key")
+	t.Setenv("ANTHROPIC_API_KEY", "anthropic-key")
+
+	tests := []struct {
+		spec   string
+		answer string
+		model  string
+	}{
+		{"", "openai", "configured-model"},
+		{"other-model", "openai", "other-model"},
+		{"ollama:llama3.1:8b", "ollama", "llama3.1:8b"},
+		{"Ollama:", "ollama", config.DefaultModel("ollama")},
+		{"anthropic:claude-test", "anthropic", "claude-test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			cfg := &config.Config{LLM: config.LLMConfig{
+				Provider:         "openai-compatible",
+				Model:            "configured-model",
+				BaseURL:          srv.URL + "/v1",
+				AnthropicBaseURL: srv.URL,
+				OllamaBaseURL:    srv.URL,
+				Fallbacks:        []config.FallbackConfig{{Provider: "ollama"}},
+			}}
+			config.ApplyModelOverride(cfg, tt.spec)
+			if tt.spec != "" && cfg.LLM.Fallbacks != nil {
+				t.Error("an override kept the configured fallbacks")
+			}
+			answer, call := generateOnce(t, cfg, calls)
+			if answer != tt.answer || call.Model != tt.model {
+				t.Errorf("got %q for model %q, want %q for model %q", answer, call.Model, tt.answer, tt.model)
+			}
+		})
+	}
+}
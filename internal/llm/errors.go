@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+	"github.com/spf13/viper"
+)
+
+// APIError is returned by HTTP-based providers when the API responds with an error.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Type       string        // Provider-specific error type, e.g. "rate_limit_error"
+	Message    string        // Human-readable error message from the provider
+	RetryAfter time.Duration // Server-suggested wait before retrying, if any
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s API error (status %d, %s): %s", e.Provider, e.StatusCode, e.Type, e.Message)
+	}
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// RateLimited reports whether the provider rejected the request for exceeding a rate limit.
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error"
+}
+
+// Overloaded reports whether the provider is temporarily overloaded.
+func (e *APIError) Overloaded() bool {
+	return e.StatusCode == 529 || e.Type == "overloaded_error"
+}
+
+// Retryable reports whether the same request may succeed if sent again later.
+func (e *APIError) Retryable() bool {
+	return e.RateLimited() || e.Overloaded() || e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout
+}
+
+// IsRateLimited reports whether err is a rate-limit error from any provider.
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.RateLimited()
+}
+
+// IsOverloaded reports whether err indicates an overloaded provider.
+func IsOverloaded(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Overloaded()
+}
+
+// ErrorClass describes how a failed request should be handled.
+type ErrorClass int
+
+const (
+	// ErrorRetryable errors are transient (rate limits, overload, 5xx, network
+	// failures): the request may succeed if retried or sent elsewhere.
+	ErrorRetryable ErrorClass = iota
+	// ErrorProvider errors are specific to one provider or model (bad API key,
+	// unknown model): retrying won't help, but another provider may work.
+	ErrorProvider
+	// ErrorFatal errors will fail the same way anywhere (malformed request,
+	// cancellation), so no retry or fallback is attempted.
+	ErrorFatal
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorRetryable:
+		return "retryable"
+	case ErrorProvider:
+		return "provider"
+	default:
+		return "fatal"
+	}
+}
+
+// Classify decides whether a request error is retryable, provider-specific or fatal.
+// Errors without an HTTP status are retryable only when the transport failed
+// (network errors, timeouts, a connection reset or cut short); anything else,
+// such as an undecodable or empty response, is provider-specific.
+func Classify(err error) ErrorClass {
+	if errors.Is(err, context.Canceled) {
+		return ErrorFatal
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Retryable() {
+		return ErrorRetryable // Also covers rate_limit_error/overloaded_error on unusual statuses
+	}
+	status := statusCode(err)
+	switch {
+	case status == 0:
+		if isTransportError(err) {
+			return ErrorRetryable
+		}
+		return ErrorProvider
+	case status == http.StatusRequestTimeout, status == http.StatusConflict,
+		status == http.StatusTooManyRequests, status >= 500:
+		return ErrorRetryable
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity:
+		return ErrorFatal
+	default:
+		return ErrorProvider
+	}
+}
+
+// IsRetryable reports whether sending the same request again may succeed.
+func IsRetryable(err error) bool {
+	return Classify(err) == ErrorRetryable
+}
+
+// isTransportError reports whether err means the request or response was lost
+// in transit rather than rejected or garbled by the provider.
+func isTransportError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// statusCode extracts the HTTP status of a provider error, or 0 if there is none.
+func statusCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return openaiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode
+	}
+	return 0
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 0
+}
+
+// retryWithBackoff calls fn up to maxRetries times with exponential backoff,
+// honouring Retry-After hints and stopping early for non-retryable API errors.
+func retryWithBackoff(ctx context.Context, provider string, maxRetries int, fn func() error) error {
+	verbose := viper.GetBool("verbose")
+	var lastError error
+
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		// Add delay with exponential backoff for retries
+		if attempt > 0 {
+			backoffDuration := time.Duration(1<<uint(attempt)) * time.Second
+			var apiErr *APIError
+			if errors.As(lastError, &apiErr) && apiErr.RetryAfter > backoffDuration {
+				backoffDuration = apiErr.RetryAfter
+			}
+			if verbose {
+				log.Printf("Retrying %s request (attempt %d/%d) after %v delay",
+					provider, attempt+1, maxRetries, backoffDuration)
+			}
+
+			// Wait with context awareness
+			select {
+			case <-time.After(backoffDuration):
+				// Waited successfully
+			case <-ctx.Done():
+				return fmt.Errorf("context cancelled during retry backoff: %w", ctx.Err())
+			}
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		lastError = err
+		if ctx.Err() != nil {
+			// Don't retry if context was cancelled
+			if verbose {
+				log.Printf("Context cancelled or timed out, not retrying: %v", ctx.Err())
+			}
+			break
+		}
+
+		// Don't retry requests that will fail the same way again (bad request, auth, ...)
+		if !IsRetryable(err) {
+			return err
+		}
+
+		if verbose {
+			log.Printf("%s API error (attempt %d/%d): %v", provider, attempt+1, maxRetries, err)
+		}
+	}
+
+	return fmt.Errorf("%s request failed after %d attempts: %w", provider, maxRetries, lastError)
+}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestClassify(t *testing.T) {
+	var syntaxErr *json.SyntaxError
+	decodeErr := json.Unmarshal([]byte("{oops"), &struct{}{})
+	if !errors.As(decodeErr, &syntaxErr) {
+		t.Fatalf("expected a JSON syntax error, got %v", decodeErr)
+	}
+	dialErr := &url.Error{Op: "Post", URL: "http://localhost:11434/api/chat", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"cancelled", fmt.Errorf("request: %w", context.Canceled), ErrorFatal},
+		{"deadline", context.DeadlineExceeded, ErrorRetryable},
+		{"connection refused", dialErr, ErrorRetryable},
+		{"connection reset", fmt.Errorf("reading stream: %w", syscall.ECONNRESET), ErrorRetryable},
+		{"cut short", fmt.Errorf("Anthropic stream ended before completion: %w", io.ErrUnexpectedEOF), ErrorRetryable},
+		{"undecodable response", fmt.Errorf("decoding Anthropic response: %w", decodeErr), ErrorProvider},
+		{"empty content", errors.New("Anthropic returned no text content (stop reason: end_turn)"), ErrorProvider},
+		{"bad request", &APIError{StatusCode: 400}, ErrorFatal},
+		{"unprocessable", &openai.APIError{HTTPStatusCode: 422}, ErrorFatal},
+		{"unauthorized", &APIError{StatusCode: 401}, ErrorProvider},
+		{"not found", &openai.RequestError{HTTPStatusCode: 404}, ErrorProvider},
+		{"timeout", &APIError{StatusCode: 408}, ErrorRetryable},
+		{"conflict", &openai.APIError{HTTPStatusCode: 409}, ErrorRetryable},
+		{"rate limited", &APIError{StatusCode: 429}, ErrorRetryable},
+		{"server error", &openai.RequestError{HTTPStatusCode: 502}, ErrorRetryable},
+		{"overloaded on 200", &APIError{StatusCode: 200, Type: "overloaded_error"}, ErrorRetryable},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("%s: Classify(%v) = %s, want %s", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
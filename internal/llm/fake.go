@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Fixture is one scripted response of the fake provider.
+type Fixture struct {
+	// Match is a regular expression tested against the system prompt and all
+	// messages. Fixtures without Match answer any request, in file order.
+	Match string `json:"match,omitempty"`
+	// Text is the completion returned for the request.
+	Text string `json:"text"`
+	// Status makes the request fail with an APIError of this HTTP status instead.
+	Status int `json:"status,omitempty"`
+	// Error is the message of the simulated APIError.
+	Error            string `json:"error,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// FakeClient returns scripted responses from a fixtures file. It never makes
+// network calls, so pipelines can run offline and deterministically.
+type FakeClient struct {
+	mu       sync.Mutex
+	matched  []Fixture
+	sequence []Fixture
+	next     int
+	Requests []Request // Every request received, in order
+}
+
+// NewFakeClient creates a fake client from fixtures.
+func NewFakeClient(fixtures []Fixture) (*FakeClient, error) {
+	c := &FakeClient{}
+	for i, f := range fixtures {
+		if f.Match == "" {
+			c.sequence = append(c.sequence, f)
+			continue
+		}
+		pattern, err := regexp.Compile(f.Match)
+		if err != nil {
+			return nil, fmt.Errorf("fixture %d: invalid match pattern: %w", i+1, err)
+		}
+		f.pattern = pattern
+		c.matched = append(c.matched, f)
+	}
+	return c, nil
+}
+
+// LoadFakeClient creates a fake client from a JSON fixtures file of the form
+// {"responses": [{"match": "...", "text": "..."}, ...]}.
+func LoadFakeClient(path string) (*FakeClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake provider fixtures: %w", err)
+	}
+	var file struct {
+		Responses []Fixture `json:"responses"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse fake provider fixtures %s: %w", path, err)
+	}
+	return NewFakeClient(file.Responses)
+}
+
+// respond picks the fixture for a request: the first matching pattern, otherwise
+// the next unconditional fixture. The last unconditional fixture repeats.
+func (c *FakeClient) respond(req Request) (*Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Requests = append(c.Requests, req)
+
+	text := requestText(req)
+	var fixture *Fixture
+	for i := range c.matched {
+		if c.matched[i].pattern.MatchString(text) {
+			fixture = &c.matched[i]
+			break
+		}
+	}
+	if fixture == nil {
+		if len(c.sequence) == 0 {
+			return nil, fmt.Errorf("fake provider: no fixture matches the request")
+		}
+		fixture = &c.sequence[c.next]
+		if c.next < len(c.sequence)-1 {
+			c.next++
+		}
+	}
+
+	if fixture.Status != 0 {
+		return nil, &APIError{Provider: "Fake", StatusCode: fixture.Status, Message: fixture.Error}
+	}
+	return &Response{
+		Text:         fixture.Text,
+		Usage:        Usage{PromptTokens: fixture.PromptTokens, CompletionTokens: fixture.CompletionTokens},
+		FinishReason: "stop",
+	}, nil
+}
+
+func (c *FakeClient) Generate(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.respond(req)
+}
+
+func (c *FakeClient) GenerateStream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	resp, err := c.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return StreamResponse(ctx, resp), nil
+}
+
+// requestText joins the system prompt and messages for matching.
+func requestText(req Request) string {
+	var b strings.Builder
+	b.WriteString(req.System)
+	for _, m := range req.Messages {
+		b.WriteString("\n")
+		b.WriteString(m.Content)
+	}
+	return b.String()
+}
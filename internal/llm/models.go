@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultContextWindow is assumed for models that are not listed in
+// contextWindows and have no llm.context_window configured.
+const DefaultContextWindow = 8192
+
+// contextWindows are the context window sizes, in tokens, of common models.
+// Like prices, models match by exact name first and then by longest prefix.
+var contextWindows = map[string]int{
+	"gpt-4o":            128000,
+	"gpt-4.1":           1047576,
+	"gpt-4-turbo":       128000,
+	"gpt-4":             8192,
+	"gpt-3.5-turbo":     16385,
+	"o1":                200000,
+	"o3":                200000,
+	"o4-mini":           200000,
+	"claude-":           200000,
+	"llama3.1":          8192, // Ollama's default num_ctx is smaller than the model's limit
+	"llama3.2":          8192,
+	"qwen2.5-coder":     8192,
+	"mistral":           8192,
+	"deepseek-coder-v2": 8192,
+}
+
+// ContextWindow returns the context window of model in tokens. A configured
+// value (llm.context_window) wins over the built-in table.
+func ContextWindow(model string, configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	model = strings.ToLower(model)
+	if window, ok := contextWindows[model]; ok {
+		return window
+	}
+	names := make([]string, 0, len(contextWindows))
+	for name := range contextWindows {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		if strings.HasPrefix(model, name) {
+			return contextWindows[name]
+		}
+	}
+	return DefaultContextWindow
+}
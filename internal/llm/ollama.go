@@ -0,0 +1,324 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultOllamaBaseURL is where a local `ollama serve` listens by default.
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	ollamaDefaultModel   = "llama3.1"
+)
+
+// OllamaClient talks to a local (or self-hosted) Ollama server, so prompts and
+// code never leave the machine.
+type OllamaClient struct {
+	baseURL    string
+	stream     bool
+	httpClient *http.Client
+
+	mu              sync.Mutex
+	availableModels map[string]bool // Models confirmed present via /api/tags
+}
+
+// NewOllamaClient creates a client for the Ollama chat API. When stream is true,
+// responses are read incrementally from the NDJSON stream.
+func NewOllamaClient(baseURL string, stream bool) *OllamaClient {
+	if baseURL == "" {
+		baseURL = DefaultOllamaBaseURL
+	}
+	return &OllamaClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		stream:  stream,
+		httpClient: &http.Client{
+			// Local models can be slow to load and generate; rely on ctx for cancellation
+			Timeout: 10 * time.Minute,
+		},
+		availableModels: make(map[string]bool),
+	}
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Format   string                 `json:"format,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+type ollamaTagsResponse struct {
+	Models []struct {
+		Name  string `json:"name"`
+		Model string `json:"model"`
+	} `json:"models"`
+}
+
+// chatRequest converts a Request into the request shared by Generate and GenerateStream.
+func (c *OllamaClient) chatRequest(req Request, model string) ollamaChatRequest {
+	var messages []ollamaMessage
+	if req.System != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, ollamaMessage{Role: m.Role, Content: m.Content})
+	}
+
+	options := map[string]interface{}{
+		"temperature": req.temperature(),
+		"num_predict": req.maxTokens(),
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+
+	chatReq := ollamaChatRequest{
+		Model:    model,
+		Messages: messages,
+		Stream:   c.stream,
+		Options:  options,
+	}
+	if req.ResponseFormat == ResponseFormatJSON {
+		chatReq.Format = "json"
+	}
+	return chatReq
+}
+
+// resolveModel applies the default model and checks that it has been pulled.
+func (c *OllamaClient) resolveModel(ctx context.Context, model string) (string, error) {
+	// Use a fallback model if the model is not specified
+	if model == "" {
+		model = ollamaDefaultModel
+		if viper.GetBool("verbose") {
+			log.Printf("No model specified, using default model: %s", model)
+		}
+	}
+	if err := c.EnsureModel(ctx, model); err != nil {
+		return "", err
+	}
+	return model, nil
+}
+
+func (c *OllamaClient) Generate(ctx context.Context, request Request) (*Response, error) {
+	model, err := c.resolveModel(ctx, request.Model)
+	if err != nil {
+		return nil, err
+	}
+	req := c.chatRequest(request, model)
+
+	var resp *Response
+	err = retryWithBackoff(ctx, "Ollama", 3, func() error {
+		var err error
+		resp, err = c.chat(ctx, req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateStream always streams from Ollama, regardless of the configured mode.
+func (c *OllamaClient) GenerateStream(ctx context.Context, request Request) (<-chan Chunk, error) {
+	model, err := c.resolveModel(ctx, request.Model)
+	if err != nil {
+		return nil, err
+	}
+	req := c.chatRequest(request, model)
+	req.Stream = true
+
+	ch := make(chan Chunk)
+	go func() {
+		defer close(ch)
+		resp, err := c.chat(ctx, req, func(text string) bool {
+			return sendChunk(ctx, ch, Chunk{Text: text})
+		})
+		if err != nil {
+			endStream(ctx, ch, err)
+			return
+		}
+		if !sendChunk(ctx, ch, Chunk{Usage: &resp.Usage, FinishReason: resp.FinishReason}) {
+			endStream(ctx, ch, nil)
+		}
+	}()
+	return ch, nil
+}
+
+// EnsureModel checks via /api/tags that the model has been pulled into the
+// local Ollama instance. Results are cached for the lifetime of the client.
+func (c *OllamaClient) EnsureModel(ctx context.Context, model string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.availableModels[model] {
+		return nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("creating Ollama request: %w", err)
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("cannot reach Ollama at %s (is `ollama serve` running?): %w", c.baseURL, err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return fmt.Errorf("reading Ollama model list: %w", err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return ollamaError(httpResp, body)
+	}
+
+	var tags ollamaTagsResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return fmt.Errorf("decoding Ollama model list: %w", err)
+	}
+
+	var names []string
+	for _, m := range tags.Models {
+		name := m.Name
+		if name == "" {
+			name = m.Model
+		}
+		names = append(names, name)
+		c.availableModels[name] = true
+		// "llama3" refers to "llama3:latest"
+		if base, tag, ok := strings.Cut(name, ":"); ok && tag == "latest" {
+			c.availableModels[base] = true
+		}
+	}
+
+	if !c.availableModels[model] {
+		available := "none"
+		if len(names) > 0 {
+			available = strings.Join(names, ", ")
+		}
+		return fmt.Errorf("model %q has not been pulled into Ollama at %s; run `ollama pull %s` (available: %s)",
+			model, c.baseURL, model, available)
+	}
+	return nil
+}
+
+// chat sends a request to /api/chat and returns the full response. In
+// streaming mode each fragment is also passed to onChunk (if set) as it arrives;
+// onChunk returns false to stop reading.
+func (c *OllamaClient) chat(ctx context.Context, req ollamaChatRequest, onChunk func(string) bool) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encoding Ollama request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating Ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(httpResp.Body)
+		return nil, ollamaError(httpResp, respBody)
+	}
+
+	if !req.Stream {
+		var resp ollamaChatResponse
+		if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+			return nil, fmt.Errorf("decoding Ollama response: %w", err)
+		}
+		if resp.Error != "" {
+			return nil, &APIError{Provider: "Ollama", StatusCode: httpResp.StatusCode, Message: resp.Error}
+		}
+		return resp.toResponse(resp.Message.Content), nil
+	}
+
+	// Streaming responses are newline-delimited JSON objects
+	var text strings.Builder
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("decoding Ollama stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, &APIError{Provider: "Ollama", StatusCode: httpResp.StatusCode, Message: chunk.Error}
+		}
+		text.WriteString(chunk.Message.Content)
+		if onChunk != nil && chunk.Message.Content != "" && !onChunk(chunk.Message.Content) {
+			return nil, ctx.Err()
+		}
+		if chunk.Done {
+			// The final chunk carries the token counts and done reason
+			return chunk.toResponse(text.String()), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading Ollama stream: %w", err)
+	}
+	return nil, fmt.Errorf("Ollama stream ended before completion: %w", io.ErrUnexpectedEOF)
+}
+
+// toResponse builds a Response from a final (done) chat response.
+func (r ollamaChatResponse) toResponse(text string) *Response {
+	return &Response{
+		Text: text,
+		Usage: Usage{
+			PromptTokens:     r.PromptEvalCount,
+			CompletionTokens: r.EvalCount,
+		},
+		FinishReason: r.DoneReason,
+	}
+}
+
+// ollamaError converts a non-200 response into an *APIError.
+func ollamaError(resp *http.Response, body []byte) error {
+	apiErr := &APIError{Provider: "Ollama", StatusCode: resp.StatusCode}
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
+		apiErr.Message = errResp.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
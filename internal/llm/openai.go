@@ -2,9 +2,12 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	openai "github.com/sashabaranov/go-openai"
@@ -12,7 +15,8 @@ import (
 )
 
 type OpenAIClient struct {
-	client *openai.Client
+	client   *openai.Client
+	provider string // Name used in logs and errors
 }
 
 func NewOpenAIClient(apiKey string) *OpenAIClient {
@@ -25,88 +29,196 @@ func NewOpenAIClient(apiKey string) *OpenAIClient {
 	config.HTTPClient = httpClient
 
 	return &OpenAIClient{
-		client: openai.NewClientWithConfig(config),
+		client:   openai.NewClientWithConfig(config),
+		provider: "OpenAI",
 	}
 }
 
-func (c *OpenAIClient) Generate(ctx context.Context, prompt string, model string) (string, error) {
-	verbose := viper.GetBool("verbose")
+// OpenAICompatibleOptions configures a client for any endpoint that speaks the
+// OpenAI chat completions API (vLLM, LM Studio, LiteLLM, Azure OpenAI, ...).
+type OpenAICompatibleOptions struct {
+	BaseURL          string            // e.g. http://localhost:8000/v1
+	APIType          string            // "openai" (default) or "azure"
+	APIVersion       string            // Required by Azure, e.g. 2024-06-01
+	Organization     string            // Sent as OpenAI-Organization
+	AzureDeployments map[string]string // Model name -> Azure deployment name
+	Headers          map[string]string // Extra HTTP headers sent with every request
+}
+
+// NewOpenAICompatibleClient creates an OpenAIClient for a custom endpoint.
+func NewOpenAICompatibleClient(apiKey string, opts OpenAICompatibleOptions) (*OpenAIClient, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("llm.base_url is required for OpenAI-compatible providers")
+	}
+
+	var config openai.ClientConfig
+	provider := "OpenAI-compatible"
+	switch strings.ToLower(opts.APIType) {
+	case "", "openai":
+		config = openai.DefaultConfig(apiKey)
+		config.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+	case "azure":
+		provider = "Azure OpenAI"
+		config = openai.DefaultAzureConfig(apiKey, opts.BaseURL)
+		if opts.APIVersion != "" {
+			config.APIVersion = opts.APIVersion
+		}
+		defaultMapper := config.AzureModelMapperFunc
+		config.AzureModelMapperFunc = func(model string) string {
+			// Viper lower-cases map keys, so match deployments case-insensitively
+			if deployment, ok := opts.AzureDeployments[strings.ToLower(model)]; ok {
+				return deployment
+			}
+			return defaultMapper(model)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported llm.api_type %q (use openai or azure)", opts.APIType)
+	}
+	config.OrgID = opts.Organization
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if len(opts.Headers) > 0 {
+		transport = &headerTransport{headers: opts.Headers, base: transport}
+	}
+	config.HTTPClient = &http.Client{
+		Timeout:   180 * time.Second, // 3 minute timeout for HTTP requests
+		Transport: transport,
+	}
 
+	return &OpenAIClient{
+		client:   openai.NewClientWithConfig(config),
+		provider: provider,
+	}, nil
+}
+
+// headerTransport adds fixed headers to every outgoing request.
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// chatRequest converts a Request into the chat completion request shared by Generate and GenerateStream.
+func (c *OpenAIClient) chatRequest(req Request) openai.ChatCompletionRequest {
+	model := req.Model
 	// Use a fallback model if the model is not specified
 	if model == "" {
 		model = "gpt-3.5-turbo" // Default model
-		if verbose {
+		if viper.GetBool("verbose") {
 			log.Printf("No model specified, using default model: %s", model)
 		}
 	}
 
-	// Create request with more conservative settings for stability
-	req := openai.ChatCompletionRequest{
-		Model: model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: "You are a helpful assistant specialized in refactoring code. Provide complete refactored code without explanations.",
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
-		Temperature:      0.2,  // Lower temperature for more deterministic output
-		MaxTokens:        4096, // Higher limit for larger code bases
+	var messages []openai.ChatCompletionMessage
+	if req.System != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: req.System,
+		})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, openai.ChatCompletionMessage{Role: m.Role, Content: m.Content})
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:            model,
+		Messages:         messages,
+		Temperature:      req.temperature(),
+		MaxTokens:        req.maxTokens(),
+		Stop:             req.Stop,
 		TopP:             0.95, // More focused sampling
 		FrequencyPenalty: 0,
 		PresencePenalty:  0,
 	}
+	if req.ResponseFormat == ResponseFormatJSON {
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return chatReq
+}
 
-	// Try with exponential backoff
-	maxRetries := 3
-	var lastError error
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		// Add delay with exponential backoff for retries
-		if attempt > 0 {
-			backoffDuration := time.Duration(1<<uint(attempt)) * time.Second
-			if verbose {
-				log.Printf("Retrying OpenAI request (attempt %d/%d) after %v delay",
-					attempt+1, maxRetries, backoffDuration)
-			}
-
-			// Wait with context awareness
-			select {
-			case <-time.After(backoffDuration):
-				// Waited successfully
-			case <-ctx.Done():
-				return "", fmt.Errorf("context cancelled during retry backoff: %w", ctx.Err())
-			}
-		}
+func (c *OpenAIClient) Generate(ctx context.Context, request Request) (*Response, error) {
+	req := c.chatRequest(request)
 
-		// Make the API call
+	var result *Response
+	err := retryWithBackoff(ctx, c.provider, 3, func() error {
 		resp, err := c.client.CreateChatCompletion(ctx, req)
-
-		// If successful, return the result
-		if err == nil {
-			if len(resp.Choices) == 0 {
-				return "", fmt.Errorf("OpenAI returned no choices")
-			}
-			return resp.Choices[0].Message.Content, nil
+		if err != nil {
+			return err
 		}
-
-		// Check if we should retry based on the type of error
-		lastError = err
-		if ctx.Err() != nil {
-			// Don't retry if context was cancelled
-			if verbose {
-				log.Printf("Context cancelled or timed out, not retrying: %v", ctx.Err())
-			}
-			break
+		if len(resp.Choices) == 0 {
+			return fmt.Errorf("%s returned no choices", c.provider)
 		}
-
-		if verbose {
-			log.Printf("OpenAI API error (attempt %d/%d): %v", attempt+1, maxRetries, err)
+		result = &Response{
+			Text: resp.Choices[0].Message.Content,
+			Usage: Usage{
+				PromptTokens:     resp.Usage.PromptTokens,
+				CompletionTokens: resp.Usage.CompletionTokens,
+			},
+			FinishReason: string(resp.Choices[0].FinishReason),
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *OpenAIClient) GenerateStream(ctx context.Context, request Request) (<-chan Chunk, error) {
+	req := c.chatRequest(request)
+	if c.provider == "OpenAI" {
+		// Ask for a final usage chunk; not every compatible server accepts this option
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+
+	var stream *openai.ChatCompletionStream
+	err := retryWithBackoff(ctx, c.provider, 3, func() error {
+		var err error
+		stream, err = c.client.CreateChatCompletionStream(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return "", fmt.Errorf("OpenAI chat completion failed after %d attempts: %w", maxRetries, lastError)
+	ch := make(chan Chunk)
+	go func() {
+		defer close(ch)
+		defer stream.Close()
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				endStream(ctx, ch, nil) // The stream may end early because ctx is done
+				return
+			}
+			if err != nil {
+				endStream(ctx, ch, fmt.Errorf("%s stream failed: %w", c.provider, err))
+				return
+			}
+			if resp.Usage != nil {
+				usage := Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens}
+				if !sendChunk(ctx, ch, Chunk{Usage: &usage}) {
+					endStream(ctx, ch, nil)
+					return
+				}
+			}
+			for _, choice := range resp.Choices {
+				if choice.Delta.Content == "" && choice.FinishReason == "" {
+					continue
+				}
+				if !sendChunk(ctx, ch, Chunk{Text: choice.Delta.Content, FinishReason: string(choice.FinishReason)}) {
+					endStream(ctx, ch, nil)
+					return
+				}
+			}
+		}
+	}()
+	return ch, nil
 }
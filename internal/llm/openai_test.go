@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// openAIServer serves the chat completions endpoint with one status per
+// request; requests past the end of statuses succeed. It returns the client
+// and the number of requests received.
+func openAIServer(t *testing.T, statuses ...int) (*OpenAIClient, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprintf(w, `{"error":{"message":"status %d","type":"test"}}`, statuses[n-1])
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"done"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":5,"completion_tokens":1}}`)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewOpenAICompatibleClient("test-key", OpenAICompatibleOptions{BaseURL: srv.URL + "/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &requests
+}
+
+func TestOpenAIRetriesServerErrors(t *testing.T) {
+	client, requests := openAIServer(t, http.StatusServiceUnavailable)
+	resp, err := client.Generate(context.Background(), testRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Response{Text: "done", Usage: Usage{PromptTokens: 5, CompletionTokens: 1}, FinishReason: "stop"}
+	if *resp != want {
+		t.Errorf("response = %+v, want %+v", *resp, want)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestOpenAIFatalErrorIsNotRetried(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized} {
+		client, requests := openAIServer(t, status, status, status)
+		_, err := client.Generate(context.Background(), testRequest)
+		var apiErr *openai.APIError
+		if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != status {
+			t.Errorf("status %d: err = %v, want the APIError", status, err)
+		}
+		if n := requests.Load(); n != 1 {
+			t.Errorf("status %d: sent %d requests, want 1", status, n)
+		}
+	}
+}
+
+func TestOpenAIBackoffStopsWithContext(t *testing.T) {
+	client, requests := openAIServer(t, 503, 503, 503)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Generate(ctx, testRequest)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("returned after %s, want it to stop waiting at the deadline", elapsed)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+}
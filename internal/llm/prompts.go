@@ -1,165 +1,155 @@
 package llm
 
 import (
-	"fmt"
 	"strings"
-)
-
-const commitPromptTemplate = `
-You are an expert programmer and Git user, tasked with writing a detailed and clear commit message. Be cheeky sometimes.
-Analyze the following code changes (provided as a git diff) and the context of the changed files.
-
-Follow the Conventional Commits specification (https://www.conventionalcommits.org/).
-The commit message should have:
-1. A type prefix (e.g., feat, fix, refactor, chore, docs, style, test, perf).
-2. A concise subject line summarizing the change (imperative mood, lowercase).
-3. A blank line.
-4. A detailed body explaining the 'what' and 'why' of the changes. Be specific. Mention key functions/files modified and the reasoning. If it fixes an issue, reference it.
-
-NEVER include triple backticks. Start your message with the <type>.
-
-Example: 
-feat: add new feature...
-
-Here is the git diff:
---- DIFF START ---
-%s
---- DIFF END ---
-
-Generate the commit message now:
-`
-
-// docsUpdatePromptTemplate is used for updating documentation based on code changes
-const docsUpdatePromptTemplate = `
-You are an expert technical writer specializing in clear and accurate documentation.
-Your task is to update the provided documentation based on code changes, ensuring it remains accurate and helpful.
-
-USER'S DOCUMENTATION UPDATE GOAL:
-%s
-
-CONTEXT (Code Changes):
---- CONTEXT START ---
-%s
---- CONTEXT END ---
-
-TARGET DOCUMENTATION:
---- TARGET START ---
-%s
---- TARGET END ---
-
-IMPORTANT INSTRUCTIONS:
-1. Only update the documentation if necessary based on the code changes.
-2. Focus on changes to:
-   - Function signatures
-   - Parameters
-   - Return types
-   - Added/removed features
-   - Usage examples
-   - Clarifications based on code changes
-3. Do not make unnecessary changes or add speculative information.
-4. Preserve existing formatting and style.
-5. If no updates are needed, respond with exactly: NO_UPDATE_NEEDED
-
-OUTPUT FORMAT:
-If changes are needed, provide them in one of these formats:
-
-1. For replacing existing content:
---- LLMIFY REPLACE START ---
-<<< ORIGINAL >>>
-[The exact lines to be replaced]
-<<< REPLACEMENT >>>
-[The new lines to replace the original block]
---- LLMIFY REPLACE END ---
-
-2. For inserting new content:
---- LLMIFY INSERT_AFTER START ---
-<<< CONTEXT_LINE >>>
-[The exact line content *immediately preceding* the desired insertion point]
-<<< INSERTION >>>
-[The new lines to be inserted]
---- LLMIFY INSERT_AFTER END ---
-
-3. For deleting content:
---- LLMIFY DELETE START ---
-<<< CONTENT >>>
-[The exact lines to be deleted]
---- LLMIFY DELETE END ---
-
-If the changes are too extensive or complex for the edit format, provide the complete updated content enclosed in triple backticks:
-` + "```" + `markdown
-[Complete updated content]
-` + "```" + `
-`
 
-// refactorPromptTemplate is used for refactoring code snippets
-const refactorPromptTemplate = `
-You are an expert developer specializing in safe and effective code refactoring.
-Your task is to refactor the provided code snippet based on the user's request, ensuring correctness and maintaining necessary imports.
+	"github.com/jake/llmify/internal/prompts"
+)
 
-USER'S REFACTORING GOAL:
-%s
+// render builds a request from a task's system and user prompt templates.
+func render(system, user string, data prompts.Data) (Request, error) {
+	set, err := prompts.Active()
+	if err != nil {
+		return Request{}, err
+	}
+	systemPrompt, err := set.Render(system, data)
+	if err != nil {
+		return Request{}, err
+	}
+	userPrompt, err := set.Render(user, data)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{
+		System:   systemPrompt,
+		Messages: []Message{{Role: RoleUser, Content: userPrompt}},
+	}, nil
+}
 
-CONTEXT (Imports, Type Definitions, Related Code - May be incomplete):
---- CONTEXT START ---
-%s
---- CONTEXT END ---
+// CreateCommitPrompt builds the request for a commit message from data.Diff
+// (and data.Files, if the template uses them). Model is left for the caller to set.
+func CreateCommitPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.CommitSystem, prompts.Commit, data)
+	req.Temperature = 0.4 // A little variety reads more naturally in commit messages
+	req.MaxTokens = 1024
+	return req, err
+}
 
-TARGET CODE SNIPPET (or Full File Content):
---- TARGET CODE START ---
-%s
---- TARGET CODE END ---
+// CreateCommitRepairPrompt continues a commit message conversation, asking the
+// LLM to fix the lint violations of the message it returned for req.
+func CreateCommitRepairPrompt(req Request, message string, violations []string) (Request, error) {
+	set, err := prompts.Active()
+	if err != nil {
+		return Request{}, err
+	}
+	followUp, err := set.Render(prompts.CommitRepair, prompts.Data{Violations: violations})
+	if err != nil {
+		return Request{}, err
+	}
+	repair := req
+	repair.Messages = append(append([]Message{}, req.Messages...),
+		Message{Role: RoleAssistant, Content: message},
+		Message{Role: RoleUser, Content: followUp},
+	)
+	repair.Temperature = 0.2 // Fix the message rather than write a new one
+	return repair, nil
+}
 
-IMPORTANT INSTRUCTIONS:
-1. Provide ONLY the complete refactored code with no additional text.
-2. Do NOT include markdown code blocks or triple backticks.
-3. Do NOT include any explanations or comments about your changes.
-4. If refactoring the entire file, include necessary import statements.
-5. The output should be valid code that can be directly saved to a file.
-6. Do NOT add any unnecessary imports or modules.
-7. Preserve existing imports and only add new ones if absolutely necessary.
-8. Preserve original indentation and formatting.
+// CreateCommitSplitPrompt builds the request that groups data.Changes into
+// several commits. The LLM replies with a JSON plan.
+func CreateCommitSplitPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.CommitSplitSystem, prompts.CommitSplit, data)
+	req.Temperature = 0.2
+	req.MaxTokens = 4096 // One message per commit
+	req.ResponseFormat = ResponseFormatJSON
+	return req, err
+}
 
-OUTPUT FORMAT:
-If the changes are targeted and specific, provide them in one of these formats:
+// CreatePRPrompt builds the request for a pull request title and description
+// from data.Commits and data.Diff. The reply is the title, a blank line and the body.
+func CreatePRPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.PRSystem, prompts.PR, data)
+	req.Temperature = 0.3
+	req.MaxTokens = 2048
+	return req, err
+}
 
-1. For replacing existing code:
---- LLMIFY REPLACE START ---
-<<< ORIGINAL >>>
-[The exact lines to be replaced]
-<<< REPLACEMENT >>>
-[The new lines to replace the original block]
---- LLMIFY REPLACE END ---
+// CreateChangelogPrompt builds the request for the changelog entries of the
+// commits in data.Commits. The LLM replies with JSON.
+func CreateChangelogPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.ChangelogSystem, prompts.Changelog, data)
+	req.Temperature = 0.2
+	req.MaxTokens = 4096
+	req.ResponseFormat = ResponseFormatJSON
+	return req, err
+}
 
-2. For inserting new code:
---- LLMIFY INSERT_AFTER START ---
-<<< CONTEXT_LINE >>>
-[The exact line content *immediately preceding* the desired insertion point]
-<<< INSERTION >>>
-[The new lines to be inserted]
---- LLMIFY INSERT_AFTER END ---
+// CreateChangelogMergePrompt builds the request that merges the entries in
+// data.Entries, written for separate batches of commits, into one list.
+func CreateChangelogMergePrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.ChangelogSystem, prompts.ChangelogMerge, data)
+	req.Temperature = 0.2
+	req.MaxTokens = 4096
+	req.ResponseFormat = ResponseFormatJSON
+	return req, err
+}
 
-3. For deleting code:
---- LLMIFY DELETE START ---
-<<< CONTENT >>>
-[The exact lines to be deleted]
---- LLMIFY DELETE END ---
+// CreateDiffSummaryPrompt builds the request that summarizes one part of a
+// diff too large for a single request (data.Diff, with data.Path naming its
+// files), or condenses the summaries in data.Summaries.
+func CreateDiffSummaryPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.DiffSummarySystem, prompts.DiffSummary, data)
+	req.Temperature = 0.2
+	req.MaxTokens = 1024
+	return req, err
+}
 
-If the changes are too extensive or complex for the edit format, provide the complete updated content enclosed in triple backticks:
-` + "```" + `language
-[Complete updated content]
-` + "```" + `
-`
+// CreateReviewPrompt builds the request for review findings on the changes
+// to data.Path, given as hunks annotated with post-image line numbers in
+// data.Diff. The LLM replies with JSON.
+func CreateReviewPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.ReviewSystem, prompts.Review, data)
+	req.Temperature = 0.1 // Findings should not change between runs
+	req.MaxTokens = 4096
+	req.ResponseFormat = ResponseFormatJSON
+	return req, err
+}
 
-func CreateCommitPrompt(diff string, context string) string {
-	return fmt.Sprintf(commitPromptTemplate, diff)
+// CreateDocsUpdatePrompt builds the request for updating the documentation in
+// data.Target.
+func CreateDocsUpdatePrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.DocsSystem, prompts.Docs, data)
+	req.Temperature = 0.2
+	req.MaxTokens = 8192 // Full-document rewrites can be long
+	return req, err
 }
 
-func CreateDocsUpdatePrompt(diff string, docContent string) string {
-	return fmt.Sprintf(docsUpdatePromptTemplate, diff, docContent)
+// CreateRefactorPrompt builds the request for refactoring the code in data.Target.
+func CreateRefactorPrompt(data prompts.Data) (Request, error) {
+	req, err := render(prompts.RefactorSystem, prompts.Refactor, data)
+	req.Temperature = 0.1 // Refactors should be as deterministic as possible
+	req.MaxTokens = 8192
+	return req, err
 }
 
-func CreateRefactorPrompt(userGoal, context, targetCode string) string {
-	return fmt.Sprintf(refactorPromptTemplate, userGoal, context, targetCode)
+// CreateRefactorRepairPrompt continues a refactoring conversation, asking the
+// LLM to fix its reply to req so that the verification commands pass.
+// failures holds each failed command with its output.
+func CreateRefactorRepairPrompt(req Request, reply string, failures []string, scope string) (Request, error) {
+	set, err := prompts.Active()
+	if err != nil {
+		return Request{}, err
+	}
+	followUp, err := set.Render(prompts.RefactorRepair, prompts.Data{Failures: failures, Scope: scope})
+	if err != nil {
+		return Request{}, err
+	}
+	repair := req
+	repair.Messages = append(append([]Message{}, req.Messages...),
+		Message{Role: RoleAssistant, Content: reply},
+		Message{Role: RoleUser, Content: followUp},
+	)
+	return repair, nil
 }
 
 // Helper function to check LLM response for docs update
@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Record/replay modes for ReplayClient.
+const (
+	ModeRecord = "record" // Call the wrapped client and save every response
+	ModeReplay = "replay" // Only answer from saved responses; never call a provider
+)
+
+// ReplayClient wraps an LLMClient and stores responses on disk keyed by a hash
+// of the request, so a recorded session can be replayed offline.
+type ReplayClient struct {
+	next LLMClient // May be nil in replay mode
+	dir  string
+	mode string
+}
+
+// recording is the on-disk format of one recorded exchange.
+type recording struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// NewReplayClient creates a record/replay wrapper storing recordings in dir.
+func NewReplayClient(next LLMClient, dir, mode string) (*ReplayClient, error) {
+	switch mode {
+	case ModeRecord:
+		if next == nil {
+			return nil, fmt.Errorf("record mode needs a provider to record from")
+		}
+	case ModeReplay:
+	default:
+		return nil, fmt.Errorf("unsupported llm.record_mode %q (use record or replay)", mode)
+	}
+	return &ReplayClient{next: next, dir: dir, mode: mode}, nil
+}
+
+// RequestHash returns the key under which a request is recorded.
+func RequestHash(req Request) string {
+	data, _ := json.Marshal(req) // Request only holds plain data, so this cannot fail
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *ReplayClient) path(req Request) string {
+	return filepath.Join(c.dir, RequestHash(req)+".json")
+}
+
+func (c *ReplayClient) load(req Request) (*Response, error) {
+	data, err := os.ReadFile(c.path(req))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recording for request %s in %s (record it with llm.record_mode: record)", RequestHash(req)[:12], c.dir)
+		}
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", c.path(req), err)
+	}
+	return &rec.Response, nil
+}
+
+func (c *ReplayClient) save(req Request, resp *Response) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+	data, err := json.MarshalIndent(recording{Request: req, Response: *resp}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := os.WriteFile(c.path(req), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return nil
+}
+
+func (c *ReplayClient) Generate(ctx context.Context, req Request) (*Response, error) {
+	if c.mode == ModeReplay {
+		return c.load(req)
+	}
+	resp, err := c.next.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.save(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ReplayClient) GenerateStream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	if c.mode == ModeReplay {
+		resp, err := c.load(req)
+		if err != nil {
+			return nil, err
+		}
+		return StreamResponse(ctx, resp), nil
+	}
+
+	in, err := c.next.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// Forward the stream, recording the response only if it completes:
+	// a cut-short response would be replayed as if it were whole
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+		resp, err := Relay(ctx, in, out)
+		if err != nil || resp.FinishReason == "" {
+			return
+		}
+		if err := c.save(req, resp); err != nil {
+			out <- Chunk{Err: err}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFixtures(t *testing.T, json string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixtures.json")
+	if err := os.WriteFile(path, []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	fake, err := LoadFakeClient(writeFixtures(t, `{"responses": [
+		{"match": "commit", "text": "feat: add replay\n\nBody line.", "prompt_tokens": 12, "completion_tokens": 5},
+		{"match": "summary", "text": "A summary."}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	recorder, err := NewReplayClient(fake, dir, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	commitReq := Request{Model: "m", System: "Write a commit message", Messages: []Message{{Role: "user", Content: "diff"}}}
+	summaryReq := Request{Model: "m", Messages: []Message{{Role: "user", Content: "Write a summary"}}}
+
+	recorded, err := recorder.Generate(ctx, commitReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := recorder.GenerateStream(ctx, summaryReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := CollectStream(ctx, stream, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 2 {
+		t.Fatalf("recorded %d files, want 2", len(files))
+	}
+
+	// Replay needs no provider and answers both ways from the files
+	replayer, err := NewReplayClient(nil, dir, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := replayer.Generate(ctx, commitReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *replayed != *recorded {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+	stream, err = replayer.GenerateStream(ctx, commitReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CollectStream(ctx, stream, nil); err != nil || *got != *recorded {
+		t.Errorf("replayed stream %+v (err %v), recorded %+v", got, err, recorded)
+	}
+	if got, err := replayer.Generate(ctx, summaryReq); err != nil || got.Text != streamed.Text || got.FinishReason != streamed.FinishReason {
+		t.Errorf("replayed %+v (err %v), streamed %+v", got, err, streamed)
+	}
+	if _, err := replayer.Generate(ctx, Request{Model: "m", Messages: []Message{{Role: "user", Content: "new"}}}); err == nil {
+		t.Error("replayed a request that was never recorded")
+	}
+}
+
+// chunkClient streams chunks from an unbuffered channel and closes done once
+// every chunk has been taken.
+type chunkClient struct {
+	chunks []Chunk
+	done   chan struct{}
+}
+
+func (c *chunkClient) Generate(ctx context.Context, req Request) (*Response, error) {
+	return nil, errors.New("not used")
+}
+
+func (c *chunkClient) GenerateStream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	ch := make(chan Chunk)
+	go func() {
+		defer close(c.done)
+		defer close(ch)
+		for _, chunk := range c.chunks {
+			ch <- chunk
+		}
+	}()
+	return ch, nil
+}
+
+func TestRecordOnlyCompleteStreams(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []Chunk
+		cancel bool
+	}{
+		{"cancelled", []Chunk{{Text: "part"}, {Err: context.Canceled}}, true},
+		{"failed", []Chunk{{Text: "part"}, {Err: errors.New("boom")}, {Text: "late"}}, false},
+		{"no finish reason", []Chunk{{Text: "part"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &chunkClient{chunks: tt.chunks, done: make(chan struct{})}
+			dir := t.TempDir()
+			recorder, err := NewReplayClient(upstream, dir, ModeRecord)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			stream, err := recorder.GenerateStream(ctx, testRequest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			CollectStream(ctx, stream, nil)
+
+			select {
+			case <-upstream.done:
+			case <-time.After(time.Second):
+				t.Fatal("the upstream stream was not drained")
+			}
+			if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+				t.Errorf("recorded an incomplete response: %v", files)
+			}
+		})
+	}
+}
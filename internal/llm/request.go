@@ -0,0 +1,112 @@
+package llm
+
+import "strings"
+
+// Message roles used in Request.Messages.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Response formats supported by Request.ResponseFormat.
+const (
+	ResponseFormatText = ""     // Free-form text (default)
+	ResponseFormatJSON = "json" // A single JSON object
+)
+
+// Defaults applied when a Request leaves Temperature or MaxTokens at zero.
+const (
+	defaultTemperature = 0.2  // Lower temperature for more deterministic output
+	defaultMaxTokens   = 4096 // Higher limit for larger code bases
+)
+
+// Message is a single turn in a conversation.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request describes a completion request independently of the provider.
+type Request struct {
+	Model          string
+	System         string    // System prompt; empty for none
+	Messages       []Message // Conversation turns, ending with a user message
+	Temperature    float32   // 0 uses defaultTemperature
+	MaxTokens      int       // 0 uses defaultMaxTokens
+	Stop           []string  // Optional stop sequences
+	ResponseFormat string    // ResponseFormatText or ResponseFormatJSON
+}
+
+// Usage reports the tokens consumed by a request.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// TotalTokens returns the sum of prompt and completion tokens.
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Response is the result of a completion request.
+type Response struct {
+	Text         string
+	Usage        Usage
+	FinishReason string // Provider-specific, e.g. "stop", "length", "end_turn"
+	Model        string // Model that answered; set by RouterClient
+	Provider     string // Provider that answered; set by RouterClient
+}
+
+// NewRequest returns a single-turn request for the given prompt.
+func NewRequest(model string, prompt string) Request {
+	return Request{
+		Model:    model,
+		Messages: []Message{{Role: RoleUser, Content: prompt}},
+	}
+}
+
+// WithModel returns a copy of the request using the given model.
+func (r Request) WithModel(model string) Request {
+	r.Model = model
+	return r
+}
+
+// temperature returns the request temperature or the default.
+func (r Request) temperature() float32 {
+	if r.Temperature == 0 {
+		return defaultTemperature
+	}
+	return r.Temperature
+}
+
+// maxTokens returns the request token limit or the default.
+func (r Request) maxTokens() int {
+	if r.MaxTokens == 0 {
+		return defaultMaxTokens
+	}
+	return r.MaxTokens
+}
+
+// jsonInstruction is appended to the system prompt for providers without a native JSON mode.
+const jsonInstruction = "Respond with a single valid JSON object and nothing else."
+
+// systemWithFormat returns the system prompt, adding a JSON instruction if needed.
+func (r Request) systemWithFormat() string {
+	if r.ResponseFormat != ResponseFormatJSON {
+		return r.System
+	}
+	if r.System == "" {
+		return jsonInstruction
+	}
+	return r.System + "\n\n" + jsonInstruction
+}
+
+// ExtractJSON returns the outermost JSON object in a reply to a JSON request,
+// dropping code fences or prose that providers without a native JSON mode add.
+func ExtractJSON(text string) string {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return text
+	}
+	return text[start : end+1]
+}
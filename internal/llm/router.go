@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/spf13/viper"
+)
+
+// Route is one provider and model a RouterClient can send requests to.
+type Route struct {
+	Provider string
+	Model    string // Empty keeps the model set on the request
+	Client   LLMClient
+}
+
+func (r Route) String() string {
+	if r.Model == "" {
+		return r.Provider
+	}
+	return r.Provider + "/" + r.Model
+}
+
+// RouterClient tries its routes in order until one succeeds. Retryable and
+// provider-specific errors move on to the next route; fatal errors stop.
+// Fallbacks are only logged in verbose mode, and the provider and model that
+// answered are reported in Response.Provider and Response.Model.
+type RouterClient struct {
+	routes []Route
+}
+
+// NewRouterClient creates a router over routes; the first route is the primary.
+func NewRouterClient(routes []Route) (*RouterClient, error) {
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no LLM routes configured")
+	}
+	return &RouterClient{routes: routes}, nil
+}
+
+// try runs fn for each route until one succeeds or an error must not fall back.
+func (c *RouterClient) try(ctx context.Context, req Request, fn func(Route, Request) error) error {
+	var errs []error
+	for i, route := range c.routes {
+		routed := req
+		if route.Model != "" {
+			routed.Model = route.Model
+		}
+		err := fn(route, routed)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", route, err))
+
+		class := Classify(err)
+		if class == ErrorFatal || ctx.Err() != nil {
+			break
+		}
+		if i+1 < len(c.routes) && viper.GetBool("verbose") {
+			log.Printf("%s failed (%s error), falling back to %s: %v", route, class, c.routes[i+1], err)
+		}
+	}
+	if len(errs) == 1 {
+		return errors.Unwrap(errs[0])
+	}
+	return fmt.Errorf("all LLM routes failed: %w", errors.Join(errs...))
+}
+
+func (c *RouterClient) Generate(ctx context.Context, req Request) (*Response, error) {
+	var resp *Response
+	err := c.try(ctx, req, func(route Route, routed Request) error {
+		r, err := route.Client.Generate(ctx, routed)
+		if err != nil {
+			return err
+		}
+		resp = r
+		resp.Model, resp.Provider = routed.Model, route.Provider
+		return nil
+	})
+	return resp, err
+}
+
+// GenerateStream falls back only while opening the stream; once text has
+// started arriving, a failure is reported to the caller as usual.
+func (c *RouterClient) GenerateStream(ctx context.Context, req Request) (<-chan Chunk, error) {
+	var in <-chan Chunk
+	var model, provider string
+	err := c.try(ctx, req, func(route Route, routed Request) error {
+		ch, err := route.Client.GenerateStream(ctx, routed)
+		if err != nil {
+			return err
+		}
+		in, model, provider = ch, routed.Model, route.Provider
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Chunk)
+	go func() {
+		defer close(out)
+		out <- Chunk{Model: model, Provider: provider}
+		Relay(ctx, in, out)
+	}()
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Chunk is a fragment of a streamed completion. The final chunk of a failed
+// stream carries Err, including a stream cut short because its context was
+// cancelled; the channel is closed when the stream ends either way, and
+// consumers read it until then. Providers that report token usage send it in
+// a chunk near the end of the stream.
+type Chunk struct {
+	Text         string
+	Err          error
+	Usage        *Usage // Set once, when the provider reports usage
+	FinishReason string // Set once, when the provider reports why generation stopped
+	Model        string // Set once, by RouterClient, to the model that answered
+	Provider     string // Set with Model, to the provider that answered
+}
+
+// collector assembles a response from the chunks of a stream.
+type collector struct {
+	text strings.Builder
+	resp Response
+	err  error
+}
+
+func (c *collector) add(chunk Chunk) {
+	if chunk.Err != nil {
+		c.err = chunk.Err
+		return
+	}
+	if chunk.Usage != nil {
+		c.resp.Usage = *chunk.Usage
+	}
+	if chunk.FinishReason != "" {
+		c.resp.FinishReason = chunk.FinishReason
+	}
+	if chunk.Model != "" {
+		c.resp.Model = chunk.Model
+	}
+	if chunk.Provider != "" {
+		c.resp.Provider = chunk.Provider
+	}
+	c.text.WriteString(chunk.Text)
+}
+
+// result returns the response and the error that ended the stream, if any.
+// A stream that closed while ctx was done is incomplete even if no chunk said so.
+func (c *collector) result(ctx context.Context) (*Response, error) {
+	resp := c.resp
+	resp.Text = c.text.String()
+	if c.err != nil {
+		return &resp, c.err
+	}
+	return &resp, ctx.Err()
+}
+
+// CollectStream drains a stream, writing each fragment to w as it arrives
+// (w may be nil), and returns the assembled response. If the stream failed
+// or was cut short, the partial response is returned with the error.
+func CollectStream(ctx context.Context, ch <-chan Chunk, w io.Writer) (*Response, error) {
+	var c collector
+	for chunk := range ch {
+		c.add(chunk)
+		if w != nil && chunk.Err == nil {
+			io.WriteString(w, chunk.Text)
+		}
+	}
+	return c.result(ctx)
+}
+
+// Relay forwards the stream in to out until in is closed, and returns the
+// response assembled from it. Wrappers use it to watch a stream they pass
+// on. The error is the one that ended the stream, as for CollectStream; a
+// response is complete only if there is no error and it has a FinishReason.
+func Relay(ctx context.Context, in <-chan Chunk, out chan<- Chunk) (*Response, error) {
+	var c collector
+	for chunk := range in {
+		c.add(chunk)
+		out <- chunk // The consumer reads until out is closed
+	}
+	return c.result(ctx)
+}
+
+// GenerateLive generates a completion and renders it to w while it streams.
+// A nil w falls back to the blocking Generate call.
+func GenerateLive(ctx context.Context, client LLMClient, req Request, w io.Writer) (*Response, error) {
+	if w == nil {
+		return client.Generate(ctx, req)
+	}
+	ch, err := client.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := CollectStream(ctx, ch, w)
+	fmt.Fprintln(w)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StreamResponse replays a complete response as a stream, one line per chunk.
+// Wrappers that answer without calling a provider use it to serve GenerateStream.
+func StreamResponse(ctx context.Context, resp *Response) <-chan Chunk {
+	ch := make(chan Chunk)
+	go func() {
+		defer close(ch)
+		for _, line := range strings.SplitAfter(resp.Text, "\n") {
+			if line != "" && !sendChunk(ctx, ch, Chunk{Text: line}) {
+				endStream(ctx, ch, nil)
+				return
+			}
+		}
+		usage := resp.Usage
+		if !sendChunk(ctx, ch, Chunk{Usage: &usage, FinishReason: resp.FinishReason}) {
+			endStream(ctx, ch, nil)
+		}
+	}()
+	return ch
+}
+
+// sendChunk delivers a chunk unless the context is cancelled first.
+// It returns false if the chunk was not delivered; the stream should then
+// stop and be ended with endStream.
+func sendChunk(ctx context.Context, ch chan<- Chunk, chunk Chunk) bool {
+	select {
+	case ch <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// endStream sends the final chunk of a stream that stopped early: ctx's
+// error if it is done, since errors caused by the cancellation only repeat
+// it, and err otherwise. Unlike sendChunk it always delivers the chunk, so a
+// consumer never mistakes a cut-short stream for a complete one.
+func endStream(ctx context.Context, ch chan<- Chunk, err error) {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	if err != nil {
+		ch <- Chunk{Err: err}
+	}
+}
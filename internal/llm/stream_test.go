@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// hangingOllama serves one fragment of a streamed chat and then waits until
+// the client goes away, like a model that is still generating.
+func hangingOllama(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			fmt.Fprint(w, `{"models":[{"name":"test:latest"}]}`)
+		case "/api/chat":
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":"partial "}}`)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var testRequest = Request{Model: "test", Messages: []Message{{Role: "user", Content: "hi"}}}
+
+func TestCollectStreamCancelledMidStream(t *testing.T) {
+	client := NewOllamaClient(hangingOllama(t).URL, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := client.GenerateStream(ctx, testRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := <-ch
+	if first.Text != "partial " {
+		t.Fatalf("first chunk = %+v, want the partial text", first)
+	}
+	cancel()
+
+	resp, err := CollectStream(ctx, ch, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if resp.FinishReason != "" {
+		t.Errorf("a cancelled response has finish reason %q", resp.FinishReason)
+	}
+}
+
+func TestGenerateLiveDeadline(t *testing.T) {
+	client := NewOllamaClient(hangingOllama(t).URL, true)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	resp, err := GenerateLive(ctx, client, testRequest, io.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if resp != nil {
+		t.Errorf("got a response for a stream cut short: %+v", resp)
+	}
+}
+
+func TestCollectStreamClosedWithoutError(t *testing.T) {
+	// A provider that closes its stream without an error chunk after the
+	// context is cancelled must not produce a successful response
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan Chunk, 1)
+	ch <- Chunk{Text: "trunc"}
+	close(ch)
+	cancel()
+
+	if _, err := CollectStream(ctx, ch, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
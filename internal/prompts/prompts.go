@@ -0,0 +1,263 @@
+// Package prompts holds the text/template prompts sent to the LLM. Every
+// built-in template can be overridden per repository from
+// .llmify/prompts/<name>.tmpl or inline in the "prompts" config section.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/jake/llmify/internal/config"
+)
+
+//go:embed defaults/*.tmpl
+var defaults embed.FS
+
+// Template names. Each task has a user prompt and a system prompt.
+const (
+	Commit            = "commit"
+	CommitSystem      = "commit_system"
+	CommitRepair      = "commit_repair"
+	CommitSplit       = "commit_split"
+	CommitSplitSystem = "commit_split_system"
+	Docs              = "docs"
+	DocsSystem        = "docs_system"
+	Refactor          = "refactor"
+	RefactorSystem    = "refactor_system"
+	RefactorRepair    = "refactor_repair"
+	PR                = "pr"
+	PRSystem          = "pr_system"
+	Changelog         = "changelog"
+	ChangelogMerge    = "changelog_merge"
+	ChangelogSystem   = "changelog_system"
+	DiffSummary       = "diff_summary"
+	DiffSummarySystem = "diff_summary_system"
+	Review            = "review"
+	ReviewSystem      = "review_system"
+)
+
+// Descriptions documents what each template is used for.
+var Descriptions = map[string]string{
+	Commit:            "Commit message for the staged diff (llmify commit)",
+	CommitSystem:      "System prompt for commit messages",
+	CommitRepair:      "Follow-up asking to fix a commit message that failed linting",
+	CommitSplit:       "Grouping of staged changes into several commits (llmify commit --split)",
+	CommitSplitSystem: "System prompt for splitting staged changes",
+	Docs:              "Documentation update for one file (llmify docs, commit --docs)",
+	DocsSystem:        "System prompt for documentation updates",
+	Refactor:          "Refactoring of a file or snippet (llmify refactor)",
+	RefactorSystem:    "System prompt for refactoring",
+	RefactorRepair:    "Follow-up asking to fix a refactoring that failed the verification commands",
+	PR:                "Pull request title and description for the current branch (llmify pr)",
+	PRSystem:          "System prompt for pull request descriptions",
+	Changelog:         "Changelog entries for a batch of commits (llmify changelog)",
+	ChangelogMerge:    "Merge of the changelog entries from several batches of commits",
+	ChangelogSystem:   "System prompt for changelogs",
+	DiffSummary:       "Summary of one part of a diff too large for a single request (commit, pr)",
+	DiffSummarySystem: "System prompt for diff summaries",
+	Review:            "Code review findings for the changes to one file (llmify review)",
+	ReviewSystem:      "System prompt for code reviews",
+}
+
+// File is a file made available to templates as .Files.
+type File struct {
+	Path    string
+	Content string
+	Deleted bool
+}
+
+// Change is one independently committable part of the staged diff, made
+// available to templates as .Changes.
+type Change struct {
+	ID   string // Label the LLM uses to refer to the change, e.g. "C3"
+	Path string
+	Diff string
+}
+
+// Data holds the variables available to every template. Fields that do not
+// apply to a task are left empty.
+type Data struct {
+	Diff        string   // Git diff of the changes
+	Files       []File   // Changed files with their contents
+	Goal        string   // What the user asked for (docs and refactor)
+	Language    string   // Language of the target file, e.g. "go" or "markdown"
+	Standards   []string // Team rules from .llmify_standards.yaml that apply to the target
+	Path        string   // Path of the target file, or the files in a part of a diff (diff_summary)
+	Target      string   // Content being updated: the document or code to refactor
+	Context     string   // Supporting context such as imports and related code, or a diffstat
+	Style       string   // Commit conventions learned from the repository history, if enabled
+	Examples    []string // Representative commit messages from the repository history
+	Violations  []string // Lint rules a generated commit message broke
+	Instruction string   // Extra instruction given when regenerating commit messages
+	Changes     []Change // Staged changes to group into commits (commit --split)
+	Commits     []string // Commit messages, oldest first (pr, changelog)
+	Branch      string   // Branch being described (pr)
+	Base        string   // Branch the pull request targets (pr)
+	Template    string   // The repository's pull request template, if it has one (pr)
+	Entries     []string // Changelog entries from each batch, as "Category: text" (changelog_merge)
+	Summaries   []string // Summaries of the parts of a diff too large to send whole; Diff is then empty
+	Collapsed   []string // Lockfiles and generated files left out of Diff, as one-line stats
+	Scope       string   // Part of the file a refactoring may change, e.g. "func:Parse"; empty for the whole file
+	Failures    []string // Verification commands a refactoring failed, each followed by its output
+}
+
+// Sources a template can come from.
+const (
+	SourceBuiltin = "builtin"
+	SourceConfig  = "config"
+)
+
+// Set is a collection of named templates with their overrides applied.
+type Set struct {
+	templates map[string]*template.Template
+	sources   map[string]string // Name -> builtin, config or the override file path
+	texts     map[string]string
+}
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// Names returns the template names in a stable order.
+func Names() []string {
+	names := make([]string, 0, len(Descriptions))
+	for name := range Descriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load builds the template set: built-in templates, overridden by files in
+// dir, overridden in turn by inline templates from config.
+func Load(dir string, inline map[string]string) (*Set, error) {
+	s := &Set{
+		templates: make(map[string]*template.Template),
+		sources:   make(map[string]string),
+		texts:     make(map[string]string),
+	}
+	for _, name := range Names() {
+		data, err := defaults.ReadFile("defaults/" + name + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("missing built-in template %q: %w", name, err)
+		}
+		text, source := string(data), SourceBuiltin
+
+		if dir != "" {
+			path := filepath.Join(dir, name+".tmpl")
+			if data, err := os.ReadFile(path); err == nil {
+				text, source = string(data), path
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read prompt template %s: %w", path, err)
+			}
+		}
+		if override, ok := inline[name]; ok && override != "" {
+			text, source = override, SourceConfig
+		}
+
+		tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prompt template %q (%s): %w", name, source, err)
+		}
+		s.templates[name] = tmpl
+		s.sources[name] = source
+		s.texts[name] = text
+	}
+
+	// Catch typos such as .llmify/prompts/comit.tmpl that would otherwise be ignored
+	if dir != "" {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		for _, path := range matches {
+			name := strings.TrimSuffix(filepath.Base(path), ".tmpl")
+			if _, ok := Descriptions[name]; !ok {
+				return nil, fmt.Errorf("unknown prompt template %s (known: %s)", path, strings.Join(Names(), ", "))
+			}
+		}
+	}
+	for name := range inline {
+		if _, ok := Descriptions[name]; !ok {
+			return nil, fmt.Errorf("unknown prompt template %q in config (known: %s)", name, strings.Join(Names(), ", "))
+		}
+	}
+	return s, nil
+}
+
+// Render executes the named template with data.
+func (s *Set) Render(name string, data Data) (string, error) {
+	tmpl, ok := s.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt template %q", name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering prompt template %q (%s): %w", name, s.sources[name], err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Source reports where the named template was loaded from.
+func (s *Set) Source(name string) string {
+	return s.sources[name]
+}
+
+// Text returns the unrendered template text.
+func (s *Set) Text(name string) string {
+	return s.texts[name]
+}
+
+var (
+	activeOnce sync.Once
+	active     *Set
+	activeErr  error
+)
+
+// Active returns the template set for the current repository, loaded on
+// first use from config.GlobalConfig.
+func Active() (*Set, error) {
+	activeOnce.Do(func() {
+		cfg := config.GlobalConfig.Prompts
+		active, activeErr = Load(cfg.Dir, cfg.Templates)
+	})
+	return active, activeErr
+}
+
+// SampleData returns placeholder values used to preview and validate templates.
+func SampleData() Data {
+	return Data{
+		Diff: `diff --git a/greet.go b/greet.go
+--- a/greet.go
++++ b/greet.go
+@@ -1,3 +1,3 @@
+ func Greet(name string) string {
+-	return "Hello " + name
++	return fmt.Sprintf("Hello, %s!", name)
+ }`,
+		Files:       []File{{Path: "greet.go", Content: "package greet\n\nfunc Greet(name string) string {\n\treturn fmt.Sprintf(\"Hello, %s!\", name)\n}\n"}},
+		Goal:        "Use fmt.Sprintf for string formatting.",
+		Language:    "go",
+		Standards:   []string{"Exported functions must have doc comments."},
+		Path:        "greet.go",
+		Target:      "func Greet(name string) string {\n\treturn \"Hello \" + name\n}",
+		Context:     "Imports:\nimport \"fmt\"",
+		Violations:  []string{"subject-max-length: the subject line is 91 characters; keep it at most 72"},
+		Instruction: "Mention the migration.",
+		Commits:     []string{"fix: add punctuation to greeting"},
+		Branch:      "fix-greeting",
+		Base:        "main",
+		Template:    "## Summary\n\n## Testing\n",
+		Entries:     []string{"Fixed: Greetings now end with punctuation."},
+		Collapsed:   []string{"go.sum (generated, +2 -0)"},
+		Failures:    []string{"$ go build ./...\n./greet.go:4:9: undefined: fmt"},
+		Changes:     []Change{{ID: "C1", Path: "greet.go", Diff: "@@ -1,3 +1,3 @@\n func Greet(name string) string {\n-\treturn \"Hello \" + name\n+\treturn fmt.Sprintf(\"Hello, %s!\", name)\n }\n"}},
+	}
+}
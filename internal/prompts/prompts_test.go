@@ -0,0 +1,134 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsRender(t *testing.T) {
+	s, err := Load("", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summarized := SampleData()
+	summarized.Diff = ""
+	summarized.Summaries = []string{"greet.go:\nGreet now punctuates."}
+	for _, name := range Names() {
+		if s.Source(name) != SourceBuiltin {
+			t.Errorf("%s: source = %q, want builtin", name, s.Source(name))
+		}
+		for _, data := range []Data{SampleData(), summarized, {}} {
+			got, err := s.Render(name, data)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			if got == "" || strings.Contains(got, "<no value>") {
+				t.Errorf("%s: rendered %q", name, got)
+			}
+		}
+	}
+
+	// Every built-in template is documented, so none is silently unused
+	entries, err := defaults.ReadDir("defaults")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := strings.TrimSuffix(entry.Name(), ".tmpl"); Descriptions[name] == "" {
+			t.Errorf("built-in template %s has no description", entry.Name())
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		Commit: "from the file {{.Branch}}",
+		Docs:   "docs from the file {{.Path}}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name+".tmpl"), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := Load(dir, map[string]string{
+		Commit: "inline {{.Base | upper}}", // Beats the file
+		PR:     "",                         // Empty overrides are ignored
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{Commit, SourceConfig, "inline MAIN"},
+		{Docs, filepath.Join(dir, "docs.tmpl"), "docs from the file greet.go"},
+		{PR, SourceBuiltin, ""},
+	}
+	for _, tt := range tests {
+		if got := s.Source(tt.name); got != tt.source {
+			t.Errorf("%s: source = %q, want %q", tt.name, got, tt.source)
+		}
+		got, err := s.Render(tt.name, SampleData())
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want != "" && got != tt.want {
+			t.Errorf("%s: rendered %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := s.Text(Commit); got != "inline {{.Base | upper}}" {
+		t.Errorf("Text(commit) = %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	typo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(typo, "comit.tmpl"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := t.TempDir()
+	if err := os.WriteFile(filepath.Join(broken, "docs.tmpl"), []byte("{{.Diff"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		dir    string
+		inline map[string]string
+		want   string
+	}{
+		{"unknown file", typo, nil, "unknown prompt template " + filepath.Join(typo, "comit.tmpl")},
+		{"unknown inline", "", map[string]string{"comit": "x"}, `unknown prompt template "comit" in config`},
+		{"invalid file", broken, nil, `invalid prompt template "docs" (` + filepath.Join(broken, "docs.tmpl") + ")"},
+		{"invalid inline", "", map[string]string{PR: "{{if}}"}, `invalid prompt template "pr" (config)`},
+		{"missing dir", filepath.Join(typo, "missing"), nil, ""},
+	}
+	for _, tt := range tests {
+		_, err := Load(tt.dir, tt.inline)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	s, err := Load("", map[string]string{Commit: "{{.Nope}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Render(Commit, SampleData()); err == nil || !strings.Contains(err.Error(), `rendering prompt template "commit" (config)`) {
+		t.Errorf("rendering an unknown field: err = %v", err)
+	}
+	if _, err := s.Render("nope", SampleData()); err == nil {
+		t.Error("rendered an unknown template")
+	}
+}
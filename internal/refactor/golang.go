@@ -0,0 +1,221 @@
+package refactor
+
+import (
+	"context"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is one problem found in a proposed change.
+type Diagnostic struct {
+	File     string // Relative to the repository root when possible
+	Line     int    // 1-based; 0 if unknown
+	Col      int
+	Severity string // SeverityError or SeverityWarning
+	Source   string // What found it: "syntax", "gofmt" or "types"
+	Message  string
+}
+
+// String renders the diagnostic as "file:line:col: message".
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location += ":" + strconv.Itoa(d.Line)
+		if d.Col > 0 {
+			location += ":" + strconv.Itoa(d.Col)
+		}
+	}
+	if d.Severity == SeverityWarning {
+		return fmt.Sprintf("%s: warning: %s", location, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", location, d.Message)
+}
+
+// HasErrors reports whether any diagnostic is an error.
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatDiagnostics renders diagnostics one per line.
+func FormatDiagnostics(diagnostics []Diagnostic) string {
+	lines := make([]string, len(diagnostics))
+	for i, d := range diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckGo checks proposedContent for the Go file at filePath without writing
+// it: it must parse, gofmt must accept it, and the package containing it
+// (with its tests) must type check with the proposed content in place. Only
+// an unformatted file is a warning, since FormatGo fixes it; every other
+// diagnostic is an error. The returned bool reports whether there were no
+// errors.
+func CheckGo(ctx context.Context, repoRoot, filePath, proposedContent string) (bool, []Diagnostic, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return false, nil, err
+	}
+	relPath := displayPath(repoRoot, absPath)
+
+	// 1. Syntax
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, absPath, proposedContent, parser.ParseComments|parser.AllErrors); err != nil {
+		var diagnostics []Diagnostic
+		if list, ok := err.(scanner.ErrorList); ok {
+			list.RemoveMultiples() // One error per line; the rest are usually follow-ons
+			for _, e := range list {
+				diagnostics = append(diagnostics, Diagnostic{File: relPath, Line: e.Pos.Line, Col: e.Pos.Column, Severity: SeverityError, Source: "syntax", Message: e.Msg})
+			}
+		} else {
+			diagnostics = append(diagnostics, Diagnostic{File: relPath, Severity: SeverityError, Source: "syntax", Message: err.Error()})
+		}
+		return false, diagnostics, nil // Type checking would only repeat these
+	}
+
+	// 2. Formatting
+	var diagnostics []Diagnostic
+	formatted, err := format.Source([]byte(proposedContent))
+	if err != nil {
+		diagnostics = append(diagnostics, gofmtDiagnostic(relPath, err))
+	} else if line := firstDifference(proposedContent, string(formatted)); line > 0 {
+		diagnostics = append(diagnostics, Diagnostic{File: relPath, Line: line, Severity: SeverityWarning, Source: "gofmt", Message: "file is not gofmt-formatted"})
+	}
+
+	// 3. Types, with the proposed content overlaid on the file
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     filepath.Dir(absPath),
+		Tests:   true,
+		Overlay: map[string][]byte{absPath: []byte(proposedContent)},
+	}
+	pkgs, err := packages.Load(cfg, "file="+absPath)
+	if err != nil {
+		return false, diagnostics, fmt.Errorf("failed to load the package of %s: %w", relPath, err)
+	}
+	// The package and its test variants share files, so errors can repeat.
+	// Errors in dependencies are not the change's fault and are left out.
+	// The go command also compiles the package and reports the same type
+	// errors without positions, so its errors only count if there are no
+	// others.
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		checked := false
+		for _, e := range pkg.Errors {
+			checked = checked || e.Kind != packages.ListError
+		}
+		for _, e := range pkg.Errors {
+			if checked && e.Kind == packages.ListError {
+				continue
+			}
+			d := packageDiagnostic(repoRoot, e)
+			if key := d.String(); !seen[key] {
+				seen[key] = true
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return !HasErrors(diagnostics), diagnostics, nil
+}
+
+// FormatGo formats content with gofmt's rules, in process so no gofmt
+// binary is needed. If the content cannot be formatted it is returned
+// unchanged with an error diagnostic for relPath.
+func FormatGo(relPath, content string) (string, []Diagnostic) {
+	formatted, err := format.Source([]byte(content))
+	if err != nil {
+		return content, []Diagnostic{gofmtDiagnostic(relPath, err)}
+	}
+	return string(formatted), nil
+}
+
+// gofmtDiagnostic converts an error from format.Source, keeping the position
+// of the first syntax error if it has one.
+func gofmtDiagnostic(relPath string, err error) Diagnostic {
+	d := Diagnostic{File: relPath, Severity: SeverityError, Source: "gofmt", Message: err.Error()}
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		d.Line, d.Col, d.Message = list[0].Pos.Line, list[0].Pos.Column, list[0].Msg
+	}
+	return d
+}
+
+// packageDiagnostic converts an error reported by go/packages, whose
+// position is "file:line:col", "file:line" or empty.
+func packageDiagnostic(repoRoot string, e packages.Error) Diagnostic {
+	d := Diagnostic{Severity: SeverityError, Source: "types", Message: e.Msg}
+	if e.Kind == packages.ParseError {
+		d.Source = "syntax"
+	}
+	pos := e.Pos
+	// Split from the right, since Windows paths contain a colon
+	if i := strings.LastIndex(pos, ":"); i > 0 {
+		if n, err := strconv.Atoi(pos[i+1:]); err == nil {
+			d.Line, pos = n, pos[:i]
+			if i := strings.LastIndex(pos, ":"); i > 0 {
+				if m, err := strconv.Atoi(pos[i+1:]); err == nil {
+					d.Line, d.Col, pos = m, n, pos[:i]
+				}
+			}
+		}
+	}
+	if pos != "" && pos != "-" {
+		d.File = displayPath(repoRoot, pos)
+	}
+	return d
+}
+
+// displayPath returns path relative to repoRoot if it is inside it.
+func displayPath(repoRoot, path string) string {
+	if repoRoot != "" {
+		if rel, err := filepath.Rel(repoRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return path
+}
+
+// firstDifference returns the first line, 1-based, on which a and b differ,
+// or 0 if they are equal.
+func firstDifference(a, b string) int {
+	if a == b {
+		return 0
+	}
+	linesA, linesB := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := 0; i < len(linesA) && i < len(linesB); i++ {
+		if linesA[i] != linesB[i] {
+			return i + 1
+		}
+	}
+	return min(len(linesA), len(linesB)) + 1
+}
@@ -0,0 +1,133 @@
+package refactor
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const doubleSource = `package pkg
+
+// Double returns twice n.
+func Double(n int) int {
+	return n * 2
+}
+`
+
+// goModule writes a module whose package pkg has two files, a.go
+// (doubleSource) and b.go, which calls Double. It returns the module root.
+func goModule(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Setenv("GOWORK", "off")
+	t.Setenv("GOFLAGS", "")
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"go.mod":        "module example.com/m\n\ngo 1.21\n",
+		"pkg/a.go":      doubleSource,
+		"pkg/b.go":      "package pkg\n\n// Quadruple returns four times n.\nfunc Quadruple(n int) int {\n\treturn Double(Double(n))\n}\n",
+		"pkg/c_test.go": "package pkg\n\nimport \"testing\"\n\nfunc TestDouble(t *testing.T) {\n\tif Double(2) != 4 {\n\t\tt.Fail()\n\t}\n}\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCheckGoChange(t *testing.T) {
+	root := goModule(t)
+	tests := []struct {
+		name     string
+		proposed string
+		passed   bool
+		want     []Diagnostic
+	}{
+		{
+			name:     "unchanged",
+			proposed: doubleSource,
+			passed:   true,
+		},
+		{
+			name:     "syntax error",
+			proposed: "package pkg\n\nfunc Double(n int) int {\n\treturn n *\n}\n",
+			want: []Diagnostic{
+				{File: "pkg/a.go", Line: 5, Col: 1, Severity: SeverityError, Source: "syntax", Message: "expected operand, found '}'"},
+			},
+		},
+		{
+			name:     "unformatted",
+			proposed: "package pkg\n\n// Double returns twice n.\nfunc Double(n int) int {\n  return n*2\n}\n",
+			passed:   true,
+			want: []Diagnostic{
+				{File: "pkg/a.go", Line: 5, Severity: SeverityWarning, Source: "gofmt", Message: "file is not gofmt-formatted"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		check := CheckGoChange(context.Background(), root, "pkg/a.go", tt.proposed)
+		if check.Command != GoCheckName || !check.Builtin || check.Skipped || check.Passed != tt.passed {
+			t.Errorf("%s: check = %+v, want passed = %v", tt.name, check, tt.passed)
+		}
+		if !reflect.DeepEqual(check.Diagnostics, tt.want) {
+			t.Errorf("%s: diagnostics = %+v, want %+v", tt.name, check.Diagnostics, tt.want)
+		}
+		if check.Output != FormatDiagnostics(tt.want) {
+			t.Errorf("%s: output = %q", tt.name, check.Output)
+		}
+	}
+
+	// The files on disk are not touched
+	if data, err := os.ReadFile(filepath.Join(root, "pkg", "a.go")); err != nil || string(data) != doubleSource {
+		t.Errorf("a.go = %q, %v", data, err)
+	}
+}
+
+func TestCheckGoChangeTypeErrorElsewhere(t *testing.T) {
+	root := goModule(t)
+	// Double still compiles, but its callers in b.go and c_test.go do not
+	proposed := "package pkg\n\n// Double returns n twice.\nfunc Double(s string) string {\n\treturn s + s\n}\n"
+	check := CheckGoChange(context.Background(), root, "pkg/a.go", proposed)
+	if check.Passed || check.Skipped {
+		t.Fatalf("check = %+v, want a failure", check)
+	}
+	files := map[string]bool{}
+	for _, d := range check.Diagnostics {
+		if d.Source != "types" || d.Severity != SeverityError || d.Line == 0 || d.Col == 0 {
+			t.Errorf("diagnostic %+v is not a positioned type error", d)
+		}
+		files[d.File] = true
+	}
+	if !files["pkg/b.go"] || !files["pkg/c_test.go"] || files["pkg/a.go"] {
+		t.Errorf("errors reported in %v, want pkg/b.go and pkg/c_test.go", files)
+	}
+	if !strings.HasPrefix(check.Output, "pkg/b.go:5:9: ") {
+		t.Errorf("output = %q, want it to start with pkg/b.go:5:9", check.Output)
+	}
+}
+
+func TestCheckGoChangeWithoutPackage(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+	t.Setenv("GOWORK", "off")
+	// A file outside any module still gets its syntax and formatting checked
+	root := t.TempDir()
+	check := CheckGoChange(context.Background(), root, "a.go", "package a\n\nfunc F() {\n")
+	if check.Passed || check.Skipped || len(check.Diagnostics) != 1 || check.Diagnostics[0].Source != "syntax" {
+		t.Errorf("check = %+v, want one syntax error", check)
+	}
+}
@@ -0,0 +1,499 @@
+package refactor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jake/llmify/internal/editor"
+	"github.com/jake/llmify/internal/language"
+)
+
+// Scope kinds accepted by --scope, as "kind:value".
+const (
+	ScopeFunc  = "func"
+	ScopeClass = "class"
+	ScopeLines = "lines"
+)
+
+// scopeAliases maps the accepted spellings of each kind.
+var scopeAliases = map[string]string{
+	"func": ScopeFunc, "function": ScopeFunc, "method": ScopeFunc, "def": ScopeFunc,
+	"class": ScopeClass, "type": ScopeClass, "struct": ScopeClass, "interface": ScopeClass,
+	"lines": ScopeLines, "line": ScopeLines,
+}
+
+// maxScopeContextChars limits the read-only context sent around a target.
+// Beyond it, only the lines near the target and the imports are kept.
+const maxScopeContextChars = 40 * 1000
+
+// scopeContextLines is how many lines around the target are kept when the
+// rest of the file is too long to send.
+const scopeContextLines = 80
+
+// Target is the part of a file a refactoring may change.
+type Target struct {
+	Scope     string // The selector, e.g. "func:ParseLLMResponse"; empty for the whole file
+	Start     int    // Byte offset where the editable region starts
+	End       int    // Byte offset just past it
+	StartLine int    // First line of the region, 1-based
+	EndLine   int    // Last line of the region
+	Code      string // The editable region
+	Context   string // The rest of the file with a marker where the region is, read-only
+}
+
+// SelectTarget finds the region of content that scope selects. Scopes are
+// "func:Name" (also "Type.Method"), "class:Name" and "lines:N-M". Go files
+// are parsed with go/parser; other languages use a brace- and
+// indentation-aware heuristic. An empty scope selects the whole file.
+func SelectTarget(path, content, scope string) (*Target, error) {
+	if strings.TrimSpace(scope) == "" {
+		return &Target{Start: 0, End: len(content), StartLine: 1, EndLine: strings.Count(content, "\n") + 1, Code: content}, nil
+	}
+	kind, value, ok := strings.Cut(scope, ":")
+	kind = scopeAliases[strings.ToLower(strings.TrimSpace(kind))]
+	value = strings.TrimSpace(value)
+	if !ok || kind == "" || value == "" {
+		return nil, fmt.Errorf("invalid scope %q: use func:<name>, class:<name> or lines:<start>-<end>", scope)
+	}
+
+	var start, end int
+	var err error
+	switch {
+	case kind == ScopeLines:
+		start, end, err = lineRegion(content, value)
+	case language.Detect(path) == "go":
+		start, end, err = goRegion(path, content, kind, value)
+	default:
+		start, end, err = heuristicRegion(content, language.Detect(path), kind, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Target{
+		Scope:     kind + ":" + value,
+		Start:     start,
+		End:       end,
+		StartLine: strings.Count(content[:start], "\n") + 1,
+		EndLine:   strings.Count(content[:end], "\n"),
+		Code:      content[start:end],
+	}
+	if end > 0 && content[end-1] != '\n' {
+		t.EndLine++ // The region ends on the last line, which has no newline
+	}
+	t.Context = scopeContext(content, t)
+	return t, nil
+}
+
+// Whole reports whether the target is the entire file.
+func (t *Target) Whole() bool {
+	return t.Scope == ""
+}
+
+// Splice replaces the target region of content with replacement and returns
+// the new file. Bytes outside the region are kept exactly. The replacement
+// gets the region's indentation and its trailing newline back if the LLM
+// dropped them, see reindent.
+func (t *Target) Splice(content, replacement string) string {
+	if t.Whole() {
+		return replacement
+	}
+	replacement = reindent(t.Code, replacement)
+	if strings.HasSuffix(t.Code, "\n") && !strings.HasSuffix(replacement, "\n") {
+		replacement += "\n"
+	}
+	return content[:t.Start] + replacement + content[t.End:]
+}
+
+// reindent restores the indentation of code, a nested region, on a
+// replacement whose first line has none. Replies are either trimmed, losing
+// only the first line's indentation, or written from column 0 as if the
+// region were top-level. The lines after the first tell them apart: a
+// dedented reply indents them less than code does, so every non-blank line
+// is indented; otherwise only the first line is. Indentation-based
+// languages stay valid either way.
+func reindent(code, replacement string) string {
+	indent := leadingSpace(code)
+	if indent == "" || replacement == "" || leadingSpace(replacement) != "" {
+		return replacement
+	}
+	_, rest, _ := strings.Cut(replacement, "\n")
+	_, codeRest, _ := strings.Cut(code, "\n")
+	want := len(indent)
+	if depth, ok := minIndent(codeRest); ok {
+		want = depth
+	}
+	if depth, ok := minIndent(rest); !ok || depth >= want {
+		return indent + replacement
+	}
+	lines := strings.SplitAfter(replacement, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// minIndent returns the smallest indentation of the non-blank lines of s, and
+// false if there are none.
+func minIndent(s string) (int, bool) {
+	depth, found := 0, false
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(leadingSpace(line)); !found || n < depth {
+			depth, found = n, true
+		}
+	}
+	return depth, found
+}
+
+// Apply applies the LLM's response, edit blocks or a full replacement, to the
+// target and splices the result into content. It returns the new file, the
+// parsed edits (if any) and whether the response was a full replacement.
+func (t *Target) Apply(content, response string) (string, []editor.Edit, bool, error) {
+	edits, full, err := editor.ParseLLMResponse(response)
+	if err != nil {
+		return "", nil, false, err
+	}
+	if full != "" {
+		return t.Splice(content, full), nil, true, nil
+	}
+	if len(edits) == 0 {
+		return content, nil, false, nil
+	}
+	code, err := editor.ApplyEdits(t.Code, edits)
+	if err != nil {
+		return "", edits, false, err
+	}
+	return t.Splice(content, code), edits, false, nil
+}
+
+// Within returns the target's region in updated, a version of original in
+// which only the region changed, such as the result of Apply. Follow-up
+// responses are applied to it.
+func (t *Target) Within(original, updated string) *Target {
+	moved := *t
+	moved.End = len(updated) - (len(original) - t.End)
+	moved.Code = updated[moved.Start:moved.End]
+	moved.EndLine = moved.StartLine + strings.Count(strings.TrimSuffix(moved.Code, "\n"), "\n")
+	return &moved
+}
+
+// lineRegion selects lines "N-M" or "N", 1-based and inclusive.
+func lineRegion(content, value string) (int, int, error) {
+	from, to, isRange := strings.Cut(value, "-")
+	first, err := strconv.Atoi(strings.TrimSpace(from))
+	last := first
+	if err == nil && isRange {
+		last, err = strconv.Atoi(strings.TrimSpace(to))
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid line range %q: use lines:<start>-<end>", value)
+	}
+	starts := lineStarts(content)
+	if first < 1 || last < first || last > len(starts) {
+		return 0, 0, fmt.Errorf("line range %d-%d is outside the file, which has %d lines", first, last, len(starts))
+	}
+	return starts[first-1], lineEnd(content, starts[last-1]), nil
+}
+
+// goRegion finds a function, method or type declaration with go/parser. Doc
+// comments belong to the declaration and are part of the region.
+func goRegion(path, content, kind, name string) (int, int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if file == nil {
+		return 0, 0, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	recv, method, isMethod := strings.Cut(name, ".")
+	var found ast.Node
+	var doc *ast.CommentGroup
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if kind != ScopeFunc {
+				continue
+			}
+			if isMethod {
+				if d.Recv != nil && d.Name.Name == method && receiverName(d.Recv) == strings.Trim(recv, "(*)") {
+					found, doc = d, d.Doc
+				}
+			} else if d.Name.Name == name && (d.Recv == nil || found == nil) {
+				found, doc = d, d.Doc // Prefer a function over a method of the same name
+			}
+		case *ast.GenDecl:
+			if kind != ScopeClass || d.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if ts.Name.Name != name {
+					continue
+				}
+				if len(d.Specs) == 1 {
+					found, doc = d, d.Doc
+				} else {
+					found, doc = ts, ts.Doc
+				}
+			}
+		}
+	}
+	if found == nil {
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s %q not found in %s (the file does not parse: %v)", kind, name, path, err)
+		}
+		return 0, 0, fmt.Errorf("%s %q not found in %s", kind, name, path)
+	}
+	pos := found.Pos()
+	if doc != nil {
+		pos = doc.Pos()
+	}
+	start := fset.Position(pos).Offset
+	end := fset.Position(found.End()).Offset
+	return lineStart(content, start), lineEnd(content, end), nil
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// or type parameters.
+func receiverName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// indentLanguages mark blocks by indentation (Ruby and Lua close them with
+// "end" at the declaration's indentation) rather than braces.
+var indentLanguages = map[string]bool{"python": true, "yaml": true, "ruby": true, "lua": true}
+
+// heuristicRegion finds a declaration in languages llmify cannot parse. The
+// declaration line is found with a regular expression; the body runs to the
+// matching closing brace, or for indentation-based languages to the last line
+// indented deeper than the declaration. Comments, decorators and annotations
+// directly above are included. "Class.method" looks for the method inside the
+// class.
+func heuristicRegion(content, lang, kind, name string) (int, int, error) {
+	if outer, inner, ok := strings.Cut(name, "."); ok && kind == ScopeFunc {
+		start, end, err := heuristicRegion(content, lang, ScopeClass, outer)
+		if err != nil {
+			return 0, 0, err
+		}
+		// Search the class body only; offsets are relative to it
+		innerStart, innerEnd, err := heuristicRegion(content[start:end], lang, ScopeFunc, inner)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s %q not found in %s", kind, inner, outer)
+		}
+		return start + innerStart, start + innerEnd, nil
+	}
+	for _, re := range declPatterns(kind, name) {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			declStart := lineStart(content, loc[0])
+			var end int
+			var ok bool
+			if indentLanguages[lang] {
+				end, ok = indentBlockEnd(content, declStart)
+			} else {
+				end, ok = braceBlockEnd(content, loc[1], lang)
+			}
+			if ok {
+				return leadingComments(content, declStart), end, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("%s %q not found", kind, name)
+}
+
+// declPatterns returns regular expressions for the declaration of name, most
+// reliable first.
+func declPatterns(kind, name string) []*regexp.Regexp {
+	n := regexp.QuoteMeta(name)
+	if kind == ScopeClass {
+		return []*regexp.Regexp{
+			regexp.MustCompile(`(?m)^[ \t]*(?:[\w@]+[ \t]+)*(?:class|interface|struct|enum|trait|type|impl|object|record|module|protocol)[ \t]+` + n + `\b`),
+		}
+	}
+	return []*regexp.Regexp{
+		// function foo(, def foo(, fn foo(, func foo(, fun foo(, sub foo
+		regexp.MustCompile(`(?m)^[ \t]*(?:[\w@]+[ \t]+)*(?:function\*?|def|fn|func|fun|sub|proc)[ \t]+` + n + `\b`),
+		// const foo = (...) =>, foo: function(, foo = async function
+		regexp.MustCompile(`(?m)^[ \t]*(?:(?:export|const|let|var|static|public|private|readonly)[ \t]+)*` + n + `[ \t]*[:=][ \t]*(?:async[ \t]+)?(?:function\b|\([^)]*\)[ \t]*(?::[^=]+)?=>|[\w$]+[ \t]*=>)`),
+		// Methods: modifiers and a return type, then foo(
+		regexp.MustCompile(`(?m)^[ \t]*(?:[\w<>\[\],.*&?@]+[ \t]+)*` + n + `[ \t]*(?:<[^>\n]*>)?[ \t]*\(`),
+	}
+}
+
+// braceBlockEnd returns the end of the line holding the brace that closes the
+// first block opened after from. It fails if a statement ends before a block
+// opens, as in a call or a declaration without a body.
+func braceBlockEnd(content string, from int, lang string) (int, bool) {
+	depth, parens := 0, 0
+	for i := from; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			i = lineEnd(content, i) - 1
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			if j := strings.Index(content[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				return 0, false
+			}
+		case c == '"' || c == '`' || c == '\'' && lang != "rust":
+			i = skipString(content, i)
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return lineEnd(content, i), true
+			}
+		case c == ';' && depth == 0 && parens <= 0:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// skipString returns the index of the quote that closes the string starting
+// at content[i]. Only backquoted strings may span lines.
+func skipString(content string, i int) int {
+	quote := content[i]
+	for j := i + 1; j < len(content); j++ {
+		switch content[j] {
+		case '\\':
+			j++
+		case quote:
+			return j
+		case '\n':
+			if quote != '`' {
+				return j - 1 // Unterminated; e.g. an apostrophe in a comment-like position
+			}
+		}
+	}
+	return len(content) - 1
+}
+
+// indentBlockEnd returns the end of the block introduced by the line at
+// declStart: every following line that is blank or indented deeper, plus a
+// closing "end" or brace line at the declaration's own indentation.
+func indentBlockEnd(content string, declStart int) (int, bool) {
+	declEnd := lineEnd(content, declStart)
+	indent := len(leadingSpace(content[declStart:declEnd]))
+	end := declEnd
+	for pos := declEnd; pos < len(content); {
+		next := lineEnd(content, pos)
+		line := strings.TrimRight(content[pos:next], "\r\n")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case len(leadingSpace(line)) > indent:
+			end = next
+		default:
+			if trimmed == "end" || strings.HasPrefix(trimmed, "}") {
+				end = next
+			}
+			return end, end > declEnd
+		}
+		pos = next
+	}
+	return end, end > declEnd
+}
+
+// leadingComments moves start up over the comment, decorator and annotation
+// lines directly above a declaration.
+func leadingComments(content string, start int) int {
+	for start > 0 {
+		prev := lineStart(content, start-1)
+		trimmed := strings.TrimSpace(content[prev:start])
+		if trimmed == "" {
+			break
+		}
+		isComment := false
+		for _, prefix := range []string{"//", "/*", "*", "#", "@", "--"} {
+			if strings.HasPrefix(trimmed, prefix) {
+				isComment = true
+			}
+		}
+		if !isComment {
+			break
+		}
+		start = prev
+	}
+	return start
+}
+
+// scopeContext returns the file with the target replaced by a marker, so the
+// LLM sees where the target sits. Long files keep only the lines near the
+// target, plus their imports.
+func scopeContext(content string, t *Target) string {
+	marker := fmt.Sprintf("<<< TARGET CODE (%s, lines %d-%d) IS HERE >>>\n", t.Scope, t.StartLine, t.EndLine)
+	before, after := content[:t.Start], content[t.End:]
+	if len(before)+len(after) <= maxScopeContextChars {
+		return before + marker + after
+	}
+	beforeLines := strings.SplitAfter(before, "\n")
+	if len(beforeLines) > scopeContextLines {
+		beforeLines = beforeLines[len(beforeLines)-scopeContextLines:]
+	}
+	afterLines := strings.SplitAfter(after, "\n")
+	if len(afterLines) > scopeContextLines {
+		afterLines = afterLines[:scopeContextLines]
+	}
+	return "Imports:\n" + extractImports(content) + "\n...\n" +
+		strings.Join(beforeLines, "") + marker + strings.Join(afterLines, "") + "\n..."
+}
+
+// lineStarts returns the offset at which each line of content starts.
+func lineStarts(content string) []int {
+	starts := []int{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' && i+1 < len(content) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// lineStart returns the offset of the start of the line holding offset.
+func lineStart(content string, offset int) int {
+	return strings.LastIndexByte(content[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset just past the newline ending the line holding
+// offset, or the end of content.
+func lineEnd(content string, offset int) int {
+	if i := strings.IndexByte(content[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(content)
+}
+
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
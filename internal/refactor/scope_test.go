@@ -0,0 +1,209 @@
+package refactor
+
+import (
+	"strings"
+	"testing"
+)
+
+const goSource = `package p
+
+import "fmt"
+
+// Greet says hello.
+func Greet(name string) string {
+	return fmt.Sprintf("hi %s", name)
+}
+
+type T struct{ n int }
+
+// Inc adds one.
+func (t *T) Inc() { t.n++ }
+
+func Inc() {}
+`
+
+const tsSource = "import { x } from \"y\";\n" +
+	"\n" +
+	"export class Greeter {\n" +
+	"  // Says hello.\n" +
+	"  greet(name: string): string {\n" +
+	"    const s = \"}{\";\n" +
+	"    return `hi ${name} }`;\n" +
+	"  }\n" +
+	"\n" +
+	"  /* block } */\n" +
+	"  other() { return '}'; }\n" +
+	"}\n" +
+	"\n" +
+	"function helper(a: number) {\n" +
+	"  if (a) { return 1; } // }\n" +
+	"  return 0;\n" +
+	"}\n"
+
+const pySource = `import os
+
+
+class Greeter:
+    """Greets."""
+
+    @staticmethod
+    def greet(name):
+        if name:
+            return "hi " + name
+
+        return "hi"
+
+    def other(self):
+        pass
+
+
+def helper():
+    return 1
+`
+
+const rubySource = `class A
+  def run
+    puts 1
+  end
+end
+`
+
+func TestSelectTarget(t *testing.T) {
+	tests := []struct {
+		path, content, scope string
+		code                 string
+		startLine, endLine   int
+	}{
+		{"p.go", goSource, "func:Greet", "// Greet says hello.\nfunc Greet(name string) string {\n\treturn fmt.Sprintf(\"hi %s\", name)\n}\n", 5, 8},
+		{"p.go", goSource, "method:T.Inc", "// Inc adds one.\nfunc (t *T) Inc() { t.n++ }\n", 12, 13},
+		{"p.go", goSource, "func:(*T).Inc", "// Inc adds one.\nfunc (t *T) Inc() { t.n++ }\n", 12, 13},
+		{"p.go", goSource, "func:Inc", "func Inc() {}\n", 15, 15},
+		{"p.go", goSource, "type:T", "type T struct{ n int }\n", 10, 10},
+		{"a.ts", tsSource, "func:helper", "function helper(a: number) {\n  if (a) { return 1; } // }\n  return 0;\n}\n", 14, 17},
+		{"a.ts", tsSource, "func:Greeter.greet", "  // Says hello.\n  greet(name: string): string {\n    const s = \"}{\";\n    return `hi ${name} }`;\n  }\n", 4, 8},
+		{"a.ts", tsSource, "func:other", "  /* block } */\n  other() { return '}'; }\n", 10, 11},
+		{"a.ts", tsSource, "class:Greeter", tsSource[strings.Index(tsSource, "export"):strings.Index(tsSource, "\nfunction")], 3, 12},
+		{"a.py", pySource, "def:Greeter.greet", "    @staticmethod\n    def greet(name):\n        if name:\n            return \"hi \" + name\n\n        return \"hi\"\n", 7, 12},
+		{"a.py", pySource, "func:helper", "def helper():\n    return 1\n", 18, 19},
+		{"a.py", pySource, "class:Greeter", pySource[strings.Index(pySource, "class"):strings.Index(pySource, "\n\ndef helper")], 4, 15},
+		{"a.rb", rubySource, "func:A.run", "  def run\n    puts 1\n  end\n", 2, 4},
+		{"a.txt", "a\nb\nc\nd", "lines:2-3", "b\nc\n", 2, 3},
+		{"a.txt", "a\nb\nc\nd", "line: 4", "d", 4, 4},
+		{"a.py", pySource, "", pySource, 1, 20},
+	}
+	for _, tt := range tests {
+		target, err := SelectTarget(tt.path, tt.content, tt.scope)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.path, tt.scope, err)
+			continue
+		}
+		if target.Code != tt.code || target.StartLine != tt.startLine || target.EndLine != tt.endLine {
+			t.Errorf("%s %s: lines %d-%d %q; want lines %d-%d %q",
+				tt.path, tt.scope, target.StartLine, target.EndLine, target.Code, tt.startLine, tt.endLine, tt.code)
+		}
+		if tt.content[target.Start:target.End] != target.Code {
+			t.Errorf("%s %s: offsets do not match the code", tt.path, tt.scope)
+		}
+		if tt.scope != "" && !strings.Contains(target.Context, "<<< TARGET CODE (") {
+			t.Errorf("%s %s: no marker in the context", tt.path, tt.scope)
+		}
+	}
+}
+
+func TestSelectTargetErrors(t *testing.T) {
+	tests := []struct{ path, content, scope string }{
+		{"p.go", goSource, "func:Missing"},
+		{"p.go", goSource, "class:Greet"}, // A function, not a type
+		{"a.ts", tsSource, "func:Greeter.missing"},
+		{"a.py", pySource, "func:os"},
+		{"a.txt", "a\nb", "lines:2-3"},
+		{"a.txt", "a\nb", "lines:2-1"},
+		{"a.txt", "a\nb", "lines:x"},
+		{"a.txt", "a\nb", "block:1"},
+		{"a.txt", "a\nb", "func:"},
+	}
+	for _, tt := range tests {
+		if target, err := SelectTarget(tt.path, tt.content, tt.scope); err == nil {
+			t.Errorf("%s %s: selected %q", tt.path, tt.scope, target.Code)
+		}
+	}
+}
+
+func TestSplice(t *testing.T) {
+	tests := []struct {
+		name, path, content, scope string
+		replacement                string
+		want                       string // The new region; everything else must be unchanged
+	}{
+		{
+			name: "go function", path: "p.go", content: goSource, scope: "func:Greet",
+			replacement: "func Greet(name string) string {\n\treturn name\n}",
+			want:        "func Greet(name string) string {\n\treturn name\n}\n",
+		},
+		{
+			name: "trimmed python method", path: "a.py", content: pySource, scope: "func:Greeter.greet",
+			replacement: "@staticmethod\n    def greet(name):\n        return name.upper()",
+			want:        "    @staticmethod\n    def greet(name):\n        return name.upper()\n",
+		},
+		{
+			name: "dedented python method", path: "a.py", content: pySource, scope: "func:Greeter.greet",
+			replacement: "@staticmethod\ndef greet(name):\n    if name:\n        return name.upper()\n\n    return \"\"\n",
+			want:        "    @staticmethod\n    def greet(name):\n        if name:\n            return name.upper()\n\n        return \"\"\n",
+		},
+		{
+			name: "dedented ruby method", path: "a.rb", content: rubySource, scope: "func:A.run",
+			replacement: "def run\n  puts 2\nend",
+			want:        "  def run\n    puts 2\n  end\n",
+		},
+		{
+			name: "trimmed ruby method", path: "a.rb", content: rubySource, scope: "func:A.run",
+			replacement: "def run\n    puts 2\n  end",
+			want:        "  def run\n    puts 2\n  end\n",
+		},
+		{
+			name: "dedented class member", path: "a.ts", content: tsSource, scope: "func:Greeter.greet",
+			replacement: "greet(name: string): string {\n  return name;\n}",
+			want:        "  greet(name: string): string {\n    return name;\n  }\n",
+		},
+		{
+			name: "indented reply", path: "a.ts", content: tsSource, scope: "func:Greeter.greet",
+			replacement: "    greet(): string {\n  return '';\n}\n",
+			want:        "    greet(): string {\n  return '';\n}\n",
+		},
+		{
+			name: "one line", path: "a.ts", content: tsSource, scope: "func:other",
+			replacement: "other() { return 2; }",
+			want:        "  other() { return 2; }\n",
+		},
+		{
+			name: "last line without newline", path: "a.txt", content: "a\nb\nc\nd", scope: "lines:4",
+			replacement: "D",
+			want:        "D",
+		},
+	}
+	for _, tt := range tests {
+		target, err := SelectTarget(tt.path, tt.content, tt.scope)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := target.Splice(tt.content, tt.replacement)
+		want := tt.content[:target.Start] + tt.want + tt.content[target.End:]
+		if got != want {
+			t.Errorf("%s: spliced\n%s\nwant\n%s", tt.name, got, want)
+			continue
+		}
+		if moved := target.Within(tt.content, got); moved.Code != tt.want || moved.StartLine != target.StartLine {
+			t.Errorf("%s: Within = lines %d-%d %q, want %q", tt.name, moved.StartLine, moved.EndLine, moved.Code, tt.want)
+		}
+	}
+}
+
+func TestSpliceWholeFile(t *testing.T) {
+	target, err := SelectTarget("a.py", pySource, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := target.Splice(pySource, "x = 1"); got != "x = 1" {
+		t.Errorf("whole-file splice = %q", got)
+	}
+}
@@ -0,0 +1,212 @@
+package refactor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/git"
+)
+
+// maxFailureChars limits the output of a failed check sent back to the LLM.
+// Compilers report the first errors first, so the start is kept.
+const maxFailureChars = 6000
+
+// Check is one verification command and its result.
+type Check struct {
+	Command     string
+	Output      string
+	Passed      bool
+	Skipped     bool         // The command's program is not installed
+	Builtin     bool         // Run by llmify itself; Command is only a name
+	Diagnostics []Diagnostic // Problems found by a builtin check
+}
+
+// title renders the check's command for logs and the LLM.
+func (c Check) title() string {
+	if c.Builtin {
+		return c.Command
+	}
+	return "$ " + c.Command
+}
+
+// Verification is the result of running the checks for one proposed change.
+type Verification struct {
+	Checks []Check
+}
+
+// Passed reports whether no check failed. Skipped checks do not count.
+func (v *Verification) Passed() bool {
+	for _, c := range v.Checks {
+		if !c.Passed && !c.Skipped {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed returns the commands of the checks that failed.
+func (v *Verification) Failed() []string {
+	var commands []string
+	for _, c := range v.Checks {
+		if !c.Passed && !c.Skipped {
+			commands = append(commands, c.Command)
+		}
+	}
+	return commands
+}
+
+// Failures renders each failed check as its command line followed by its
+// output, shortened for the LLM.
+func (v *Verification) Failures() []string {
+	var failures []string
+	for _, c := range v.Checks {
+		if c.Passed || c.Skipped {
+			continue
+		}
+		output := strings.TrimSpace(c.Output)
+		if len(output) > maxFailureChars {
+			output = output[:maxFailureChars] + "\n... (output truncated)"
+		}
+		failures = append(failures, c.title()+"\n"+output)
+	}
+	return failures
+}
+
+// Log renders every check with its status and full output.
+func (v *Verification) Log() string {
+	var b strings.Builder
+	for _, c := range v.Checks {
+		status := "FAILED"
+		switch {
+		case c.Skipped:
+			status = "SKIPPED"
+		case c.Passed:
+			status = "PASSED"
+		}
+		fmt.Fprintf(&b, "%s\n[%s]\n", c.title(), status)
+		if output := strings.TrimSpace(c.Output); output != "" {
+			b.WriteString(output + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Commands returns the verification commands for lang, with {file} replaced
+// by relPath. The commands in refactor.verify.commands can run anything, so
+// they are used only when refactor.verify.enabled is set; otherwise the
+// language's default checks run.
+func Commands(cfg *config.Config, lang, relPath string) []string {
+	configured := config.DefaultVerifyCommands(lang)
+	if cfg.Refactor.Verify.Enabled {
+		configured = cfg.Refactor.Verify.Commands[strings.ToLower(lang)]
+	}
+	var commands []string
+	for _, command := range configured {
+		if command = strings.TrimSpace(command); command != "" {
+			commands = append(commands, strings.ReplaceAll(command, "{file}", shellQuote(filepath.ToSlash(relPath))))
+		}
+	}
+	return commands
+}
+
+// GoCheckName names the check run by CheckGoChange.
+const GoCheckName = "go parse, gofmt and type check"
+
+// CheckGoChange runs CheckGo as a verification check for the Go file at
+// relPath. It is skipped if the package cannot be loaded, for example
+// because go is not installed.
+func CheckGoChange(ctx context.Context, repoRoot, relPath, proposed string) Check {
+	check := Check{Command: GoCheckName, Builtin: true}
+	ok, diagnostics, err := CheckGo(ctx, repoRoot, filepath.Join(repoRoot, relPath), proposed)
+	if err != nil && !HasErrors(diagnostics) {
+		check.Skipped = true
+		check.Output = err.Error()
+		return check
+	}
+	check.Output = FormatDiagnostics(diagnostics)
+	check.Passed = ok
+	check.Diagnostics = diagnostics
+	return check
+}
+
+// runCheck runs command with the platform's shell in dir.
+func runCheck(ctx context.Context, dir, command string, timeout time.Duration) Check {
+	check := Check{Command: command}
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := shellCommand(cmdCtx, command)
+	cmd.Dir = dir
+	// Killing the shell leaves the programs it started holding the output
+	// pipe, so stop waiting for them shortly after
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	check.Output = string(output)
+	check.Passed = err == nil
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == commandNotFound() {
+		check.Skipped = true
+	}
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		check.Output += fmt.Sprintf("\n(timed out after %s)", timeout)
+	}
+	return check
+}
+
+// SaveRejected writes a refactoring that failed verification under dir
+// (relative to repoRoot) as a patch next to its verification log, and
+// returns both paths relative to repoRoot.
+func SaveRejected(repoRoot, dir, relPath, original, proposed string, v *Verification) (string, string, error) {
+	patch, err := git.DiffContents(relPath, original, proposed)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create patch: %w", err)
+	}
+	base := filepath.Join(dir, relPath)
+	if err := os.MkdirAll(filepath.Join(repoRoot, filepath.Dir(base)), 0755); err != nil {
+		return "", "", err
+	}
+	patchPath, logPath := base+".patch", base+".log"
+	if err := os.WriteFile(filepath.Join(repoRoot, patchPath), []byte(patch), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, logPath), []byte(v.Log()), 0644); err != nil {
+		return "", "", err
+	}
+	return patchPath, logPath, nil
+}
+
+// shellCommand runs command with the platform's shell.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// commandNotFound is the exit status of the platform's shell for a command
+// that is not installed.
+func commandNotFound() int {
+	if runtime.GOOS == "windows" {
+		return 9009
+	}
+	return 127
+}
+
+// shellQuote quotes s for the shell if it contains anything but safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+", r))
+	}) < 0 {
+		return s
+	}
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
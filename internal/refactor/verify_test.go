@@ -0,0 +1,153 @@
+package refactor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/gittest"
+)
+
+func TestCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cmd quotes differently")
+	}
+	configured := map[string][]string{
+		"python": {"ruff check {file}", "  ", "pytest"},
+		"go":     {"make lint"},
+	}
+	tests := []struct {
+		name    string
+		enabled bool
+		lang    string
+		relPath string
+		want    []string
+	}{
+		{"default", false, "Python", "pkg/app.py", []string{"mypy pkg/app.py"}},
+		{"default with spaces", false, "python", "my pkg/it's.py", []string{`mypy 'my pkg/it'\''s.py'`}},
+		{"default go", false, "go", "main.go", config.DefaultVerifyCommands("go")},
+		{"configured ignored", false, "typescript", "a.ts", []string{"tsc --noEmit"}},
+		{"configured", true, "python", "pkg/app.py", []string{"ruff check pkg/app.py", "pytest"}},
+		{"configured replaces defaults", true, "go", "main.go", []string{"make lint"}},
+		{"nothing configured", true, "typescript", "a.ts", nil},
+		{"unknown language", false, "cobol", "a.cbl", nil},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{}
+		cfg.Refactor.Verify.Enabled = tt.enabled
+		cfg.Refactor.Verify.Commands = configured
+		if got := Commands(cfg, tt.lang, tt.relPath); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the checks use sh")
+	}
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Refactor.Verify.Enabled = true
+	cfg.Refactor.Verify.Commands = map[string][]string{"python": {"printf '%s\\n' {file}"}}
+	quoted := Commands(cfg, "python", filepath.Join("my pkg", "it's $HOME.py"))[0]
+
+	tests := []struct {
+		command string
+		timeout time.Duration
+		want    Check
+	}{
+		{"echo ok; pwd", time.Minute, Check{Output: "ok\n" + dir + "\n", Passed: true}},
+		{quoted, time.Minute, Check{Output: "my pkg/it's $HOME.py\n", Passed: true}},
+		{"echo broken >&2; exit 2", time.Minute, Check{Output: "broken\n"}},
+		{"llmify-no-such-program --check", time.Minute, Check{Skipped: true}},
+		{"echo started; sleep 10", 100 * time.Millisecond, Check{Output: "started\n\n(timed out after 100ms)"}},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		got := runCheck(context.Background(), dir, tt.command, tt.timeout)
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("%s: ran for %s", tt.command, time.Since(start))
+		}
+		tt.want.Command = tt.command
+		if tt.want.Skipped {
+			got.Output = "" // The shell's message varies
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestVerificationFailures(t *testing.T) {
+	long := strings.Repeat("x", maxFailureChars+100)
+	v := &Verification{Checks: []Check{
+		{Command: GoCheckName, Builtin: true, Output: "\n" + long + "\n"},
+		{Command: "go build ./...", Passed: true, Output: "built\n"},
+		{Command: "mypy app.py", Skipped: true, Output: "sh: mypy: not found\n"},
+		{Command: "go vet ./...", Output: "  vet: bad\n\n"},
+	}}
+	if v.Passed() {
+		t.Error("Passed() with two failed checks")
+	}
+	if want := []string{GoCheckName, "go vet ./..."}; !reflect.DeepEqual(v.Failed(), want) {
+		t.Errorf("Failed() = %q, want %q", v.Failed(), want)
+	}
+	wantFailures := []string{
+		GoCheckName + "\n" + long[:maxFailureChars] + "\n... (output truncated)",
+		"$ go vet ./...\nvet: bad",
+	}
+	if got := v.Failures(); !reflect.DeepEqual(got, wantFailures) {
+		t.Errorf("Failures() = %q, want %q", got, wantFailures)
+	}
+	wantLog := GoCheckName + "\n[FAILED]\n" + long + "\n\n" +
+		"$ go build ./...\n[PASSED]\nbuilt\n\n" +
+		"$ mypy app.py\n[SKIPPED]\nsh: mypy: not found\n\n" +
+		"$ go vet ./...\n[FAILED]\nvet: bad\n\n"
+	if got := v.Log(); got != wantLog {
+		t.Errorf("Log() = %q, want %q", got, wantLog)
+	}
+
+	skipped := &Verification{Checks: []Check{{Command: "tsc --noEmit", Skipped: true}}}
+	if !skipped.Passed() || skipped.Failures() != nil {
+		t.Error("a skipped check counts as a failure")
+	}
+}
+
+func TestSaveRejected(t *testing.T) {
+	root := gittest.Repo(t)
+	original := "def main():\n    print('hi')\n\n\nmain()\n"
+	proposed := "def main() -> None:\n    print('hello')\n\n\nmain()\n"
+	gittest.WriteFile(t, "src/app.py", original)
+	gittest.Commit(t, "Initial commit")
+	v := &Verification{Checks: []Check{{Command: "mypy src/app.py", Output: "src/app.py:2: error\n"}}}
+
+	patchPath, logPath, err := SaveRejected(root, filepath.Join(".llmify", "refactor"), "src/app.py", original, proposed, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(".llmify", "refactor", "src", "app.py.patch"); patchPath != want {
+		t.Errorf("patch path = %q, want %q", patchPath, want)
+	}
+	if want := filepath.Join(".llmify", "refactor", "src", "app.py.log"); logPath != want {
+		t.Errorf("log path = %q, want %q", logPath, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, logPath)); err != nil || string(data) != v.Log() {
+		t.Errorf("log = %q, %v; want %q", data, err, v.Log())
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "src", "app.py")); err != nil || string(data) != original {
+		t.Errorf("the file was changed to %q, %v", data, err)
+	}
+
+	// The saved change applies to the working tree as it was
+	gittest.Run(t, "apply", filepath.ToSlash(patchPath))
+	if data, err := os.ReadFile(filepath.Join(root, "src", "app.py")); err != nil || string(data) != proposed {
+		t.Errorf("after git apply the file is %q, %v; want %q", data, err, proposed)
+	}
+}
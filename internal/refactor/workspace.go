@@ -0,0 +1,263 @@
+package refactor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jake/llmify/internal/git"
+	"github.com/spf13/viper"
+)
+
+// workspacePrefix names the temporary directories that verification runs in,
+// so RecoverWorkspaces can find the ones left behind by a run that crashed.
+const workspacePrefix = "llmify-verify-"
+
+// workspaceLockName is the file a running llmify keeps locked in each of its
+// workspaces. A workspace whose lock can be taken has no owner any more.
+const workspaceLockName = "owner.lock"
+
+// Workspace is a private copy of a repository's working tree in which
+// proposed changes are checked, so the user's files are never written before
+// a change is accepted. Tracked, untracked and ignored files are all copied:
+// checks may write to ignored directories such as build outputs, caches or
+// node_modules, and must not change the user's. The copy is made on first
+// use and shared by every check of a run.
+type Workspace struct {
+	repoRoot string
+
+	once sync.Once
+	err  error
+	dir  string   // Temporary directory holding the lock and the copy
+	root string   // The copy of the repository root
+	lock *os.File // Held until Close
+}
+
+// NewWorkspace returns a workspace for the repository at repoRoot.
+func NewWorkspace(repoRoot string) *Workspace {
+	return &Workspace{repoRoot: repoRoot}
+}
+
+// open creates the copy of the working tree.
+func (w *Workspace) open() error {
+	w.once.Do(func() {
+		w.err = w.create()
+		if w.err != nil {
+			w.Close()
+		}
+	})
+	return w.err
+}
+
+func (w *Workspace) create() error {
+	var err error
+	if w.dir, err = os.MkdirTemp("", workspacePrefix+"*"); err != nil {
+		return fmt.Errorf("failed to create verification workspace: %w", err)
+	}
+	if w.lock, err = os.Create(filepath.Join(w.dir, workspaceLockName)); err != nil {
+		return err
+	}
+	if _, err := tryLockFile(w.lock); err != nil {
+		return fmt.Errorf("failed to lock verification workspace: %w", err)
+	}
+	// Tools print paths, so the copy keeps the repository's directory name
+	w.root = filepath.Join(w.dir, filepath.Base(w.repoRoot))
+
+	files, err := git.ListFiles(w.repoRoot, false)
+	if err != nil {
+		return err
+	}
+	for _, path := range files {
+		if err := copyPath(filepath.Join(w.repoRoot, path), filepath.Join(w.root, path)); err != nil {
+			return fmt.Errorf("failed to copy %s into the verification workspace: %w", path, err)
+		}
+	}
+	ignored, err := git.ListFiles(w.repoRoot, true)
+	if err != nil {
+		return err
+	}
+	for _, path := range ignored {
+		path = strings.TrimSuffix(path, "/")
+		if err := copyPath(filepath.Join(w.repoRoot, path), filepath.Join(w.root, path)); err != nil {
+			return fmt.Errorf("failed to copy ignored path %s into the verification workspace: %w", path, err)
+		}
+	}
+	if viper.GetBool("verbose") {
+		log.Printf("Copied %d files into verification workspace %s", len(files), w.root)
+	}
+	return nil
+}
+
+// copyPath copies the file, symlink or directory at src to dst. Symlinks are
+// copied as links, not followed.
+func copyPath(src, dst string) error {
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) {
+		return nil // Deleted in the working tree
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case info.IsDir():
+		return copyDir(src, dst)
+	case !info.Mode().IsRegular():
+		return nil // Sockets, pipes and devices are not part of a build
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyDir copies the directory tree at src to dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		return copyPath(path, filepath.Join(dst, rel))
+	})
+}
+
+// Update sets the content of relPath in the workspace after a change was
+// applied to the working tree. It does nothing before the copy is made.
+func (w *Workspace) Update(relPath, content string) error {
+	if w.root == "" {
+		return nil
+	}
+	return w.write(relPath, content)
+}
+
+func (w *Workspace) write(relPath, content string) error {
+	if err := w.open(); err != nil {
+		return err
+	}
+	path := filepath.Join(w.root, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// Verify runs commands from the workspace's repository root with proposed
+// written to relPath, then puts original back. Commands whose program is not
+// installed are skipped. Paths into the workspace in the output are shown
+// as paths into the repository.
+func (w *Workspace) Verify(ctx context.Context, relPath, original, proposed string, commands []string, timeout time.Duration) (result *Verification, err error) {
+	if err := w.write(relPath, proposed); err != nil {
+		return nil, fmt.Errorf("failed to write proposed content to %s for verification: %w", relPath, err)
+	}
+	defer func() {
+		if restoreErr := w.write(relPath, original); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	result = &Verification{}
+	for _, command := range commands {
+		check := runCheck(ctx, w.root, command, timeout)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		check.Output = strings.ReplaceAll(check.Output, w.root, w.repoRoot)
+		result.Checks = append(result.Checks, check)
+	}
+	return result, nil
+}
+
+// Close removes the workspace.
+func (w *Workspace) Close() error {
+	if w.dir == "" {
+		return nil
+	}
+	if w.lock != nil {
+		w.lock.Close() // Also releases the lock
+		w.lock = nil
+	}
+	err := os.RemoveAll(w.dir)
+	w.dir, w.root = "", ""
+	return err
+}
+
+// RecoverWorkspaces removes the workspaces left in the temporary directory
+// by runs that crashed or were killed, and returns how many it removed.
+// Workspaces of runs still in progress are kept.
+func RecoverWorkspaces() (int, error) {
+	entries, err := os.ReadDir(os.TempDir())
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), workspacePrefix) {
+			continue
+		}
+		dir := filepath.Join(os.TempDir(), entry.Name())
+		if !abandoned(dir) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, fmt.Errorf("failed to remove abandoned workspace %s: %w", dir, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// abandoned reports whether no running llmify owns the workspace in dir.
+// Workspaces younger than a minute are left alone, since their owner may not
+// have taken the lock yet.
+func abandoned(dir string) bool {
+	lockPath := filepath.Join(dir, workspaceLockName)
+	info, err := os.Stat(lockPath)
+	if os.IsNotExist(err) {
+		// Crashed before the lock file was created
+		info, err = os.Stat(dir)
+		return err == nil && time.Since(info.ModTime()) > time.Hour
+	}
+	if err != nil || time.Since(info.ModTime()) < time.Minute {
+		return false
+	}
+	f, err := os.OpenFile(lockPath, os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	locked, err := tryLockFile(f)
+	return err == nil && locked
+}
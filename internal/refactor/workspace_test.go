@@ -0,0 +1,254 @@
+package refactor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jake/llmify/internal/gittest"
+)
+
+// makeWorkspaceDir creates a workspace-like directory in parent, optionally
+// with a lock file, and backdates it by age.
+func makeWorkspaceDir(t *testing.T, parent, name string, withLock bool, age time.Duration) string {
+	t.Helper()
+	dir := filepath.Join(parent, name)
+	if err := os.MkdirAll(filepath.Join(dir, "repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	then := time.Now().Add(-age)
+	if withLock {
+		lock := filepath.Join(dir, workspaceLockName)
+		if err := os.WriteFile(lock, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(lock, then, then); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(dir, then, then); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// holdLock locks the workspace in dir as its running owner would.
+func holdLock(t *testing.T, dir string) {
+	t.Helper()
+	f, err := os.OpenFile(filepath.Join(dir, workspaceLockName), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if locked, err := tryLockFile(f); err != nil || !locked {
+		t.Fatalf("could not lock %s: %v", dir, err)
+	}
+}
+
+func TestAbandoned(t *testing.T) {
+	tests := []struct {
+		name     string
+		withLock bool
+		locked   bool
+		age      time.Duration
+		want     bool
+	}{
+		{"unlocked", true, false, 2 * time.Minute, true},
+		{"locked by its owner", true, true, 2 * time.Minute, false},
+		{"locked for days", true, true, 72 * time.Hour, false},
+		{"too new to judge", true, false, 10 * time.Second, false},
+		{"no lock file, old", false, false, 2 * time.Hour, true},
+		{"no lock file, recent", false, false, 30 * time.Minute, false},
+	}
+	parent := t.TempDir()
+	for i, tt := range tests {
+		dir := makeWorkspaceDir(t, parent, workspacePrefix+string(rune('a'+i)), tt.withLock, tt.age)
+		if tt.locked {
+			holdLock(t, dir)
+		}
+		if got := abandoned(dir); got != tt.want {
+			t.Errorf("%s: abandoned = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if abandoned(filepath.Join(parent, "missing")) {
+		t.Error("a missing directory counts as abandoned")
+	}
+}
+
+func TestRecoverWorkspaces(t *testing.T) {
+	tmp := t.TempDir()
+	for _, env := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(env, tmp)
+	}
+	crashed := makeWorkspaceDir(t, tmp, workspacePrefix+"crashed", true, time.Hour)
+	neverLocked := makeWorkspaceDir(t, tmp, workspacePrefix+"early", false, 3*time.Hour)
+	running := makeWorkspaceDir(t, tmp, workspacePrefix+"running", true, time.Hour)
+	holdLock(t, running)
+	starting := makeWorkspaceDir(t, tmp, workspacePrefix+"starting", false, time.Minute)
+	other := makeWorkspaceDir(t, tmp, "other-tool-1234", true, 3*time.Hour)
+	if err := os.WriteFile(filepath.Join(tmp, workspacePrefix+"file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := RecoverWorkspaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 {
+		t.Errorf("removed %d workspaces, want 2", removed)
+	}
+	for dir, want := range map[string]bool{crashed: false, neverLocked: false, running: true, starting: true, other: true} {
+		if _, err := os.Stat(dir); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", filepath.Base(dir), err == nil, want)
+		}
+	}
+
+	// A workspace created by this process is locked, so it survives recovery
+	w := NewWorkspace(gittest.Repo(t))
+	defer w.Close()
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := RecoverWorkspaces(); err != nil || removed != 0 {
+		t.Errorf("RecoverWorkspaces() = %d, %v with a live workspace", removed, err)
+	}
+	if _, err := os.Stat(w.dir); err != nil {
+		t.Errorf("the live workspace was removed: %v", err)
+	}
+}
+
+// snapshot returns the content of every file under root outside .git.
+func snapshot(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			files[path] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestWorkspaceVerify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the checks use sh")
+	}
+	root := gittest.Repo(t)
+	gittest.WriteFile(t, "a.txt", "original\n")
+	gittest.WriteFile(t, ".gitignore", "build/\n")
+	gittest.Commit(t, "Initial commit")
+	gittest.WriteFile(t, "build/cache.txt", "cached\n")
+	gittest.WriteFile(t, "untracked.txt", "untracked\n")
+	before := snapshot(t, root)
+
+	w := NewWorkspace(root)
+	defer w.Close()
+	if err := w.Update("a.txt", "ignored before the copy is made\n"); err != nil || w.root != "" {
+		t.Fatalf("Update made the copy early: %v", err)
+	}
+
+	result, err := w.Verify(context.Background(), "a.txt", "original\n", "proposed\n", []string{
+		"cat a.txt",
+		"cat build/cache.txt untracked.txt",
+		"echo changed > build/cache.txt && echo new > a.txt.orig && rm untracked.txt && pwd",
+		"llmify-no-such-program",
+		"echo broken >&2; exit 3",
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Check{
+		{Command: "cat a.txt", Output: "proposed\n", Passed: true},
+		{Command: "cat build/cache.txt untracked.txt", Output: "cached\nuntracked\n", Passed: true},
+		{Command: "echo changed > build/cache.txt && echo new > a.txt.orig && rm untracked.txt && pwd", Output: root + "\n", Passed: true},
+		{Command: "llmify-no-such-program", Skipped: true},
+		{Command: "echo broken >&2; exit 3", Output: "broken\n"},
+	}
+	if len(result.Checks) != len(want) {
+		t.Fatalf("got %d checks, want %d", len(result.Checks), len(want))
+	}
+	for i, c := range result.Checks {
+		if want[i].Skipped {
+			c.Output = "" // The shell's message varies
+		}
+		if c.Command != want[i].Command || c.Output != want[i].Output || c.Passed != want[i].Passed || c.Skipped != want[i].Skipped {
+			t.Errorf("check %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+	if result.Passed() || strings.Join(result.Failed(), "|") != "echo broken >&2; exit 3" {
+		t.Errorf("failed checks = %q", result.Failed())
+	}
+
+	// The proposed content is only in the copy, and only while checks run
+	if data, err := os.ReadFile(filepath.Join(w.root, "a.txt")); err != nil || string(data) != "original\n" {
+		t.Errorf("workspace a.txt = %q, %v; want the original back", data, err)
+	}
+	if after := snapshot(t, root); len(after) != len(before) {
+		t.Errorf("the repository changed: %v, was %v", after, before)
+	} else {
+		for path, content := range before {
+			if after[path] != content {
+				t.Errorf("%s changed to %q", path, after[path])
+			}
+		}
+	}
+
+	// Later checks see changes applied to the repository
+	if err := w.Update("a.txt", "applied\n"); err != nil {
+		t.Fatal(err)
+	}
+	result, err = w.Verify(context.Background(), "b.txt", "", "new file\n", []string{"cat a.txt b.txt"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := result.Checks[0].Output; out != "applied\nnew file\n" {
+		t.Errorf("output = %q", out)
+	}
+
+	dir := w.dir
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Close left %s behind", dir)
+	}
+}
+
+func TestWorkspaceVerifyCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the checks use sh")
+	}
+	root := gittest.Repo(t)
+	gittest.WriteFile(t, "a.txt", "original\n")
+	gittest.Commit(t, "Initial commit")
+	w := NewWorkspace(root)
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := w.Verify(ctx, "a.txt", "original\n", "proposed\n", []string{"sleep 10"}, time.Minute); err == nil {
+		t.Fatal("a cancelled verification returned a result")
+	}
+	if data, err := os.ReadFile(filepath.Join(w.root, "a.txt")); err != nil || string(data) != "original\n" {
+		t.Errorf("workspace a.txt = %q, %v after cancelling; want the original back", data, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Package review turns the LLM's code review replies into findings anchored to
+// lines of the changed files, and writes them as text, JSON or SARIF.
+package review
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jake/llmify/internal/diff"
+	"github.com/jake/llmify/internal/llm"
+)
+
+// Severities, from least to most severe.
+const (
+	Low      = "low"
+	Medium   = "medium"
+	High     = "high"
+	Critical = "critical"
+)
+
+// Severities lists the severities from least to most severe.
+var Severities = []string{Low, Medium, High, Critical}
+
+// ParseSeverity normalizes a severity name.
+func ParseSeverity(s string) (string, error) {
+	for _, severity := range Severities {
+		if strings.EqualFold(strings.TrimSpace(s), severity) {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity %q (want one of %s)", s, strings.Join(Severities, ", "))
+}
+
+func rank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// Finding is one review comment. Lines are in the post-image, the file as it
+// is after the change.
+type Finding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	EndLine    int    `json:"end_line,omitempty"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Annotate renders a hunk for review with the post-image line number in front
+// of every line that exists after the change; removed lines get none.
+func Annotate(h *diff.Hunk) string {
+	var b strings.Builder
+	b.WriteString(strings.SplitN(h.String(), "\n", 2)[0])
+	line := h.NewStart
+	for _, l := range h.Lines {
+		if strings.HasPrefix(l, "-") || strings.HasPrefix(l, "\\") {
+			fmt.Fprintf(&b, "\n%6s %s", "", l)
+			continue
+		}
+		fmt.Fprintf(&b, "\n%6d %s", line, l)
+		line++
+	}
+	return b.String()
+}
+
+// ParseFindings reads the LLM's JSON reply for file f. Lines are kept inside
+// the hunks of f, since those are the only lines the LLM saw numbered.
+// Findings with an unknown severity count as medium.
+func ParseFindings(text string, f *diff.File) ([]Finding, error) {
+	var reply struct {
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(llm.ExtractJSON(text)), &reply); err != nil {
+		return nil, fmt.Errorf("could not parse review findings: %w", err)
+	}
+	var findings []Finding
+	for _, finding := range reply.Findings {
+		finding.Message = strings.TrimSpace(finding.Message)
+		if finding.Message == "" {
+			continue
+		}
+		finding.File = f.Path()
+		if severity, err := ParseSeverity(finding.Severity); err == nil {
+			finding.Severity = severity
+		} else {
+			finding.Severity = Medium
+		}
+		finding.Category = strings.ToLower(strings.TrimSpace(finding.Category))
+		if finding.Category == "" {
+			finding.Category = "general"
+		}
+		finding.Suggestion = strings.TrimSpace(finding.Suggestion)
+		if finding.EndLine > finding.Line {
+			finding.EndLine = anchor(f, finding.EndLine)
+		}
+		finding.Line = anchor(f, finding.Line)
+		if finding.EndLine <= finding.Line {
+			finding.EndLine = 0 // Ranges that collapse onto one line after anchoring, too
+		}
+		findings = append(findings, finding)
+	}
+	return findings, nil
+}
+
+// anchor moves line to the nearest post-image line covered by f's hunks.
+func anchor(f *diff.File, line int) int {
+	best, bestDistance := 0, -1
+	for _, h := range f.Hunks {
+		if h.NewLines == 0 {
+			continue
+		}
+		first, last := h.NewStart, h.NewStart+h.NewLines-1
+		nearest := min(max(line, first), last)
+		distance := nearest - line
+		if distance < 0 {
+			distance = -distance
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = nearest, distance
+		}
+	}
+	if bestDistance < 0 {
+		return 1
+	}
+	return best
+}
+
+// Sort orders findings by file and line.
+func Sort(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+}
+
+// AtLeast returns the findings of the given severity or worse.
+func AtLeast(findings []Finding, severity string) []Finding {
+	var result []Finding
+	for _, f := range findings {
+		if rank(f.Severity) >= rank(severity) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// WriteText writes findings for a terminal, one "file:line: severity
+// [category] message" entry each, followed by a count per severity.
+func WriteText(w io.Writer, findings []Finding) error {
+	if len(findings) == 0 {
+		_, err := fmt.Fprintln(w, "No findings.")
+		return err
+	}
+	counts := make(map[string]int)
+	for _, f := range findings {
+		location := fmt.Sprintf("%s:%d", f.File, f.Line)
+		if f.EndLine > 0 {
+			location += fmt.Sprintf("-%d", f.EndLine)
+		}
+		fmt.Fprintf(w, "%s: %s [%s] %s\n", location, f.Severity, f.Category, f.Message)
+		if f.Suggestion != "" {
+			fmt.Fprintf(w, "    Suggested fix: %s\n", strings.ReplaceAll(f.Suggestion, "\n", "\n    "))
+		}
+		counts[f.Severity]++
+	}
+	var parts []string
+	for i := len(Severities) - 1; i >= 0; i-- {
+		if n := counts[Severities[i]]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, Severities[i]))
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d finding(s): %s\n", len(findings), strings.Join(parts, ", "))
+	return err
+}
+
+// WriteJSON writes findings as a JSON array.
+func WriteJSON(w io.Writer, findings []Finding) error {
+	if findings == nil {
+		findings = []Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(findings)
+}
@@ -0,0 +1,193 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jake/llmify/internal/diff"
+)
+
+// testPatch changes post-image lines 3-7 and 21-23 of main.go.
+const testPatch = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,4 +3,5 @@ import "fmt"
+ func a() {
+-	old()
++	new1()
++	new2()
+ }
+ 
+@@ -20,3 +21,3 @@ func b() {
+ 	x := 1
+-	y := 2
++	y := 3
+ 	z := 4
+`
+
+func parseTestPatch(t *testing.T) *diff.File {
+	t.Helper()
+	files, err := diff.Parse(testPatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("parsed %d files, want 1", len(files))
+	}
+	return files[0]
+}
+
+func TestAnnotate(t *testing.T) {
+	f := parseTestPatch(t)
+	want := strings.Join([]string{
+		`@@ -3,4 +3,5 @@ import "fmt"`,
+		"     3  func a() {",
+		"       -\told()",
+		"     4 +\tnew1()",
+		"     5 +\tnew2()",
+		"     6  }",
+		"     7  ",
+	}, "\n")
+	if got := Annotate(f.Hunks[0]); got != want {
+		t.Errorf("Annotate =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseFindings(t *testing.T) {
+	f := parseTestPatch(t)
+	reply := "Here is my review:\n```json\n" + `{"findings": [
+		{"line": 4, "severity": "HIGH", "category": " Security ", "message": " Unchecked input ", "suggestion": " validate it\n"},
+		{"line": 1, "severity": "blocker", "message": "Before the first hunk"},
+		{"line": 12, "severity": "low", "category": "style", "message": "Between hunks, nearer the first"},
+		{"line": 19, "severity": "low", "category": "style", "message": "Between hunks, nearer the second"},
+		{"line": 100, "severity": "critical", "category": "bug", "message": "Past the end"},
+		{"line": 4, "end_line": 6, "severity": "medium", "category": "bug", "message": "A range"},
+		{"line": 6, "end_line": 5, "severity": "medium", "category": "bug", "message": "A backwards range"},
+		{"line": 10, "end_line": 12, "severity": "medium", "category": "bug", "message": "A range outside the hunks"},
+		{"line": 5, "severity": "high", "message": "   "}
+	]}` + "\n```"
+	findings, err := ParseFindings(reply, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Finding{
+		{File: "main.go", Line: 4, Severity: High, Category: "security", Message: "Unchecked input", Suggestion: "validate it"},
+		{File: "main.go", Line: 3, Severity: Medium, Category: "general", Message: "Before the first hunk"},
+		{File: "main.go", Line: 7, Severity: Low, Category: "style", Message: "Between hunks, nearer the first"},
+		{File: "main.go", Line: 21, Severity: Low, Category: "style", Message: "Between hunks, nearer the second"},
+		{File: "main.go", Line: 23, Severity: Critical, Category: "bug", Message: "Past the end"},
+		{File: "main.go", Line: 4, EndLine: 6, Severity: Medium, Category: "bug", Message: "A range"},
+		{File: "main.go", Line: 6, Severity: Medium, Category: "bug", Message: "A backwards range"},
+		{File: "main.go", Line: 7, Severity: Medium, Category: "bug", Message: "A range outside the hunks"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings =\n%+v\nwant\n%+v", findings, want)
+	}
+
+	if _, err := ParseFindings("I found nothing worth mentioning.", f); err == nil {
+		t.Error("parsed a reply without JSON")
+	}
+}
+
+func TestParseFindingsDeletedFile(t *testing.T) {
+	files, err := diff.Parse("diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-package gone\n-\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := ParseFindings(`{"findings": [{"line": 2, "severity": "low", "message": "Still used elsewhere"}]}`, files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 || findings[0].File != "gone.go" || findings[0].Line != 1 {
+		t.Errorf("findings = %+v, want one on gone.go:1", findings)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	findings := []Finding{
+		{File: "cmd/main.go", Line: 4, EndLine: 6, Severity: Critical, Category: "security", Message: "SQL injection", Suggestion: "Use a prepared statement"},
+		{File: "main.go", Line: 7, Severity: Low, Category: "style", Message: "Long line"},
+		{File: "main.go", Line: 9, Severity: Medium, Category: "security", Message: "Weak hash"},
+	}
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, findings); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region map[string]int `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Schema != sarifSchema || log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log header: %s", buf.String())
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "llmify" {
+		t.Errorf("tool name = %q", run.Tool.Driver.Name)
+	}
+	var rules []string
+	for _, r := range run.Tool.Driver.Rules {
+		rules = append(rules, r.ID)
+	}
+	if !reflect.DeepEqual(rules, []string{"security", "style"}) {
+		t.Errorf("rules = %v, want one per category, sorted", rules)
+	}
+	if len(run.Results) != len(findings) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(findings))
+	}
+	wantLevels := []string{"error", "note", "warning"}
+	wantRegions := []map[string]int{{"startLine": 4, "endLine": 6}, {"startLine": 7}, {"startLine": 9}}
+	for i, r := range run.Results {
+		loc := r.Locations[0].PhysicalLocation
+		if r.RuleID != findings[i].Category || r.Level != wantLevels[i] || loc.ArtifactLocation.URI != findings[i].File {
+			t.Errorf("result %d = %+v", i, r)
+		}
+		if !reflect.DeepEqual(loc.Region, wantRegions[i]) {
+			t.Errorf("result %d region = %v, want %v", i, loc.Region, wantRegions[i])
+		}
+	}
+	if text := run.Results[0].Message.Text; text != "SQL injection\n\nSuggested fix: Use a prepared statement" {
+		t.Errorf("message = %q", text)
+	}
+}
+
+func TestWriteSARIFEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Consumers reject null where SARIF expects arrays
+	if out := buf.String(); strings.Contains(out, "null") || !strings.Contains(out, `"results": []`) || !strings.Contains(out, `"rules": []`) {
+		t.Errorf("empty log:\n%s", out)
+	}
+}
@@ -0,0 +1,122 @@
+package review
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// SARIF 2.1.0 (https://docs.oasis-open.org/sarif/sarif/v2.1.0/), as read by
+// GitHub code scanning and most CI dashboards. Only the parts llmify fills in
+// are modelled.
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine,omitempty"`
+}
+
+// sarifLevel maps severities to SARIF result levels.
+var sarifLevel = map[string]string{
+	Low:      "note",
+	Medium:   "warning",
+	High:     "error",
+	Critical: "error",
+}
+
+// WriteSARIF writes findings as a SARIF log with one rule per category. File
+// paths are relative to the repository root.
+func WriteSARIF(w io.Writer, findings []Finding) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "llmify",
+			InformationURI: "https://github.com/jakezegil/llmify",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	categories := make(map[string]bool)
+	for _, f := range findings {
+		categories[f.Category] = true
+		text := f.Message
+		if f.Suggestion != "" {
+			text += "\n\nSuggested fix: " + f.Suggestion
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:  f.Category,
+			Level:   sarifLevel[f.Severity],
+			Message: sarifMessage{Text: text},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: f.File},
+				Region:           sarifRegion{StartLine: f.Line, EndLine: f.EndLine},
+			}}},
+			Properties: map[string]string{"severity": f.Severity},
+		})
+	}
+	var ids []string
+	for id := range categories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: "Code review: " + id}})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
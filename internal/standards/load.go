@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/gobwas/glob"              // For glob pattern matching
 	"github.com/jake/llmify/internal/git" // Assuming git package is available
@@ -175,3 +176,27 @@ func checkAppliesTo(patterns []string, filePathRel string) bool {
 	}
 	return false // No patterns matched
 }
+
+var (
+	repoStandardsOnce sync.Once
+	repoStandards     *StandardsConfig
+)
+
+// RulePrompts returns the prompts of the LLM rules that apply to a file, for
+// use in prompt templates. It returns nil if the repository has no standards file.
+func RulePrompts(filePathRel string, lang string) []string {
+	repoStandardsOnce.Do(func() {
+		repoStandards, _, _ = LoadStandards("")
+	})
+	if repoStandards == nil {
+		return nil
+	}
+	rules, _ := GetApplicableRules(repoStandards, filePathRel, lang, nil)
+	var prompts []string
+	for _, rule := range rules {
+		if rule.Prompt != "" {
+			prompts = append(prompts, rule.Prompt)
+		}
+	}
+	return prompts
+}
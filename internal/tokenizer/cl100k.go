@@ -0,0 +1,55 @@
+package tokenizer
+
+import (
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// bpeTokenizer wraps a tiktoken BPE encoding. The vocabulary is embedded in
+// the binary through the offline loader, so counting never hits the network.
+type bpeTokenizer struct {
+	name string
+	enc  *tiktoken.Tiktoken
+}
+
+func newBPETokenizer(name, encoding string) (Tokenizer, error) {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &bpeTokenizer{name: name, enc: enc}, nil
+}
+
+func (t *bpeTokenizer) Name() string { return t.name }
+
+func (t *bpeTokenizer) Count(text string) int {
+	return len(t.enc.EncodeOrdinary(text))
+}
+
+func (t *bpeTokenizer) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	tokens := t.enc.EncodeOrdinary(text)
+	if len(tokens) <= maxTokens {
+		return text
+	}
+	// A token can end inside a multi-byte character; drop tokens until the
+	// cut falls between characters
+	valid := utf8.ValidString(text)
+	n := maxTokens
+	truncated := t.enc.Decode(tokens[:n])
+	for valid && n > 0 && !utf8.ValidString(truncated) {
+		n--
+		truncated = t.enc.Decode(tokens[:n])
+	}
+	return truncated
+}
+
+func init() {
+	Register("cl100k", func() (Tokenizer, error) { return newBPETokenizer("cl100k", "cl100k_base") })
+	Register("o200k", func() (Tokenizer, error) { return newBPETokenizer("o200k", "o200k_base") })
+}
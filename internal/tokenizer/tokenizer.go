@@ -0,0 +1,111 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultTokenizer is the tokenizer used when none is specified.
+const DefaultTokenizer = "cl100k"
+
+// Tokenizer counts and truncates text in model tokens.
+type Tokenizer interface {
+	// Name returns the registered name of the tokenizer.
+	Name() string
+	// Count returns the number of tokens in text.
+	Count(text string) int
+	// Truncate returns the longest prefix of text that fits in maxTokens.
+	Truncate(text string, maxTokens int) string
+}
+
+// Factory creates a Tokenizer instance.
+type Factory func() (Tokenizer, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]Factory{}
+	instances  = map[string]Tokenizer{}
+)
+
+// Register makes a tokenizer available under the given name.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(name)] = factory
+}
+
+// Get returns the tokenizer registered under name, creating it on first use.
+func Get(name string) (Tokenizer, error) {
+	if name == "" {
+		name = DefaultTokenizer
+	}
+	name = strings.ToLower(name)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if t, ok := instances[name]; ok {
+		return t, nil
+	}
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tokenizer %q (available: %s)", name, strings.Join(availableLocked(), ", "))
+	}
+	t, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("initializing tokenizer %q: %w", name, err)
+	}
+	instances[name] = t
+	return t, nil
+}
+
+// Available returns the names of all registered tokenizers.
+func Available() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return availableLocked()
+}
+
+func availableLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// approxTokenizer estimates tokens at roughly four characters per token.
+// It needs no vocabulary and is useful for quick estimates or unknown models.
+type approxTokenizer struct{}
+
+const approxCharsPerToken = 4
+
+func (approxTokenizer) Name() string { return "approx" }
+
+func (approxTokenizer) Count(text string) int {
+	runes := utf8.RuneCountInString(text)
+	return (runes + approxCharsPerToken - 1) / approxCharsPerToken
+}
+
+func (approxTokenizer) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	maxRunes := maxTokens * approxCharsPerToken
+	count := 0
+	for i := range text {
+		if count == maxRunes {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
+func init() {
+	Register("approx", func() (Tokenizer, error) { return approxTokenizer{}, nil })
+}
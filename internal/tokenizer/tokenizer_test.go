@@ -0,0 +1,91 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		tokenizer string
+		text      string
+		want      int
+	}{
+		{"approx", "", 0},
+		{"approx", "abcd", 1},
+		{"approx", "abcde", 2},
+		{"approx", "héllo", 2}, // Runes, not bytes
+		{"cl100k", "", 0},
+		{"cl100k", "hello world", 2},
+		{"cl100k", "package main\n", 3},
+		{"o200k", "hello world", 2},
+	}
+	for _, tt := range tests {
+		tok, err := Get(tt.tokenizer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("%s.Count(%q) = %d, want %d", tt.tokenizer, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	for _, name := range Available() {
+		tok, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, max := range []int{0, 1, 7, 50, 10000} {
+			got := tok.Truncate(text, max)
+			if !strings.HasPrefix(text, got) {
+				t.Errorf("%s.Truncate(text, %d) is not a prefix: %q", name, max, got)
+			}
+			if n := tok.Count(got); n > max {
+				t.Errorf("%s.Truncate(text, %d) has %d tokens", name, max, n)
+			}
+		}
+		if got := tok.Truncate(text, tok.Count(text)); got != text {
+			t.Errorf("%s.Truncate shortened text that fits", name)
+		}
+	}
+}
+
+func TestTruncateMultiByte(t *testing.T) {
+	// Emoji and many CJK characters take more than one token each
+	text := strings.Repeat("日本語のテキストを切る🙂👍🏽🇯🇵 ", 10)
+	for _, name := range Available() {
+		tok, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for max := 1; max <= 60; max++ {
+			got := tok.Truncate(text, max)
+			if !utf8.ValidString(got) {
+				t.Errorf("%s.Truncate(text, %d) cuts a character: %q", name, max, got)
+			}
+			if !strings.HasPrefix(text, got) {
+				t.Errorf("%s.Truncate(text, %d) is not a prefix: %q", name, max, got)
+			}
+			if n := tok.Count(got); n > max {
+				t.Errorf("%s.Truncate(text, %d) has %d tokens", name, max, n)
+			}
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	tok, err := Get("")
+	if err != nil || tok.Name() != DefaultTokenizer {
+		t.Errorf("Get(\"\") = %v, %v; want the default tokenizer", tok, err)
+	}
+	if tok, err := Get("CL100K"); err != nil || tok.Name() != "cl100k" {
+		t.Errorf("Get is not case-insensitive: %v, %v", tok, err)
+	}
+	if _, err := Get("nope"); err == nil || !strings.Contains(err.Error(), "approx") {
+		t.Errorf("Get(\"nope\") error = %v, want one listing the available tokenizers", err)
+	}
+}
@@ -3,17 +3,31 @@ package ui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
+// stdin is shared by every prompt so input piped ahead of time is not lost in
+// the buffer of a reader that has been thrown away.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine reads one line from stdin without the trailing newline. A final line
+// without a newline is returned as is.
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
 // Confirm prompts the user for a yes/no question and returns their response.
 // The defaultAnswer parameter should be "Y" or "n" to indicate the default response.
 func Confirm(prompt string, defaultAnswer string) (bool, error) {
-	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s [%s]: ", prompt, defaultAnswer)
 
-	response, err := reader.ReadString('\n')
+	response, err := readLine()
 	if err != nil {
 		return false, fmt.Errorf("failed to read input: %w", err)
 	}
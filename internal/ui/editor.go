@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,29 +11,44 @@ import (
 // DefaultEditorOrder defines the fallback order for text editors.
 var DefaultEditorOrder = []string{"vim", "nano", "vi", "emacs", "code", "notepad"} // Add more as needed
 
+// scissorsLine marks the point below which git discards everything in a commit message.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// commitEditHelp is appended to the message being edited, like git does.
+const commitEditHelp = `
+# Please enter the commit message for your changes. Lines starting
+# with '#' will be ignored, and an empty message aborts the commit.
+`
+
 // findEditor determines the editor to use based on environment or defaults.
-func findEditor() (string, error) {
-	editor := os.Getenv("EDITOR")
-	if editor != "" {
+// It returns the command followed by its arguments, so values such as
+// "code --wait" work.
+func findEditor() ([]string, error) {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		fields := strings.Fields(os.Getenv(env))
+		if len(fields) == 0 {
+			continue
+		}
 		// Ensure the editor command exists
-		path, err := exec.LookPath(editor)
+		path, err := exec.LookPath(fields[0])
 		if err == nil {
-			return path, nil
+			return append([]string{path}, fields[1:]...), nil
 		}
 	}
 	// Try default editors
 	for _, ed := range DefaultEditorOrder {
 		path, err := exec.LookPath(ed)
 		if err == nil {
-			return path, nil
+			return []string{path}, nil
 		}
 	}
-	return "", fmt.Errorf("no suitable text editor found in PATH or $EDITOR")
+	return nil, fmt.Errorf("no suitable text editor found in PATH, $VISUAL or $EDITOR")
 }
 
 // EditCommitMessage launches an editor to allow modification of the message.
+// Comment lines are stripped from the result the way git does.
 func EditCommitMessage(initialMessage string) (string, error) {
-	editorPath, err := findEditor()
+	editor, err := findEditor()
 	if err != nil {
 		return "", fmt.Errorf("cannot find editor: %w", err)
 	}
@@ -46,7 +60,7 @@ func EditCommitMessage(initialMessage string) (string, error) {
 	defer os.Remove(tmpfile.Name()) // Clean up
 
 	// Write initial message to temp file
-	if _, err := tmpfile.WriteString(initialMessage); err != nil {
+	if _, err := tmpfile.WriteString(strings.TrimSpace(initialMessage) + "\n" + commitEditHelp); err != nil {
 		tmpfile.Close()
 		return "", fmt.Errorf("failed to write to temporary file: %w", err)
 	}
@@ -55,14 +69,14 @@ func EditCommitMessage(initialMessage string) (string, error) {
 	}
 
 	// Prepare and run the editor command
-	cmd := exec.Command(editorPath, tmpfile.Name())
+	cmd := exec.Command(editor[0], append(editor[1:], tmpfile.Name())...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	fmt.Printf("Launching editor (%s) for commit message...\n", editorPath)
+	fmt.Printf("Launching editor (%s) for commit message...\n", editor[0])
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("editor '%s' failed: %w", editorPath, err)
+		return "", fmt.Errorf("editor '%s' failed: %w", editor[0], err)
 	}
 
 	// Read the potentially modified content back
@@ -71,33 +85,121 @@ func EditCommitMessage(initialMessage string) (string, error) {
 		return "", fmt.Errorf("failed to read back from temporary file: %w", err)
 	}
 
-	return string(contentBytes), nil
+	return StripComments(string(contentBytes)), nil
 }
 
-// ConfirmCommit prompts the user unless force is true. Returns true if commit should proceed.
-func ConfirmCommit(force bool) (bool, bool, error) { // Returns (proceed, editAgain, error)
-	if force {
-		return true, false, nil
+// StripComments cleans up an edited commit message like git's default
+// "strip" mode: it drops lines starting with '#' and everything below a
+// scissors line, removes trailing whitespace, collapses runs of blank lines
+// and trims blank lines at both ends.
+func StripComments(message string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		if line == scissorsLine {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
 	}
+	return strings.Join(lines, "\n")
+}
 
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Commit with the edited message? [Y/n/e(dit)] ")
-	response, err := reader.ReadString('\n')
-	if err != nil {
-		return false, false, fmt.Errorf("failed to read confirmation: %w", err)
+// CommitAction is what the user chose to do with a proposed commit message.
+type CommitAction int
+
+const (
+	CommitAbort      CommitAction = iota // Do not commit
+	CommitAccept                         // Commit with the current message
+	CommitEdit                           // Open the message in an editor
+	CommitRegenerate                     // Ask the LLM for a new message
+	CommitPrevious                       // Show the previous candidate message
+	CommitNext                           // Show the next candidate message
+)
+
+// CommitChoice is the answer to ConfirmCommit.
+type CommitChoice struct {
+	Action      CommitAction
+	Instruction string // Optional extra instruction for CommitRegenerate
+}
+
+// ConfirmOptions controls which answers ConfirmCommit offers.
+type ConfirmOptions struct {
+	Question   string // Defaults to "Commit with this message?"
+	Force      bool   // Skip the prompt and accept the message
+	AllowEdit  bool   // Offer to open the message in an editor
+	Candidate  int    // 1-based position of the message shown among the candidates
+	Candidates int    // Number of candidate messages generated so far
+}
+
+// ConfirmCommit prompts the user unless opts.Force is true. Answering "r" asks
+// for an optional extra instruction for the regenerated message; it can also
+// be given inline, as in "r mention the migration".
+func ConfirmCommit(opts ConfirmOptions) (CommitChoice, error) {
+	if opts.Force {
+		return CommitChoice{Action: CommitAccept}, nil
 	}
 
-	response = strings.ToLower(strings.TrimSpace(response))
-
-	switch response {
-	case "y", "yes", "": // Default to yes
-		return true, false, nil
-	case "e", "edit":
-		return false, true, nil // Signal to edit again
-	case "n", "no":
-		return false, false, nil // Abort
-	default:
-		fmt.Println("Invalid response. Aborting commit.")
-		return false, false, nil
+	options := "Y/n"
+	if opts.AllowEdit {
+		options += "/e(dit)"
+	}
+	options += "/r(egenerate)"
+	if opts.Candidates > 1 {
+		options += fmt.Sprintf("/<,>(candidate %d of %d)", opts.Candidate, opts.Candidates)
+	}
+
+	question := opts.Question
+	if question == "" {
+		question = "Commit with this message?"
+	}
+	for {
+		fmt.Printf("%s [%s] ", question, options)
+		response, err := readLine()
+		if err != nil {
+			return CommitChoice{}, fmt.Errorf("failed to read confirmation: %w", err)
+		}
+
+		response = strings.TrimSpace(response)
+		answer, instruction, _ := strings.Cut(response, " ")
+		switch strings.ToLower(answer) {
+		case "y", "yes", "": // Default to yes
+			return CommitChoice{Action: CommitAccept}, nil
+		case "n", "no":
+			return CommitChoice{Action: CommitAbort}, nil
+		case "e", "edit":
+			if opts.AllowEdit {
+				return CommitChoice{Action: CommitEdit}, nil
+			}
+		case "r", "regenerate":
+			instruction = strings.TrimSpace(instruction)
+			if instruction == "" {
+				fmt.Print("Extra instruction for the new message (optional, e.g. \"mention the migration\"): ")
+				if instruction, err = readLine(); err != nil {
+					return CommitChoice{}, fmt.Errorf("failed to read instruction: %w", err)
+				}
+			}
+			return CommitChoice{Action: CommitRegenerate, Instruction: strings.TrimSpace(instruction)}, nil
+		case "<", "p", "prev":
+			if opts.Candidates > 1 {
+				return CommitChoice{Action: CommitPrevious}, nil
+			}
+		case ">", "next":
+			if opts.Candidates > 1 {
+				return CommitChoice{Action: CommitNext}, nil
+			}
+		}
+		fmt.Println("Invalid response.")
 	}
 }
@@ -0,0 +1,115 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jake/llmify/internal/config"
+)
+
+// Entry is one line of the usage ledger: the usage of one model during one command run.
+type Entry struct {
+	Time             time.Time `json:"time"`
+	Command          string    `json:"command"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	Calls            int       `json:"calls"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	Cost             float64   `json:"cost_usd"`
+	Estimated        bool      `json:"estimated,omitempty"`
+}
+
+// LedgerPath returns the ledger location from config, falling back to
+// $XDG_DATA_HOME/llmify/usage.jsonl (or ~/.local/share/llmify/usage.jsonl).
+func LedgerPath(cfg *config.Config) (string, error) {
+	if cfg.Usage.LedgerPath != "" {
+		return cfg.Usage.LedgerPath, nil
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine home directory: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "llmify", "usage.jsonl"), nil
+}
+
+// Entries converts the tracked usage into ledger entries.
+func (t *Tracker) Entries(prices PriceTable) []Entry {
+	var entries []Entry
+	for _, m := range t.Models() {
+		cost, _ := prices.Cost(m.Provider, m.Model, m.PromptTokens, m.CompletionTokens)
+		entries = append(entries, Entry{
+			Time:             t.Started.UTC(),
+			Command:          t.Command,
+			Provider:         m.Provider,
+			Model:            m.Model,
+			Calls:            m.Calls,
+			PromptTokens:     m.PromptTokens,
+			CompletionTokens: m.CompletionTokens,
+			Cost:             cost,
+			Estimated:        m.Estimated,
+		})
+	}
+	return entries
+}
+
+// AppendLedger appends entries to the JSONL ledger at path, creating it if needed.
+func AppendLedger(path string, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create ledger directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to write usage ledger: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadLedger reads all ledger entries recorded at or after since.
+// A missing ledger is not an error. Malformed lines are skipped.
+func ReadLedger(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !e.Time.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,89 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jake/llmify/internal/config"
+)
+
+// PriceTable maps model names to prices in USD per million tokens.
+type PriceTable map[string]config.ModelPrice
+
+// DefaultPrices are list prices for common models, used unless overridden in config.
+var DefaultPrices = PriceTable{
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"o3-mini":           {Input: 1.10, Output: 4.40},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00},
+	"claude-opus-4":     {Input: 15.00, Output: 75.00},
+}
+
+// localPrices price every model of the local providers, which run on the
+// user's machine. A model is priced for one provider as "provider/model";
+// the bare "provider/" prefix covers all of its models.
+var localPrices = PriceTable{
+	"ollama/": {},
+	"fake/":   {},
+}
+
+// LoadPrices returns the default price table merged with overrides from config.
+// Models of local providers such as Ollama (and the fake provider) are free
+// unless priced explicitly, e.g. as "ollama/llama3".
+func LoadPrices(cfg *config.Config) PriceTable {
+	prices := make(PriceTable, len(DefaultPrices)+len(localPrices)+len(cfg.Pricing))
+	for model, price := range DefaultPrices {
+		prices[model] = price
+	}
+	for model, price := range localPrices {
+		prices[model] = price
+	}
+	for model, price := range cfg.Pricing {
+		prices[strings.ToLower(model)] = price
+	}
+	return prices
+}
+
+// Cost returns the cost in USD of the given token counts for a model of
+// provider. Prices for "provider/model" win over prices for the model alone.
+// Models are matched exactly first, then by the longest known prefix (so
+// "gpt-4o-2024-08-06" uses the "gpt-4o" price). The second result is false
+// if no price is known.
+func (p PriceTable) Cost(provider, model string, promptTokens, completionTokens int) (float64, bool) {
+	price, ok := p.lookup(provider + "/" + model)
+	if !ok {
+		price, ok = p.lookup(model)
+	}
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6, true
+}
+
+func (p PriceTable) lookup(model string) (config.ModelPrice, bool) {
+	model = strings.ToLower(model)
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	// Longest prefix wins so "gpt-4o-mini-..." does not match "gpt-4o"
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		if strings.HasPrefix(model, name) {
+			return p[name], true
+		}
+	}
+	return config.ModelPrice{}, false
+}
@@ -0,0 +1,80 @@
+package usage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jake/llmify/internal/config"
+	"github.com/jake/llmify/internal/llm"
+)
+
+func TestCost(t *testing.T) {
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Provider:  "openai",
+			Fallbacks: []config.FallbackConfig{{Provider: "ollama", Model: "llama3"}},
+		},
+		Pricing: map[string]config.ModelPrice{"ollama/Mixtral": {Input: 1, Output: 1}},
+	}
+	prices := LoadPrices(cfg)
+	tests := []struct {
+		provider, model string
+		cost            float64
+		known           bool
+	}{
+		{"openai", "gpt-4o-2024-08-06", 12.50, true},
+		{"openai", "gpt-4o-mini", 0.75, true},
+		{"openai", "gpt-9-preview", 0, false}, // Unknown even though a local fallback is configured
+		{"ollama", "llama3", 0, true},
+		{"ollama", "mixtral", 2, true},
+		{"fake", "fake", 0, true},
+		{"", "(default)", 0, false},
+	}
+	for _, tt := range tests {
+		cost, known := prices.Cost(tt.provider, tt.model, 1e6, 1e6)
+		if cost != tt.cost || known != tt.known {
+			t.Errorf("Cost(%q, %q) = %v, %v; want %v, %v", tt.provider, tt.model, cost, known, tt.cost, tt.known)
+		}
+	}
+}
+
+func TestTrackerRecordsAnsweringProvider(t *testing.T) {
+	failing, err := llm.NewFakeClient([]llm.Fixture{{Status: 503, Error: "overloaded"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answering, err := llm.NewFakeClient([]llm.Fixture{{Text: "ok", PromptTokens: 10, CompletionTokens: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := llm.NewRouterClient([]llm.Route{
+		{Provider: "openai", Client: failing},
+		{Provider: "ollama", Model: "llama3", Client: answering},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := NewTracker("test", "openai")
+	client := tracker.Wrap(router)
+	req := llm.Request{Model: "gpt-4o", Messages: []llm.Message{{Role: "user", Content: "hi"}}}
+
+	if _, err := client.Generate(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.GenerateStream(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := llm.CollectStream(context.Background(), stream, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := tracker.Entries(LoadPrices(&config.Config{}))
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1: %+v", len(entries), entries)
+	}
+	e := entries[0]
+	if e.Provider != "ollama" || e.Model != "llama3" || e.Calls != 2 || e.Cost != 0 {
+		t.Errorf("entry = %+v, want 2 free ollama/llama3 calls", e)
+	}
+}
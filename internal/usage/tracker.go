@@ -0,0 +1,185 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jake/llmify/internal/llm"
+	"github.com/jake/llmify/internal/tokenizer"
+)
+
+// ModelUsage aggregates the usage of a single model during a run.
+type ModelUsage struct {
+	Provider         string
+	Model            string
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	Estimated        bool // True if any call had to be estimated locally
+}
+
+// Tracker aggregates token usage for one command run.
+type Tracker struct {
+	Command  string
+	Provider string // The primary provider, for calls that do not report theirs
+	Started  time.Time
+
+	mu     sync.Mutex
+	models map[string]*ModelUsage
+}
+
+// NewTracker creates a tracker for a command run.
+func NewTracker(command, provider string) *Tracker {
+	return &Tracker{
+		Command:  command,
+		Provider: provider,
+		Started:  time.Now(),
+		models:   make(map[string]*ModelUsage),
+	}
+}
+
+// Record adds the usage of one LLM call answered by provider, or by the
+// primary provider if it is empty.
+func (t *Tracker) Record(provider, model string, u llm.Usage, estimated bool) {
+	if provider == "" {
+		provider = t.Provider
+	}
+	if model == "" {
+		model = "(default)"
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := provider + "/" + model
+	m, ok := t.models[key]
+	if !ok {
+		m = &ModelUsage{Provider: provider, Model: model}
+		t.models[key] = m
+	}
+	m.Calls++
+	m.PromptTokens += u.PromptTokens
+	m.CompletionTokens += u.CompletionTokens
+	m.Estimated = m.Estimated || estimated
+}
+
+// Models returns the aggregated usage per provider and model, sorted by model name.
+func (t *Tracker) Models() []ModelUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make([]ModelUsage, 0, len(t.models))
+	for _, m := range t.models {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Model != result[j].Model {
+			return result[i].Model < result[j].Model
+		}
+		return result[i].Provider < result[j].Provider
+	})
+	return result
+}
+
+// WriteSummary prints a one-line-per-model usage summary with estimated cost.
+// Nothing is printed if no LLM calls were made.
+func (t *Tracker) WriteSummary(w io.Writer, prices PriceTable) {
+	models := t.Models()
+	if len(models) == 0 {
+		return
+	}
+	var totalCost float64
+	fmt.Fprintln(w, "\n--- LLM Usage ---")
+	for _, m := range models {
+		cost, known := prices.Cost(m.Provider, m.Model, m.PromptTokens, m.CompletionTokens)
+		totalCost += cost
+		costStr := formatCost(cost)
+		if !known {
+			costStr = "unknown price"
+		}
+		approx := ""
+		if m.Estimated {
+			approx = " (estimated)"
+		}
+		fmt.Fprintf(w, "%s: %d call(s), %d prompt + %d completion tokens%s, %s\n",
+			m.Model, m.Calls, m.PromptTokens, m.CompletionTokens, approx, costStr)
+	}
+	if len(models) > 1 {
+		fmt.Fprintf(w, "Total: %s\n", formatCost(totalCost))
+	}
+}
+
+// Wrap returns an LLMClient that records the usage of every call made through it.
+func (t *Tracker) Wrap(client llm.LLMClient) llm.LLMClient {
+	return &trackingClient{next: client, tracker: t}
+}
+
+// trackingClient decorates an LLMClient with usage tracking.
+type trackingClient struct {
+	next    llm.LLMClient
+	tracker *Tracker
+}
+
+func (c *trackingClient) Generate(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	resp, err := c.next.Generate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	model := req.Model
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	c.record(req, resp.Provider, model, resp.Usage, resp.Text)
+	return resp, nil
+}
+
+func (c *trackingClient) GenerateStream(ctx context.Context, req llm.Request) (<-chan llm.Chunk, error) {
+	in, err := c.next.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan llm.Chunk)
+	go func() {
+		defer close(out)
+		resp, err := llm.Relay(ctx, in, out)
+		if err != nil {
+			return
+		}
+		model := req.Model
+		if resp.Model != "" {
+			model = resp.Model
+		}
+		c.record(req, resp.Provider, model, resp.Usage, resp.Text)
+	}()
+	return out, nil
+}
+
+// record stores the usage of a call, estimating it locally when the provider
+// did not report any (e.g. some OpenAI-compatible servers while streaming).
+func (c *trackingClient) record(req llm.Request, provider, model string, u llm.Usage, completion string) {
+	estimated := false
+	if u.TotalTokens() == 0 {
+		u = estimateUsage(req, completion)
+		estimated = true
+	}
+	c.tracker.Record(provider, model, u, estimated)
+}
+
+// estimateUsage counts request and response tokens with the default tokenizer.
+func estimateUsage(req llm.Request, completion string) llm.Usage {
+	tok, err := tokenizer.Get(tokenizer.DefaultTokenizer)
+	if err != nil {
+		tok, _ = tokenizer.Get("approx")
+	}
+	prompt := tok.Count(req.System)
+	for _, m := range req.Messages {
+		prompt += tok.Count(m.Content)
+	}
+	return llm.Usage{PromptTokens: prompt, CompletionTokens: tok.Count(completion)}
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}